@@ -0,0 +1,71 @@
+package apperrors
+
+import (
+	"fmt"
+
+	"github.com/adampresley/mytime/api/storage"
+)
+
+// NotFoundError is returned when a record asked for by code or ID does
+// not exist.
+type NotFoundError struct {
+	Entity string
+	Field  string
+	Value  interface{}
+}
+
+func NotFound(entity, field string, value interface{}) error {
+	return &NotFoundError{Entity: entity, Field: field, Value: value}
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %s %v not found", e.Entity, e.Field, e.Value)
+}
+
+// Is lets callers keep checking for storage.ErrNotFound.
+func (e *NotFoundError) Is(target error) bool {
+	return target == storage.ErrNotFound
+}
+
+// DuplicateCodeError is returned when a code is already used by another
+// record of the same kind.
+type DuplicateCodeError struct {
+	Entity string
+	Code   string
+}
+
+func DuplicateCode(entity, code string) error {
+	return &DuplicateCodeError{Entity: entity, Code: code}
+}
+
+func (e *DuplicateCodeError) Error() string {
+	return fmt.Sprintf("%s code %s is already in use", e.Entity, e.Code)
+}
+
+// ConflictError is returned when the data is not in a state that allows
+// the change, such as invoicing a session that is already invoiced.
+type ConflictError struct {
+	Message string
+}
+
+func Conflict(format string, args ...interface{}) error {
+	return &ConflictError{Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+// ValidationError is returned when a value given to My Time is not
+// acceptable, such as a bad setting or an unreadable import file.
+type ValidationError struct {
+	Message string
+}
+
+func Validation(format string, args ...interface{}) error {
+	return &ValidationError{Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
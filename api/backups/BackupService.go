@@ -0,0 +1,608 @@
+package backups
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adampresley/mytime/api/apperrors"
+	"github.com/adampresley/mytime/api/profiles"
+	"github.com/adampresley/mytime/api/storage"
+)
+
+const (
+	BackupDirectory string = "backups"
+	ManifestName    string = "manifest.json"
+
+	restoreDirectory string = ".restore"
+	timestampFormat  string = "20060102-150405"
+
+	/*
+	 * While a restore swaps files, the current data is moved aside. The
+	 * aside directory is renamed as each step finishes, so an interrupted
+	 * restore can tell how far it got.
+	 */
+	asidePartialDirectory  string = ".previous.partial"
+	asideDirectory         string = ".previous"
+	asideFinishedDirectory string = ".previous.done"
+)
+
+type BackupServicer interface {
+	AutoBackup(reason string) (string, error)
+	CreateBackup(fileName string) (string, error)
+	ListAutoBackups() ([]string, error)
+	RecoverRestore() (bool, error)
+	Restore(fileName string) error
+	ValidateBackup(fileName string) (Manifest, error)
+}
+
+type BackupServiceConfig struct {
+	DataDirectory string
+	Keep          int
+	Locker        storage.Locker
+}
+
+type BackupService struct {
+	DataDirectory string
+	Keep          int
+	Locker        storage.Locker
+}
+
+// Manifest is stored in every backup archive, and describes what is in it.
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Files     []string  `json:"files"`
+}
+
+func NewBackupService(config BackupServiceConfig) BackupService {
+	return BackupService{
+		DataDirectory: config.DataDirectory,
+		Keep:          config.Keep,
+		Locker:        config.Locker,
+	}
+}
+
+// AutoBackup takes a backup into the data directory's backups folder
+// before something destructive happens, then removes the oldest
+// automatic backups so only the configured number are kept.
+func (s BackupService) AutoBackup(reason string) (string, error) {
+	var (
+		err      error
+		result   string
+		existing []string
+	)
+
+	dir := filepath.Join(s.DataDirectory, BackupDirectory)
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("Error creating backup directory: %w", err)
+	}
+
+	fileName := filepath.Join(dir, fmt.Sprintf("auto-%s-%s.tar.gz", time.Now().Format(timestampFormat), reason))
+
+	if result, err = s.CreateBackup(fileName); err != nil {
+		return result, err
+	}
+
+	if existing, err = s.ListAutoBackups(); err != nil {
+		return result, err
+	}
+
+	keep := s.Keep
+
+	if keep < 1 {
+		keep = 1
+	}
+
+	for index := 0; index < len(existing)-keep; index++ {
+		if err = os.Remove(existing[index]); err != nil {
+			return result, fmt.Errorf("Error removing old backup %s: %w", existing[index], err)
+		}
+	}
+
+	return result, nil
+}
+
+// CreateBackup writes a gzipped tar of the whole data directory, apart
+// from the automatic backups. An empty file name writes a timestamped
+// archive to the current directory.
+func (s BackupService) CreateBackup(fileName string) (string, error) {
+	var (
+		err  error
+		f    *os.File
+		data []byte
+	)
+
+	if fileName == "" {
+		fileName = fmt.Sprintf("mytime-backup-%s.tar.gz", time.Now().Format(timestampFormat))
+	}
+
+	if err = s.Locker.RLock(); err != nil {
+		return fileName, err
+	}
+
+	defer s.Locker.RUnlock()
+
+	files, err := s.dataFiles()
+
+	if err != nil {
+		return fileName, err
+	}
+
+	if f, err = os.Create(fileName); err != nil {
+		return fileName, fmt.Errorf("Error creating backup file: %w", err)
+	}
+
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	manifest := Manifest{
+		Version:   1,
+		CreatedAt: time.Now(),
+		Files:     files,
+	}
+
+	if data, err = json.MarshalIndent(manifest, "", "  "); err != nil {
+		return fileName, fmt.Errorf("Error writing backup manifest: %w", err)
+	}
+
+	if err = writeTarFile(tw, ManifestName, data, manifest.CreatedAt); err != nil {
+		return fileName, err
+	}
+
+	for _, name := range files {
+		var info os.FileInfo
+
+		fullPath := filepath.Join(s.DataDirectory, filepath.FromSlash(name))
+
+		if info, err = os.Stat(fullPath); err != nil {
+			return fileName, fmt.Errorf("Error reading %s: %w", name, err)
+		}
+
+		if data, err = ioutil.ReadFile(fullPath); err != nil {
+			return fileName, fmt.Errorf("Error reading %s: %w", name, err)
+		}
+
+		if err = writeTarFile(tw, name, data, info.ModTime()); err != nil {
+			return fileName, err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return fileName, fmt.Errorf("Error finishing backup archive: %w", err)
+	}
+
+	if err = gz.Close(); err != nil {
+		return fileName, fmt.Errorf("Error finishing backup archive: %w", err)
+	}
+
+	if err = f.Sync(); err != nil {
+		return fileName, fmt.Errorf("Error writing backup file: %w", err)
+	}
+
+	return fileName, nil
+}
+
+// ListAutoBackups returns the automatic backups, oldest first.
+func (s BackupService) ListAutoBackups() ([]string, error) {
+	result, err := filepath.Glob(filepath.Join(s.DataDirectory, BackupDirectory, "auto-*.tar.gz"))
+
+	if err != nil {
+		return result, fmt.Errorf("Error listing backups: %w", err)
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
+
+// Restore replaces the data directory with the contents of a backup. The
+// archive is validated first, and the current data is backed up
+// automatically before anything is replaced.
+func (s BackupService) Restore(fileName string) error {
+	var (
+		err      error
+		manifest Manifest
+		current  []string
+	)
+
+	if manifest, err = s.ValidateBackup(fileName); err != nil {
+		return err
+	}
+
+	if err = s.Locker.Lock(); err != nil {
+		return err
+	}
+
+	defer s.Locker.Unlock()
+
+	if _, err = s.AutoBackup("restore"); err != nil {
+		return fmt.Errorf("Error backing up current data before restoring: %w", err)
+	}
+
+	/*
+	 * Unpack next to the data first, so a failure part way through
+	 * extracting doesn't leave a half restored data directory.
+	 */
+	tempDir := filepath.Join(s.DataDirectory, restoreDirectory)
+
+	if err = os.RemoveAll(tempDir); err != nil {
+		return fmt.Errorf("Error clearing restore directory: %w", err)
+	}
+
+	defer os.RemoveAll(tempDir)
+
+	if err = s.extract(fileName, tempDir); err != nil {
+		return err
+	}
+
+	if current, err = s.dataFiles(); err != nil {
+		return err
+	}
+
+	return s.swap(tempDir, current, manifest.Files)
+}
+
+/*
+ * swap moves the current data files aside, then the restored files into
+ * place. The aside directory only gets its final name once every current
+ * file is in it, so it is always clear what to put back. Any error puts
+ * the current data back.
+ */
+func (s BackupService) swap(tempDir string, current, restored []string) error {
+	var err error
+
+	partial := filepath.Join(s.DataDirectory, asidePartialDirectory)
+	aside := filepath.Join(s.DataDirectory, asideDirectory)
+	finished := filepath.Join(s.DataDirectory, asideFinishedDirectory)
+
+	if err = os.RemoveAll(partial); err != nil {
+		return fmt.Errorf("Error clearing %s: %w", asidePartialDirectory, err)
+	}
+
+	if err = moveFiles(s.DataDirectory, partial, current); err != nil {
+		return s.rollBackSwap(err)
+	}
+
+	if err = os.Rename(partial, aside); err != nil {
+		return s.rollBackSwap(fmt.Errorf("Error moving current data aside: %w", err))
+	}
+
+	if err = moveFiles(tempDir, s.DataDirectory, restored); err != nil {
+		return s.rollBackSwap(err)
+	}
+
+	if err = os.Rename(aside, finished); err != nil {
+		return s.rollBackSwap(fmt.Errorf("Error finishing restore: %w", err))
+	}
+
+	_ = os.RemoveAll(finished)
+	return nil
+}
+
+// rollBackSwap puts the data back after a failed swap, and returns the
+// error that caused it, along with any from putting the data back.
+func (s BackupService) rollBackSwap(cause error) error {
+	if _, err := s.putBack(); err != nil {
+		return fmt.Errorf("%w. Putting the current data back also failed, and it is in %s: %s", cause, s.DataDirectory, err.Error())
+	}
+
+	return cause
+}
+
+/*
+ * RecoverRestore puts back the data from before a restore that was
+ * interrupted part way, and reports whether there was one. It is run
+ * before anything reads the data.
+ */
+func (s BackupService) RecoverRestore() (bool, error) {
+	if err := s.Locker.Lock(); err != nil {
+		return false, err
+	}
+
+	defer s.Locker.Unlock()
+
+	_ = os.RemoveAll(filepath.Join(s.DataDirectory, asideFinishedDirectory))
+	return s.putBack()
+}
+
+/*
+ * putBack moves the data that was set aside back into place. Once all of
+ * it was aside, anything in the data directory came from the backup, so
+ * it is removed first. Before that, the files still in place are the
+ * current data, and are left alone.
+ */
+func (s BackupService) putBack() (bool, error) {
+	var (
+		err      error
+		restored []string
+		moved    []string
+	)
+
+	dir := filepath.Join(s.DataDirectory, asideDirectory)
+
+	if _, err = os.Stat(dir); err == nil {
+		if restored, err = s.dataFiles(); err != nil {
+			return true, err
+		}
+
+		for _, name := range restored {
+			if err = os.Remove(filepath.Join(s.DataDirectory, filepath.FromSlash(name))); err != nil {
+				return true, fmt.Errorf("Error removing restored %s: %w", name, err)
+			}
+		}
+	} else {
+		dir = filepath.Join(s.DataDirectory, asidePartialDirectory)
+
+		if _, err = os.Stat(dir); err != nil {
+			return false, nil
+		}
+	}
+
+	if moved, err = listFiles(dir); err != nil {
+		return true, err
+	}
+
+	if err = moveFiles(dir, s.DataDirectory, moved); err != nil {
+		return true, err
+	}
+
+	if err = os.RemoveAll(dir); err != nil {
+		return true, fmt.Errorf("Error removing %s: %w", dir, err)
+	}
+
+	return true, nil
+}
+
+// ValidateBackup checks that an archive was written by CreateBackup, that
+// every file in it is listed in its manifest with a safe path, and that
+// the data files are readable JSON.
+func (s BackupService) ValidateBackup(fileName string) (Manifest, error) {
+	var (
+		err      error
+		f        *os.File
+		gz       *gzip.Reader
+		header   *tar.Header
+		data     []byte
+		manifest Manifest
+	)
+
+	seen := make(map[string]bool)
+
+	if f, err = os.Open(fileName); err != nil {
+		return manifest, fmt.Errorf("Error opening backup: %w", err)
+	}
+
+	defer f.Close()
+
+	if gz, err = gzip.NewReader(f); err != nil {
+		return manifest, fmt.Errorf("Backup is not a gzip archive: %w", err)
+	}
+
+	tr := tar.NewReader(gz)
+
+	for {
+		if header, err = tr.Next(); err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return manifest, fmt.Errorf("Backup archive is corrupt: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			return manifest, fmt.Errorf("Backup contains %s, which is not a regular file", header.Name)
+		}
+
+		if !isSafePath(header.Name) {
+			return manifest, fmt.Errorf("Backup contains an unsafe path %s", header.Name)
+		}
+
+		if data, err = ioutil.ReadAll(tr); err != nil {
+			return manifest, fmt.Errorf("Backup archive is corrupt reading %s: %w", header.Name, err)
+		}
+
+		if header.Name == ManifestName {
+			if err = json.Unmarshal(data, &manifest); err != nil {
+				return manifest, fmt.Errorf("Backup manifest is invalid: %w", err)
+			}
+
+			continue
+		}
+
+		if isDataFile(header.Name) && len(data) > 0 {
+			var records []interface{}
+
+			if err = json.Unmarshal(data, &records); err != nil {
+				return manifest, apperrors.Validation("Backup file %s is not valid data: %s", header.Name, err.Error())
+			}
+		}
+
+		seen[header.Name] = true
+	}
+
+	if manifest.Version == 0 {
+		return manifest, apperrors.Validation("Backup has no manifest. Is this a My Time backup?")
+	}
+
+	for _, name := range manifest.Files {
+		if !seen[name] {
+			return manifest, apperrors.Validation("Backup is missing %s", name)
+		}
+	}
+
+	if len(seen) != len(manifest.Files) {
+		return manifest, apperrors.Validation("Backup contains files not listed in its manifest")
+	}
+
+	return manifest, nil
+}
+
+// dataFiles lists the files in the data directory, as slash separated
+// paths relative to it, leaving out backups, other profiles, and hidden
+// files and directories such as the lock file and restore leftovers.
+func (s BackupService) dataFiles() ([]string, error) {
+	return listFiles(s.DataDirectory)
+}
+
+func listFiles(root string) ([]string, error) {
+	result := make([]string, 0, 10)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+
+		if rel == "." {
+			return nil
+		}
+
+		if info.IsDir() {
+			if rel == BackupDirectory || rel == profiles.ProfilesDirectory || strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if info.Mode().IsRegular() && !strings.HasPrefix(info.Name(), ".") {
+			result = append(result, rel)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return result, fmt.Errorf("Error reading data directory: %w", err)
+	}
+
+	return result, nil
+}
+
+// moveFiles renames each of names, relative to from, to the same path
+// under to.
+func moveFiles(from, to string, names []string) error {
+	for _, name := range names {
+		target := filepath.Join(to, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("Error moving %s: %w", name, err)
+		}
+
+		if err := os.Rename(filepath.Join(from, filepath.FromSlash(name)), target); err != nil {
+			return fmt.Errorf("Error moving %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s BackupService) extract(fileName, dir string) error {
+	var (
+		err    error
+		f      *os.File
+		gz     *gzip.Reader
+		header *tar.Header
+		out    *os.File
+	)
+
+	if f, err = os.Open(fileName); err != nil {
+		return fmt.Errorf("Error opening backup: %w", err)
+	}
+
+	defer f.Close()
+
+	if gz, err = gzip.NewReader(f); err != nil {
+		return fmt.Errorf("Error reading backup: %w", err)
+	}
+
+	tr := tar.NewReader(gz)
+
+	for {
+		if header, err = tr.Next(); err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("Error reading backup: %w", err)
+		}
+
+		if header.Name == ManifestName {
+			continue
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("Error extracting %s: %w", header.Name, err)
+		}
+
+		if out, err = os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644); err != nil {
+			return fmt.Errorf("Error extracting %s: %w", header.Name, err)
+		}
+
+		if _, err = io.Copy(out, tr); err != nil {
+			out.Close()
+			return fmt.Errorf("Error extracting %s: %w", header.Name, err)
+		}
+
+		if err = out.Close(); err != nil {
+			return fmt.Errorf("Error extracting %s: %w", header.Name, err)
+		}
+	}
+}
+
+/*
+ * Collections written by simdb are named after the entity, with no
+ * extension. Anything else, like config.yml, is copied as is.
+ */
+func isDataFile(name string) bool {
+	return filepath.Ext(name) == ""
+}
+
+func isSafePath(name string) bool {
+	if name == "" || strings.HasPrefix(name, "/") || strings.Contains(name, `\`) {
+		return false
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." || part == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("Error adding %s to backup: %w", name, err)
+	}
+
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("Error adding %s to backup: %w", name, err)
+	}
+
+	return nil
+}
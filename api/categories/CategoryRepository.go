@@ -0,0 +1,11 @@
+package categories
+
+// CategoryRepository stores and retrieves categories.
+type CategoryRepository interface {
+	Create(category Category) (int, error)
+	Delete(category Category) error
+	GetByCode(code string) (Category, error)
+	GetByID(id int) (Category, error)
+	List(archived bool) (CategoryCollection, error)
+	Update(category Category) error
+}
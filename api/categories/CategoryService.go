@@ -1,67 +1,93 @@
 package categories
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/adampresley/mytime/api/apperrors"
 	"github.com/adampresley/mytime/api/helpers"
-	"github.com/adampresley/simdb"
+	"github.com/adampresley/mytime/api/storage"
 )
 
 type CategoryServicer interface {
+	ArchiveCategory(code string) error
 	CreateCategory(category Category) (int, error)
+	DeleteCategory(code string) error
 	ListCategories(search CategorySearch) (CategoryCollection, error)
 	GetCategoryByCode(code string) (Category, error)
 	GetCategoryByID(id int) (Category, error)
+	UnarchiveCategory(code string) error
 	UpdateCategory(category Category) error
 }
 
 type CategoryServiceConfig struct {
-	DB            *simdb.Driver
-	HelperService helpers.HelperServicer
+	CategoryRepository CategoryRepository
+	HelperService      helpers.HelperServicer
 }
 
 type CategoryService struct {
-	DB            *simdb.Driver
-	HelperService helpers.HelperServicer
+	CategoryRepository CategoryRepository
+	HelperService      helpers.HelperServicer
 }
 
 func NewCategoryService(config CategoryServiceConfig) CategoryService {
 	return CategoryService{
-		DB:            config.DB,
-		HelperService: config.HelperService,
+		CategoryRepository: config.CategoryRepository,
+		HelperService:      config.HelperService,
 	}
 }
 
-func (s CategoryService) CreateCategory(category Category) (int, error) {
-	var err error
+func (s CategoryService) ArchiveCategory(code string) error {
+	return s.setArchived(code, true)
+}
 
-	category.CategoryID = s.DB.Open(Category{}).GetNextNumericID()
+func (s CategoryService) CreateCategory(category Category) (int, error) {
+	var (
+		err   error
+		id    int
+		codes helpers.Codes
+	)
+
+	if codes, err = s.codes(0); err != nil {
+		return 0, err
+	}
 
 	if category.Code == "" {
-		category.Code = s.HelperService.CreateAutoCode(category.Name)
+		category.Code = s.HelperService.CreateAutoCode(category.Name, codes.Taken)
+	}
+
+	if codes.Taken(category.Code) {
+		return 0, apperrors.DuplicateCode("Category", category.Code)
 	}
 
-	if err = s.DB.Insert(category); err != nil {
+	if id, err = s.CategoryRepository.Create(category); err != nil {
 		return 0, fmt.Errorf("error inserting new category: %w", err)
 	}
 
-	return category.CategoryID, nil
+	return id, nil
 }
 
-func (s CategoryService) ListCategories(search CategorySearch) (CategoryCollection, error) {
+func (s CategoryService) DeleteCategory(code string) error {
 	var err error
+	var category Category
 
-	result := make(CategoryCollection, 0, 10)
-	d := s.DB.Open(Category{})
+	if category, err = s.GetCategoryByCode(code); err != nil {
+		return err
+	}
 
-	if search.Archived {
-		d = d.Where("archived", "=", true)
-	} else {
-		d = d.Where("archived", "=", false)
+	if err = s.CategoryRepository.Delete(category); err != nil {
+		return fmt.Errorf("Error deleting category: %w", err)
 	}
 
-	if err = d.Get().AsEntity(&result); err != nil {
+	return nil
+}
+
+func (s CategoryService) ListCategories(search CategorySearch) (CategoryCollection, error) {
+	var err error
+	var result CategoryCollection
+
+	if result, err = s.CategoryRepository.List(search.Archived); err != nil {
 		return result, fmt.Errorf("Error querying for Categories: %w", err)
 	}
 
@@ -76,7 +102,11 @@ func (s CategoryService) GetCategoryByCode(code string) (Category, error) {
 	var err error
 	var category Category
 
-	if err = s.DB.Open(Category{}).Where("code", "=", code).First().AsEntity(&category); err != nil {
+	if category, err = s.CategoryRepository.GetByCode(code); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return category, apperrors.NotFound("Category", "code", code)
+		}
+
 		return category, fmt.Errorf("Error finding category with a code '%s': %w", code, err)
 	}
 
@@ -87,15 +117,38 @@ func (s CategoryService) GetCategoryByID(id int) (Category, error) {
 	var err error
 	var category Category
 
-	if err = s.DB.Open(Category{}).Where("categoryID", "=", id).First().AsEntity(&category); err != nil {
-		return category, fmt.Errorf("Error finding category with an ID '%s': %w", id, err)
+	if category, err = s.CategoryRepository.GetByID(id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return category, apperrors.NotFound("Category", "ID", id)
+		}
+
+		return category, fmt.Errorf("Error finding category with an ID '%d': %w", id, err)
 	}
 
 	return category, nil
 }
 
+func (s CategoryService) UnarchiveCategory(code string) error {
+	return s.setArchived(code, false)
+}
+
 func (s CategoryService) UpdateCategory(category Category) error {
-	return s.DB.Update(category)
+	var err error
+	var codes helpers.Codes
+
+	if category.Code == "" {
+		return apperrors.Validation("Category code can't be empty")
+	}
+
+	if codes, err = s.codes(category.CategoryID); err != nil {
+		return err
+	}
+
+	if codes.Taken(category.Code) {
+		return apperrors.DuplicateCode("Category", category.Code)
+	}
+
+	return s.CategoryRepository.Update(category)
 }
 
 func (s CategoryService) filterCategoriesByName(categories CategoryCollection, name string) CategoryCollection {
@@ -111,3 +164,66 @@ func (s CategoryService) filterCategoriesByName(categories CategoryCollection, n
 
 	return result
 }
+
+// codes returns the codes of every category but exceptID.
+func (s CategoryService) codes(exceptID int) (helpers.Codes, error) {
+	var err error
+	var all CategoryCollection
+
+	if all, err = s.listAll(); err != nil {
+		return nil, err
+	}
+
+	result := make(helpers.Codes, 0, len(all))
+
+	for _, c := range all {
+		if c.CategoryID != exceptID {
+			result = append(result, c.Code)
+		}
+	}
+
+	return result, nil
+}
+
+// listAll returns active and archived categories.
+func (s CategoryService) listAll() (CategoryCollection, error) {
+	var err error
+	var active, archived CategoryCollection
+
+	if active, err = s.CategoryRepository.List(false); err != nil {
+		return active, fmt.Errorf("Error querying for categories: %w", err)
+	}
+
+	if archived, err = s.CategoryRepository.List(true); err != nil {
+		return active, fmt.Errorf("Error querying for categories: %w", err)
+	}
+
+	return append(active, archived...), nil
+}
+
+// setArchived archives or unarchives a category. Asking for the state it is
+// already in is a conflict, so a mistyped code doesn't go unnoticed.
+func (s CategoryService) setArchived(code string, archived bool) error {
+	var err error
+	var category Category
+
+	if category, err = s.GetCategoryByCode(code); err != nil {
+		return err
+	}
+
+	if category.Archived == archived {
+		if archived {
+			return apperrors.Conflict("Category %s is already archived", code)
+		}
+
+		return apperrors.Conflict("Category %s is not archived", code)
+	}
+
+	category.Archived = archived
+
+	if err = s.CategoryRepository.Update(category); err != nil {
+		return fmt.Errorf("Error updating category: %w", err)
+	}
+
+	return nil
+}
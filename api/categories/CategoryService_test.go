@@ -0,0 +1,132 @@
+package categories
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/adampresley/mytime/api/apperrors"
+	"github.com/adampresley/mytime/api/helpers"
+)
+
+func newTestCategoryService(t *testing.T) CategoryService {
+	t.Helper()
+
+	service := NewCategoryService(CategoryServiceConfig{
+		CategoryRepository: NewMemoryCategoryRepository(),
+		HelperService:      helpers.NewHelperService(helpers.HelperServiceConfig{}),
+	})
+
+	for _, c := range []Category{
+		{Name: "Development", Code: "dev", Rate: 100},
+		{Name: "Meetings", Code: "meet", Rate: 80},
+		{Name: "Design", Code: "design", Archived: true},
+	} {
+		if _, err := service.CategoryRepository.Create(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return service
+}
+
+func TestCreateCategoryCodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		category      Category
+		wantCode      string
+		wantDuplicate bool
+	}{
+		{name: "new code", category: Category{Name: "Support", Code: "support"}, wantCode: "support"},
+		{name: "taken code in another case", category: Category{Name: "Dev", Code: "DEV"}, wantDuplicate: true},
+		{name: "taken by an archived category", category: Category{Name: "Design", Code: "design"}, wantDuplicate: true},
+		{name: "auto code", category: Category{Name: "Code Review"}, wantCode: "code-review"},
+		{name: "auto code taken by an archived category", category: Category{Name: "Design"}, wantCode: "design2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var duplicate *apperrors.DuplicateCodeError
+
+			service := newTestCategoryService(t)
+			id, err := service.CreateCategory(tt.category)
+
+			if tt.wantDuplicate {
+				if !errors.As(err, &duplicate) {
+					t.Errorf("CreateCategory() = %v, want a duplicate code error", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("CreateCategory() = %v", err)
+			}
+
+			got, _ := service.GetCategoryByID(id)
+
+			if got.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", got.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUpdateCategoryCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		wantErr bool
+	}{
+		{name: "keeps its own code", code: "dev"},
+		{name: "new code", code: "development"},
+		{name: "taken by another category", code: "Meet", wantErr: true},
+		{name: "empty code", code: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestCategoryService(t)
+			category, _ := service.GetCategoryByID(1)
+			category.Code = tt.code
+
+			if err := service.UpdateCategory(category); (err != nil) != tt.wantErr {
+				t.Errorf("UpdateCategory() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListCategories(t *testing.T) {
+	tests := []struct {
+		name   string
+		search CategorySearch
+		want   string
+	}{
+		{name: "active", search: CategorySearch{}, want: "dev,meet"},
+		{name: "archived", search: CategorySearch{Archived: true}, want: "design"},
+		{name: "name in any case", search: CategorySearch{Name: "MEETING"}, want: "meet"},
+		{name: "code", search: CategorySearch{Name: "dev"}, want: "dev"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestCategoryService(t)
+			result, err := service.ListCategories(tt.search)
+
+			if err != nil {
+				t.Fatalf("ListCategories() = %v", err)
+			}
+
+			got := make([]string, 0, len(result))
+
+			for _, c := range result {
+				got = append(got, c.Code)
+			}
+
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("ListCategories() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
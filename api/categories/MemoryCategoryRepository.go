@@ -0,0 +1,119 @@
+package categories
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/adampresley/mytime/api/helpers"
+	"github.com/adampresley/mytime/api/storage"
+)
+
+// MemoryCategoryRepository keeps categories in memory.
+type MemoryCategoryRepository struct {
+	mutex   *sync.RWMutex
+	records map[int]Category
+}
+
+func NewMemoryCategoryRepository() MemoryCategoryRepository {
+	return MemoryCategoryRepository{
+		mutex:   &sync.RWMutex{},
+		records: make(map[int]Category),
+	}
+}
+
+func (r MemoryCategoryRepository) Create(category Category) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	category.CategoryID = 1
+
+	for id := range r.records {
+		if id >= category.CategoryID {
+			category.CategoryID = id + 1
+		}
+	}
+
+	r.records[category.CategoryID] = category
+	return category.CategoryID, nil
+}
+
+func (r MemoryCategoryRepository) Delete(category Category) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.records[category.CategoryID]; !ok {
+		return fmt.Errorf("failed to delete, unable to find any Category record with categoryID %d", category.CategoryID)
+	}
+
+	delete(r.records, category.CategoryID)
+	return nil
+}
+
+// GetByCode ignores case, as SimdbCategoryRepository does.
+func (r MemoryCategoryRepository) GetByCode(code string) (Category, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	ids := r.sortedIDs()
+	codes := make(helpers.Codes, 0, len(ids))
+
+	for _, id := range ids {
+		codes = append(codes, r.records[id].Code)
+	}
+
+	if index := codes.Match(code); index >= 0 {
+		return r.records[ids[index]], nil
+	}
+
+	return Category{}, storage.ErrNotFound
+}
+
+func (r MemoryCategoryRepository) GetByID(id int) (Category, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if category, ok := r.records[id]; ok {
+		return category, nil
+	}
+
+	return Category{}, storage.ErrNotFound
+}
+
+func (r MemoryCategoryRepository) List(archived bool) (CategoryCollection, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make(CategoryCollection, 0, len(r.records))
+
+	for _, id := range r.sortedIDs() {
+		if r.records[id].Archived == archived {
+			result = append(result, r.records[id])
+		}
+	}
+
+	return result, nil
+}
+
+func (r MemoryCategoryRepository) Update(category Category) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.records[category.CategoryID]; !ok {
+		return fmt.Errorf("failed to update, unable to find any Category record with categoryID %d", category.CategoryID)
+	}
+
+	r.records[category.CategoryID] = category
+	return nil
+}
+
+func (r MemoryCategoryRepository) sortedIDs() []int {
+	result := make([]int, 0, len(r.records))
+
+	for id := range r.records {
+		result = append(result, id)
+	}
+
+	sort.Ints(result)
+	return result
+}
@@ -0,0 +1,110 @@
+package categories
+
+import (
+	"github.com/adampresley/mytime/api/helpers"
+	"github.com/adampresley/mytime/api/storage"
+	"github.com/adampresley/simdb"
+)
+
+// SimdbCategoryRepository keeps categories in a simdb JSON collection.
+type SimdbCategoryRepository struct {
+	DB     *simdb.Driver
+	Fs     storage.AtomicFs
+	Locker storage.Locker
+}
+
+func NewSimdbCategoryRepository(db *simdb.Driver, fs storage.AtomicFs, locker storage.Locker) SimdbCategoryRepository {
+	return SimdbCategoryRepository{
+		DB:     db,
+		Fs:     fs,
+		Locker: locker,
+	}
+}
+
+func (r SimdbCategoryRepository) Create(category Category) (int, error) {
+	if err := r.Locker.Lock(); err != nil {
+		return 0, err
+	}
+
+	defer r.Locker.Unlock()
+
+	category.CategoryID = r.DB.Open(Category{}).GetNextNumericID()
+	return category.CategoryID, r.Fs.Committed(r.DB.Insert(category))
+}
+
+// GetByCode ignores case. simdb can't compare without case, so its
+// contains, which ignores case, narrows the records down first.
+func (r SimdbCategoryRepository) GetByCode(code string) (Category, error) {
+	if err := r.Locker.RLock(); err != nil {
+		return Category{}, err
+	}
+
+	defer r.Locker.RUnlock()
+
+	candidates := make(CategoryCollection, 0, 2)
+
+	if err := r.DB.Open(Category{}).Where("code", "contains", code).Get().AsEntity(&candidates); err != nil {
+		return Category{}, storage.FromSimdbError(err)
+	}
+
+	codes := make(helpers.Codes, 0, len(candidates))
+
+	for _, c := range candidates {
+		codes = append(codes, c.Code)
+	}
+
+	if index := codes.Match(code); index >= 0 {
+		return candidates[index], nil
+	}
+
+	return Category{}, storage.ErrNotFound
+}
+
+func (r SimdbCategoryRepository) Delete(category Category) error {
+	if err := r.Locker.Lock(); err != nil {
+		return err
+	}
+
+	defer r.Locker.Unlock()
+
+	type Category struct{ storage.SimdbKey }
+
+	key := Category{storage.SimdbKey{Field: "categoryID", Value: float64(category.CategoryID)}}
+	return r.Fs.Committed(r.DB.Open(Category{}).Delete(key))
+}
+
+func (r SimdbCategoryRepository) GetByID(id int) (Category, error) {
+	if err := r.Locker.RLock(); err != nil {
+		return Category{}, err
+	}
+
+	defer r.Locker.RUnlock()
+
+	var category Category
+
+	err := r.DB.Open(Category{}).Where("categoryID", "=", id).First().AsEntity(&category)
+	return category, storage.FromSimdbError(err)
+}
+
+func (r SimdbCategoryRepository) List(archived bool) (CategoryCollection, error) {
+	if err := r.Locker.RLock(); err != nil {
+		return nil, err
+	}
+
+	defer r.Locker.RUnlock()
+
+	result := make(CategoryCollection, 0, 10)
+
+	err := r.DB.Open(Category{}).Where("archived", "=", archived).Get().AsEntity(&result)
+	return result, storage.FromSimdbError(err)
+}
+
+func (r SimdbCategoryRepository) Update(category Category) error {
+	if err := r.Locker.Lock(); err != nil {
+		return err
+	}
+
+	defer r.Locker.Unlock()
+
+	return r.Fs.Committed(r.DB.Open(Category{}).Update(category))
+}
@@ -1,10 +1,18 @@
 package clients
 
+import "time"
+
 type Client struct {
-	ClientID int    `json:"clientID"`
-	Name     string `json:"name"`
-	Code     string `json:"code"`
-	Archived bool   `json:"archived"`
+	ClientID     int    `json:"clientID"`
+	Name         string `json:"name"`
+	Code         string `json:"code"`
+	Address      string `json:"address"`
+	ContactName  string `json:"contactName"`
+	ContactEmail string `json:"contactEmail"`
+	PaymentTerms int    `json:"paymentTerms"`
+	TaxID        string `json:"taxID"`
+	Notes        string `json:"notes"`
+	Archived     bool   `json:"archived"`
 }
 
 type ClientCollection []Client
@@ -12,3 +20,9 @@ type ClientCollection []Client
 func (c Client) ID() (string, interface{}) {
 	return "clientID", c.ClientID
 }
+
+// DueDate returns the date an invoice issued on invoiceDate is due, based on
+// this client's payment terms in days. No terms means due on receipt.
+func (c Client) DueDate(invoiceDate time.Time) time.Time {
+	return invoiceDate.AddDate(0, 0, c.PaymentTerms)
+}
@@ -0,0 +1,11 @@
+package clients
+
+// ClientRepository stores and retrieves clients.
+type ClientRepository interface {
+	Create(client Client) (int, error)
+	Delete(client Client) error
+	GetByCode(code string) (Client, error)
+	GetByID(id int) (Client, error)
+	List(archived bool) (ClientCollection, error)
+	Update(client Client) error
+}
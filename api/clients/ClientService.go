@@ -1,29 +1,34 @@
 package clients
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/adampresley/mytime/api/apperrors"
 	"github.com/adampresley/mytime/api/helpers"
-	"github.com/adampresley/simdb"
+	"github.com/adampresley/mytime/api/storage"
 )
 
 type ClientServicer interface {
+	ArchiveClient(code string) error
 	CreateClient(client Client) (int, error)
+	DeleteClient(code string) error
 	ListClients(search ClientSearch) (ClientCollection, error)
 	GetClientByCode(code string) (Client, error)
 	GetClientByID(id int) (Client, error)
+	UnarchiveClient(code string) error
 	UpdateClient(client Client) error
 }
 
 type ClientService struct {
-	DB            *simdb.Driver
-	HelperService helpers.HelperServicer
+	ClientRepository ClientRepository
+	HelperService    helpers.HelperServicer
 }
 
 type ClientServiceConfig struct {
-	DB            *simdb.Driver
-	HelperService helpers.HelperServicer
+	ClientRepository ClientRepository
+	HelperService    helpers.HelperServicer
 }
 
 type ClientSearch struct {
@@ -33,36 +38,61 @@ type ClientSearch struct {
 
 func NewClientService(config ClientServiceConfig) ClientService {
 	return ClientService{
-		DB:            config.DB,
-		HelperService: config.HelperService,
+		ClientRepository: config.ClientRepository,
+		HelperService:    config.HelperService,
 	}
 }
 
+func (s ClientService) ArchiveClient(code string) error {
+	return s.setArchived(code, true)
+}
+
 func (s ClientService) CreateClient(client Client) (int, error) {
-	var err error
+	var (
+		err   error
+		id    int
+		codes helpers.Codes
+	)
+
+	if codes, err = s.codes(0); err != nil {
+		return 0, err
+	}
 
-	client.ClientID = s.DB.Open(Client{}).GetNextNumericID()
+	if client.Code == "" {
+		client.Code = s.HelperService.CreateAutoCode(client.Name, codes.Taken)
+	}
+
+	if codes.Taken(client.Code) {
+		return 0, apperrors.DuplicateCode("Client", client.Code)
+	}
 
-	if err = s.DB.Insert(client); err != nil {
+	if id, err = s.ClientRepository.Create(client); err != nil {
 		return 0, fmt.Errorf("Error creating new client: %w", err)
 	}
 
-	return client.ClientID, nil
+	return id, nil
 }
 
-func (s ClientService) ListClients(search ClientSearch) (ClientCollection, error) {
+func (s ClientService) DeleteClient(code string) error {
 	var err error
+	var client Client
 
-	result := make(ClientCollection, 0, 10)
-	d := s.DB.Open(Client{})
+	if client, err = s.GetClientByCode(code); err != nil {
+		return err
+	}
 
-	if search.Archived {
-		d = d.Where("archived", "=", true)
-	} else {
-		d = d.Where("archived", "=", false)
+	if err = s.ClientRepository.Delete(client); err != nil {
+		return fmt.Errorf("Error deleting client: %w", err)
 	}
 
-	if err = d.Get().AsEntity(&result); err != nil {
+	return nil
+}
+
+func (s ClientService) ListClients(search ClientSearch) (ClientCollection, error) {
+	var err error
+	var result ClientCollection
+
+	if result, err = s.ClientRepository.List(search.Archived); err != nil {
 		return result, fmt.Errorf("Error querying for Clients: %w", err)
 	}
 
@@ -77,7 +107,11 @@ func (s ClientService) GetClientByCode(code string) (Client, error) {
 	var err error
 	var client Client
 
-	if err = s.DB.Open(Client{}).Where("code", "=", code).First().AsEntity(&client); err != nil {
+	if client, err = s.ClientRepository.GetByCode(code); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return client, apperrors.NotFound("Client", "code", code)
+		}
+
 		return client, fmt.Errorf("Error finding client with a code '%s': %w", code, err)
 	}
 
@@ -88,15 +122,38 @@ func (s ClientService) GetClientByID(id int) (Client, error) {
 	var err error
 	var client Client
 
-	if err = s.DB.Open(Client{}).Where("clientID", "=", id).First().AsEntity(&client); err != nil {
-		return client, fmt.Errorf("Error finding client with an id'%s': %w", id, err)
+	if client, err = s.ClientRepository.GetByID(id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return client, apperrors.NotFound("Client", "ID", id)
+		}
+
+		return client, fmt.Errorf("Error finding client with an id '%d': %w", id, err)
 	}
 
 	return client, nil
 }
 
+func (s ClientService) UnarchiveClient(code string) error {
+	return s.setArchived(code, false)
+}
+
 func (s ClientService) UpdateClient(client Client) error {
-	return s.DB.Open(Client{}).Update(client)
+	var err error
+	var codes helpers.Codes
+
+	if client.Code == "" {
+		return apperrors.Validation("Client code can't be empty")
+	}
+
+	if codes, err = s.codes(client.ClientID); err != nil {
+		return err
+	}
+
+	if codes.Taken(client.Code) {
+		return apperrors.DuplicateCode("Client", client.Code)
+	}
+
+	return s.ClientRepository.Update(client)
 }
 
 func (s ClientService) filterClientsByName(clients ClientCollection, clientName string) ClientCollection {
@@ -112,3 +169,66 @@ func (s ClientService) filterClientsByName(clients ClientCollection, clientName
 
 	return result
 }
+
+// codes returns the codes of every client but exceptID.
+func (s ClientService) codes(exceptID int) (helpers.Codes, error) {
+	var err error
+	var all ClientCollection
+
+	if all, err = s.listAll(); err != nil {
+		return nil, err
+	}
+
+	result := make(helpers.Codes, 0, len(all))
+
+	for _, c := range all {
+		if c.ClientID != exceptID {
+			result = append(result, c.Code)
+		}
+	}
+
+	return result, nil
+}
+
+// listAll returns active and archived clients.
+func (s ClientService) listAll() (ClientCollection, error) {
+	var err error
+	var active, archived ClientCollection
+
+	if active, err = s.ClientRepository.List(false); err != nil {
+		return active, fmt.Errorf("Error querying for clients: %w", err)
+	}
+
+	if archived, err = s.ClientRepository.List(true); err != nil {
+		return active, fmt.Errorf("Error querying for clients: %w", err)
+	}
+
+	return append(active, archived...), nil
+}
+
+// setArchived archives or unarchives a client. Asking for the state it is
+// already in is a conflict, so a mistyped code doesn't go unnoticed.
+func (s ClientService) setArchived(code string, archived bool) error {
+	var err error
+	var client Client
+
+	if client, err = s.GetClientByCode(code); err != nil {
+		return err
+	}
+
+	if client.Archived == archived {
+		if archived {
+			return apperrors.Conflict("Client %s is already archived", code)
+		}
+
+		return apperrors.Conflict("Client %s is not archived", code)
+	}
+
+	client.Archived = archived
+
+	if err = s.ClientRepository.Update(client); err != nil {
+		return fmt.Errorf("Error updating client: %w", err)
+	}
+
+	return nil
+}
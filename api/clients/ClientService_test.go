@@ -0,0 +1,189 @@
+package clients
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/adampresley/mytime/api/apperrors"
+	"github.com/adampresley/mytime/api/helpers"
+)
+
+func newTestClientService(t *testing.T) ClientService {
+	t.Helper()
+
+	service := NewClientService(ClientServiceConfig{
+		ClientRepository: NewMemoryClientRepository(),
+		HelperService:    helpers.NewHelperService(helpers.HelperServiceConfig{}),
+	})
+
+	for _, c := range []Client{
+		{Name: "Acme", Code: "acme"},
+		{Name: "Old Co", Code: "old", Archived: true},
+	} {
+		if _, err := service.ClientRepository.Create(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return service
+}
+
+func TestCreateClientCodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		client        Client
+		wantCode      string
+		wantDuplicate bool
+	}{
+		{name: "new code", client: Client{Name: "Initech", Code: "initech"}, wantCode: "initech"},
+		{name: "taken code", client: Client{Name: "Acme Two", Code: "acme"}, wantDuplicate: true},
+		{name: "taken code in another case", client: Client{Name: "Acme Two", Code: "ACME"}, wantDuplicate: true},
+		{name: "taken by an archived client", client: Client{Name: "Old Co", Code: "old"}, wantDuplicate: true},
+		{name: "auto code", client: Client{Name: "Globex Corp."}, wantCode: "globex-corp"},
+		{name: "auto code already taken", client: Client{Name: "ACME"}, wantCode: "acme2"},
+		{name: "auto code taken by an archived client", client: Client{Name: "Old"}, wantCode: "old2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var duplicate *apperrors.DuplicateCodeError
+
+			service := newTestClientService(t)
+			id, err := service.CreateClient(tt.client)
+
+			if tt.wantDuplicate {
+				if !errors.As(err, &duplicate) {
+					t.Errorf("CreateClient() = %v, want a duplicate code error", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("CreateClient() = %v", err)
+			}
+
+			got, _ := service.GetClientByID(id)
+
+			if got.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", got.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUpdateClientCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		wantErr interface{}
+	}{
+		{name: "keeps its own code", code: "acme"},
+		{name: "changes case of its own code", code: "ACME"},
+		{name: "new code", code: "acme-inc"},
+		{name: "taken by an archived client", code: "OLD", wantErr: &apperrors.DuplicateCodeError{}},
+		{name: "empty code", code: "", wantErr: &apperrors.ValidationError{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestClientService(t)
+			client, _ := service.GetClientByID(1)
+			client.Code = tt.code
+
+			err := service.UpdateClient(client)
+
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("UpdateClient() = %v, want no error", err)
+				}
+
+			case *apperrors.DuplicateCodeError:
+				if !errors.As(err, &want) {
+					t.Errorf("UpdateClient() = %v, want a duplicate code error", err)
+				}
+
+			case *apperrors.ValidationError:
+				if !errors.As(err, &want) {
+					t.Errorf("UpdateClient() = %v, want a validation error", err)
+				}
+			}
+		})
+	}
+}
+
+func TestListClients(t *testing.T) {
+	tests := []struct {
+		name   string
+		search ClientSearch
+		want   string
+	}{
+		{name: "active", search: ClientSearch{}, want: "acme"},
+		{name: "archived", search: ClientSearch{Archived: true}, want: "old"},
+		{name: "name in any case", search: ClientSearch{Name: "ACM"}, want: "acme"},
+		{name: "code", search: ClientSearch{Name: "ol", Archived: true}, want: "old"},
+		{name: "no match", search: ClientSearch{Name: "globex"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestClientService(t)
+			result, err := service.ListClients(tt.search)
+
+			if err != nil {
+				t.Fatalf("ListClients() = %v", err)
+			}
+
+			got := make([]string, 0, len(result))
+
+			for _, c := range result {
+				got = append(got, c.Code)
+			}
+
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("ListClients() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetArchived(t *testing.T) {
+	var conflict *apperrors.ConflictError
+	var notFound *apperrors.NotFoundError
+
+	service := newTestClientService(t)
+
+	if err := service.ArchiveClient("old"); !errors.As(err, &conflict) {
+		t.Errorf("ArchiveClient(archived) = %v, want a conflict", err)
+	}
+
+	if err := service.UnarchiveClient("acme"); !errors.As(err, &conflict) {
+		t.Errorf("UnarchiveClient(active) = %v, want a conflict", err)
+	}
+
+	if err := service.ArchiveClient("missing"); !errors.As(err, &notFound) {
+		t.Errorf("ArchiveClient(missing) = %v, want not found", err)
+	}
+
+	if err := service.ArchiveClient("acme"); err != nil {
+		t.Errorf("ArchiveClient(active) = %v", err)
+	}
+}
+
+func TestGetClientByCode(t *testing.T) {
+	var notFound *apperrors.NotFoundError
+
+	service := newTestClientService(t)
+
+	for _, code := range []string{"acme", "ACME", "Acme"} {
+		if client, err := service.GetClientByCode(code); err != nil || client.Code != "acme" {
+			t.Errorf("GetClientByCode(%q) = %v, %v, want acme", code, client.Code, err)
+		}
+	}
+
+	if _, err := service.GetClientByCode("acm"); !errors.As(err, &notFound) {
+		t.Errorf("GetClientByCode(%q) = %v, want not found", "acm", err)
+	}
+}
@@ -0,0 +1,120 @@
+package clients
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/adampresley/mytime/api/helpers"
+	"github.com/adampresley/mytime/api/storage"
+)
+
+// MemoryClientRepository keeps clients in memory. It is meant for tests
+// and for tools embedding My Time that don't want anything on disk.
+type MemoryClientRepository struct {
+	mutex   *sync.RWMutex
+	records map[int]Client
+}
+
+func NewMemoryClientRepository() MemoryClientRepository {
+	return MemoryClientRepository{
+		mutex:   &sync.RWMutex{},
+		records: make(map[int]Client),
+	}
+}
+
+func (r MemoryClientRepository) Create(client Client) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	client.ClientID = 1
+
+	for id := range r.records {
+		if id >= client.ClientID {
+			client.ClientID = id + 1
+		}
+	}
+
+	r.records[client.ClientID] = client
+	return client.ClientID, nil
+}
+
+func (r MemoryClientRepository) Delete(client Client) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.records[client.ClientID]; !ok {
+		return fmt.Errorf("failed to delete, unable to find any Client record with clientID %d", client.ClientID)
+	}
+
+	delete(r.records, client.ClientID)
+	return nil
+}
+
+// GetByCode ignores case, as SimdbClientRepository does.
+func (r MemoryClientRepository) GetByCode(code string) (Client, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	ids := r.sortedIDs()
+	codes := make(helpers.Codes, 0, len(ids))
+
+	for _, id := range ids {
+		codes = append(codes, r.records[id].Code)
+	}
+
+	if index := codes.Match(code); index >= 0 {
+		return r.records[ids[index]], nil
+	}
+
+	return Client{}, storage.ErrNotFound
+}
+
+func (r MemoryClientRepository) GetByID(id int) (Client, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if client, ok := r.records[id]; ok {
+		return client, nil
+	}
+
+	return Client{}, storage.ErrNotFound
+}
+
+func (r MemoryClientRepository) List(archived bool) (ClientCollection, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make(ClientCollection, 0, len(r.records))
+
+	for _, id := range r.sortedIDs() {
+		if r.records[id].Archived == archived {
+			result = append(result, r.records[id])
+		}
+	}
+
+	return result, nil
+}
+
+func (r MemoryClientRepository) Update(client Client) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.records[client.ClientID]; !ok {
+		return fmt.Errorf("failed to update, unable to find any Client record with clientID %d", client.ClientID)
+	}
+
+	r.records[client.ClientID] = client
+	return nil
+}
+
+func (r MemoryClientRepository) sortedIDs() []int {
+	result := make([]int, 0, len(r.records))
+
+	for id := range r.records {
+		result = append(result, id)
+	}
+
+	sort.Ints(result)
+	return result
+}
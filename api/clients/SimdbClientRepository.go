@@ -0,0 +1,110 @@
+package clients
+
+import (
+	"github.com/adampresley/mytime/api/helpers"
+	"github.com/adampresley/mytime/api/storage"
+	"github.com/adampresley/simdb"
+)
+
+// SimdbClientRepository keeps clients in a simdb JSON collection.
+type SimdbClientRepository struct {
+	DB     *simdb.Driver
+	Fs     storage.AtomicFs
+	Locker storage.Locker
+}
+
+func NewSimdbClientRepository(db *simdb.Driver, fs storage.AtomicFs, locker storage.Locker) SimdbClientRepository {
+	return SimdbClientRepository{
+		DB:     db,
+		Fs:     fs,
+		Locker: locker,
+	}
+}
+
+func (r SimdbClientRepository) Create(client Client) (int, error) {
+	if err := r.Locker.Lock(); err != nil {
+		return 0, err
+	}
+
+	defer r.Locker.Unlock()
+
+	client.ClientID = r.DB.Open(Client{}).GetNextNumericID()
+	return client.ClientID, r.Fs.Committed(r.DB.Insert(client))
+}
+
+// GetByCode ignores case. simdb can't compare without case, so its
+// contains, which ignores case, narrows the records down first.
+func (r SimdbClientRepository) GetByCode(code string) (Client, error) {
+	if err := r.Locker.RLock(); err != nil {
+		return Client{}, err
+	}
+
+	defer r.Locker.RUnlock()
+
+	candidates := make(ClientCollection, 0, 2)
+
+	if err := r.DB.Open(Client{}).Where("code", "contains", code).Get().AsEntity(&candidates); err != nil {
+		return Client{}, storage.FromSimdbError(err)
+	}
+
+	codes := make(helpers.Codes, 0, len(candidates))
+
+	for _, c := range candidates {
+		codes = append(codes, c.Code)
+	}
+
+	if index := codes.Match(code); index >= 0 {
+		return candidates[index], nil
+	}
+
+	return Client{}, storage.ErrNotFound
+}
+
+func (r SimdbClientRepository) Delete(client Client) error {
+	if err := r.Locker.Lock(); err != nil {
+		return err
+	}
+
+	defer r.Locker.Unlock()
+
+	type Client struct{ storage.SimdbKey }
+
+	key := Client{storage.SimdbKey{Field: "clientID", Value: float64(client.ClientID)}}
+	return r.Fs.Committed(r.DB.Open(Client{}).Delete(key))
+}
+
+func (r SimdbClientRepository) GetByID(id int) (Client, error) {
+	if err := r.Locker.RLock(); err != nil {
+		return Client{}, err
+	}
+
+	defer r.Locker.RUnlock()
+
+	var client Client
+
+	err := r.DB.Open(Client{}).Where("clientID", "=", id).First().AsEntity(&client)
+	return client, storage.FromSimdbError(err)
+}
+
+func (r SimdbClientRepository) List(archived bool) (ClientCollection, error) {
+	if err := r.Locker.RLock(); err != nil {
+		return nil, err
+	}
+
+	defer r.Locker.RUnlock()
+
+	result := make(ClientCollection, 0, 10)
+
+	err := r.DB.Open(Client{}).Where("archived", "=", archived).Get().AsEntity(&result)
+	return result, storage.FromSimdbError(err)
+}
+
+func (r SimdbClientRepository) Update(client Client) error {
+	if err := r.Locker.Lock(); err != nil {
+		return err
+	}
+
+	defer r.Locker.Unlock()
+
+	return r.Fs.Committed(r.DB.Open(Client{}).Update(client))
+}
@@ -0,0 +1,125 @@
+package clients
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/adampresley/mytime/api/storage"
+	"github.com/adampresley/simdb"
+	"github.com/spf13/afero"
+)
+
+// renameFailsFs fails every rename, as when the data directory can't be
+// written to.
+type renameFailsFs struct {
+	afero.Fs
+}
+
+func (fs renameFailsFs) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrPermission}
+}
+
+func newTestSimdbRepository(t *testing.T, base afero.Fs) SimdbClientRepository {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "mytime-clients")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	fs := storage.NewAtomicFs(base)
+	db, err := simdb.New(fs, dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewSimdbClientRepository(db, fs, storage.NewFileLocker(dir, time.Second))
+}
+
+func TestSimdbGetByCode(t *testing.T) {
+	var err error
+
+	repository := newTestSimdbRepository(t, afero.NewOsFs())
+
+	/*
+	 * Saved before codes ignored case, so both are there
+	 */
+	for _, c := range []Client{{Name: "Acme", Code: "acme"}, {Name: "Acme Upper", Code: "ACME"}, {Name: "Globex", Code: "globex"}} {
+		if _, err = repository.Create(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		code     string
+		wantName string
+	}{
+		{code: "acme", wantName: "Acme"},
+		{code: "ACME", wantName: "Acme Upper"},
+		{code: "Globex", wantName: "Globex"},
+		{code: "GLOBEX", wantName: "Globex"},
+		{code: "glob"},
+		{code: "initech"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			client, err := repository.GetByCode(tt.code)
+
+			if tt.wantName == "" {
+				if !errors.Is(err, storage.ErrNotFound) {
+					t.Errorf("GetByCode(%q) = %v, %v, want not found", tt.code, client, err)
+				}
+
+				return
+			}
+
+			if err != nil || client.Name != tt.wantName {
+				t.Errorf("GetByCode(%q) = %v, %v, want %s", tt.code, client.Name, err, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestSimdbDelete(t *testing.T) {
+	repository := newTestSimdbRepository(t, afero.NewOsFs())
+
+	for _, c := range []Client{{Name: "Acme", Code: "acme"}, {Name: "Globex", Code: "globex"}} {
+		if _, err := repository.Create(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repository.Delete(Client{ClientID: 1}); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+
+	if err := repository.Delete(Client{ClientID: 1}); err == nil {
+		t.Errorf("Delete() of a deleted client = nil, want an error")
+	}
+
+	remaining, err := repository.List(false)
+
+	if err != nil || len(remaining) != 1 || remaining[0].Code != "globex" {
+		t.Errorf("List() = %v, %v, want only globex", remaining, err)
+	}
+}
+
+func TestSimdbWriteFails(t *testing.T) {
+	repository := newTestSimdbRepository(t, renameFailsFs{afero.NewOsFs()})
+
+	if _, err := repository.Create(Client{Name: "Acme", Code: "acme"}); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Create() = %v, want the rename's error", err)
+	}
+
+	if remaining, err := repository.List(false); err != nil || len(remaining) != 0 {
+		t.Errorf("List() = %v, %v, want no clients saved", remaining, err)
+	}
+}
@@ -0,0 +1,246 @@
+package exports
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/adampresley/mytime/api/categories"
+	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/projects"
+	"github.com/adampresley/mytime/api/sessions"
+	"github.com/adampresley/mytime/api/settings"
+)
+
+type ExportServicer interface {
+	GetSessionRecords(search sessions.SessionSearch) (SessionRecordCollection, error)
+	WriteCSV(w io.Writer, records SessionRecordCollection) error
+	WriteICS(w io.Writer, records SessionRecordCollection) error
+	WriteJSON(w io.Writer, records SessionRecordCollection) error
+	WriteJSONLines(w io.Writer, records SessionRecordCollection) error
+	WriteOrg(w io.Writer, records SessionRecordCollection) error
+	WriteTimeclock(w io.Writer, records SessionRecordCollection) error
+	WriteTimewarrior(w io.Writer, records SessionRecordCollection, options TimewarriorOptions) error
+	WriteTimewarriorDataDir(dir string, records SessionRecordCollection, options TimewarriorOptions) (int, error)
+}
+
+type ExportServiceConfig struct {
+	CategoryService categories.CategoryServicer
+	ClientService   clients.ClientServicer
+	ProjectService  projects.ProjectServicer
+	SessionService  sessions.SessionServicer
+	Settings        settings.Settings
+}
+
+type ExportService struct {
+	CategoryService categories.CategoryServicer
+	ClientService   clients.ClientServicer
+	ProjectService  projects.ProjectServicer
+	SessionService  sessions.SessionServicer
+	Settings        settings.Settings
+}
+
+var csvHeader = []string{
+	"sessionID",
+	"clientID",
+	"clientCode",
+	"clientName",
+	"projectID",
+	"projectCode",
+	"projectName",
+	"categoryID",
+	"categoryCode",
+	"categoryName",
+	"startDateTime",
+	"endDateTime",
+	"durationSeconds",
+	"durationHours",
+	"rate",
+	"amount",
+	"notes",
+	"invoiced",
+	"invoiceDate",
+	"paid",
+	"paidDate",
+}
+
+func NewExportService(config ExportServiceConfig) ExportService {
+	return ExportService{
+		CategoryService: config.CategoryService,
+		ClientService:   config.ClientService,
+		ProjectService:  config.ProjectService,
+		SessionService:  config.SessionService,
+		Settings:        config.Settings,
+	}
+}
+
+func (s ExportService) GetSessionRecords(search sessions.SessionSearch) (SessionRecordCollection, error) {
+	var (
+		err         error
+		allSessions sessions.SessionCollection
+	)
+
+	result := make(SessionRecordCollection, 0, 100)
+
+	clientCache := make(map[int]clients.Client)
+	projectCache := make(map[int]projects.Project)
+	categoryCache := make(map[int]categories.Category)
+
+	if allSessions, err = s.SessionService.ListSessions(search); err != nil {
+		return result, fmt.Errorf("Error getting sessions to export: %w", err)
+	}
+
+	for _, session := range allSessions {
+		client, ok := clientCache[session.ClientID]
+
+		if !ok {
+			if client, err = s.ClientService.GetClientByID(session.ClientID); err != nil {
+				return result, fmt.Errorf("Error getting client for session %d: %w", session.SessionID, err)
+			}
+
+			clientCache[session.ClientID] = client
+		}
+
+		project, ok := projectCache[session.ProjectID]
+
+		if !ok {
+			if project, err = s.ProjectService.GetProjectByID(session.ProjectID); err != nil {
+				return result, fmt.Errorf("Error getting project for session %d: %w", session.SessionID, err)
+			}
+
+			projectCache[session.ProjectID] = project
+		}
+
+		category, ok := categoryCache[session.CategoryID]
+
+		if !ok {
+			if category, err = s.CategoryService.GetCategoryByID(session.CategoryID); err != nil {
+				return result, fmt.Errorf("Error getting category for session %d: %w", session.SessionID, err)
+			}
+
+			categoryCache[session.CategoryID] = category
+		}
+
+		result = append(result, s.newSessionRecord(session, client, project, category))
+	}
+
+	return result, nil
+}
+
+func (s ExportService) WriteCSV(w io.Writer, records SessionRecordCollection) error {
+	var err error
+
+	writer := csv.NewWriter(w)
+
+	if err = writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("Error writing CSV header: %w", err)
+	}
+
+	for _, r := range records {
+		row := []string{
+			strconv.Itoa(r.SessionID),
+			strconv.Itoa(r.ClientID),
+			r.ClientCode,
+			r.ClientName,
+			strconv.Itoa(r.ProjectID),
+			r.ProjectCode,
+			r.ProjectName,
+			strconv.Itoa(r.CategoryID),
+			r.CategoryCode,
+			r.CategoryName,
+			r.StartDateTime.Format(time.RFC3339),
+			r.EndDateTime.Format(time.RFC3339),
+			strconv.FormatInt(r.DurationSeconds, 10),
+			strconv.FormatFloat(r.DurationHours, 'f', -1, 64),
+			strconv.FormatFloat(r.Rate, 'f', 2, 64),
+			strconv.FormatFloat(r.Amount, 'f', s.Settings.Precision, 64),
+			r.Notes,
+			strconv.FormatBool(r.Invoiced),
+			formatOptionalTime(r.InvoiceDate),
+			strconv.FormatBool(r.Paid),
+			formatOptionalTime(r.PaidDate),
+		}
+
+		if err = writer.Write(row); err != nil {
+			return fmt.Errorf("Error writing CSV row for session %d: %w", r.SessionID, err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (s ExportService) WriteJSON(w io.Writer, records SessionRecordCollection) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(records)
+}
+
+func (s ExportService) WriteJSONLines(w io.Writer, records SessionRecordCollection) error {
+	var err error
+
+	encoder := json.NewEncoder(w)
+
+	for _, r := range records {
+		if err = encoder.Encode(r); err != nil {
+			return fmt.Errorf("Error writing session %d: %w", r.SessionID, err)
+		}
+	}
+
+	return nil
+}
+
+/*
+ * newSessionRecord flattens a session. The duration is rounded by the
+ * rounding settings, and hours and amount to the precision setting, so
+ * every output agrees with what the tables show.
+ */
+func (s ExportService) newSessionRecord(session sessions.Session, client clients.Client, project projects.Project, category categories.Category) SessionRecord {
+	duration := s.Settings.Round(session.EndDateTime.Sub(session.StartDateTime))
+
+	result := SessionRecord{
+		SessionID:       session.SessionID,
+		ClientID:        client.ClientID,
+		ClientCode:      client.Code,
+		ClientName:      client.Name,
+		ProjectID:       project.ProjectID,
+		ProjectCode:     project.Code,
+		ProjectName:     project.Name,
+		CategoryID:      category.CategoryID,
+		CategoryCode:    category.Code,
+		CategoryName:    category.Name,
+		StartDateTime:   session.StartDateTime,
+		EndDateTime:     session.EndDateTime,
+		DurationSeconds: int64(duration.Seconds()),
+		DurationHours:   s.Settings.RoundToPrecision(duration.Hours()),
+		Rate:            category.Rate,
+		Amount:          s.Settings.RoundToPrecision(duration.Hours() * category.Rate),
+		Notes:           session.Notes,
+		Invoiced:        session.Invoiced,
+		Paid:            session.Paid,
+	}
+
+	if session.Invoiced {
+		invoiceDate := session.InvoiceDate
+		result.InvoiceDate = &invoiceDate
+	}
+
+	if session.Paid {
+		paidDate := session.PaidDate
+		result.PaidDate = &paidDate
+	}
+
+	return result
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,50 @@
+package exports
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adampresley/mytime/api/categories"
+	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/projects"
+	"github.com/adampresley/mytime/api/sessions"
+	"github.com/adampresley/mytime/api/settings"
+)
+
+func TestNewSessionRecordRounding(t *testing.T) {
+	start := time.Date(2020, time.January, 7, 9, 0, 0, 0, time.UTC)
+	session := sessions.Session{SessionID: 1, StartDateTime: start, EndDateTime: start.Add(67 * time.Minute)}
+	category := categories.Category{Rate: 100}
+
+	tests := []struct {
+		name        string
+		increment   int
+		mode        string
+		precision   int
+		wantSeconds int64
+		wantHours   float64
+		wantAmount  float64
+	}{
+		{name: "no rounding", precision: 2, wantSeconds: 4020, wantHours: 1.12, wantAmount: 111.67},
+		{name: "more places", precision: 4, wantSeconds: 4020, wantHours: 1.1167, wantAmount: 111.6667},
+		{name: "nearest quarter hour", increment: 15, mode: "nearest", precision: 2, wantSeconds: 3600, wantHours: 1, wantAmount: 100},
+		{name: "up to the quarter hour", increment: 15, mode: "up", precision: 2, wantSeconds: 4500, wantHours: 1.25, wantAmount: 125},
+		{name: "whole numbers", increment: 6, mode: "down", precision: 0, wantSeconds: 3960, wantHours: 1, wantAmount: 110},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appSettings := settings.Defaults()
+			appSettings.RoundingIncrement = tt.increment
+			appSettings.RoundingMode = tt.mode
+			appSettings.Precision = tt.precision
+
+			service := NewExportService(ExportServiceConfig{Settings: appSettings})
+			got := service.newSessionRecord(session, clients.Client{}, projects.Project{}, category)
+
+			if got.DurationSeconds != tt.wantSeconds || got.DurationHours != tt.wantHours || got.Amount != tt.wantAmount {
+				t.Errorf("seconds, hours, amount = %d, %v, %v, want %d, %v, %v", got.DurationSeconds, got.DurationHours, got.Amount, tt.wantSeconds, tt.wantHours, tt.wantAmount)
+			}
+		})
+	}
+}
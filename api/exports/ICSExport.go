@@ -0,0 +1,74 @@
+package exports
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const icsTimeFormat string = "20060102T150405Z"
+
+// WriteICS writes sessions as an iCalendar file with one VEVENT per
+// session. The summary starts with the project code in square brackets,
+// which is what "mt import ics" looks for by default.
+func (s ExportService) WriteICS(w io.Writer, records SessionRecordCollection) error {
+	writer := bufio.NewWriter(w)
+	now := time.Now().UTC().Format(icsTimeFormat)
+
+	writeICSLine(writer, "BEGIN:VCALENDAR")
+	writeICSLine(writer, "VERSION:2.0")
+	writeICSLine(writer, "PRODID:-//adampresley//My Time//EN")
+	writeICSLine(writer, "CALSCALE:GREGORIAN")
+
+	for _, r := range records {
+		summary := fmt.Sprintf("[%s] %s - %s", r.ProjectCode, r.ProjectName, r.ClientName)
+
+		writeICSLine(writer, "BEGIN:VEVENT")
+		writeICSLine(writer, fmt.Sprintf("UID:session-%d@mytime", r.SessionID))
+		writeICSLine(writer, "DTSTAMP:"+now)
+		writeICSLine(writer, "DTSTART:"+r.StartDateTime.UTC().Format(icsTimeFormat))
+		writeICSLine(writer, "DTEND:"+r.EndDateTime.UTC().Format(icsTimeFormat))
+		writeICSLine(writer, "SUMMARY:"+escapeICSText(summary))
+		writeICSLine(writer, "DESCRIPTION:"+escapeICSText(r.Notes))
+		writeICSLine(writer, "CATEGORIES:"+escapeICSText(r.CategoryName))
+		writeICSLine(writer, "END:VEVENT")
+	}
+
+	writeICSLine(writer, "END:VCALENDAR")
+	return writer.Flush()
+}
+
+func escapeICSText(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+
+	return replacer.Replace(value)
+}
+
+/*
+ * Lines longer than 75 octets are folded onto continuation lines that
+ * start with a space, taking care not to split a multi-byte character.
+ */
+func writeICSLine(w *bufio.Writer, line string) {
+	const limit = 75
+
+	for len(line) > limit {
+		cut := limit
+
+		for cut > 0 && (line[cut]&0xC0) == 0x80 {
+			cut--
+		}
+
+		w.WriteString(line[:cut] + "\r\n")
+		line = " " + line[cut:]
+	}
+
+	w.WriteString(line + "\r\n")
+}
@@ -0,0 +1,108 @@
+package exports
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WriteTimeclock writes sessions as ledger/hledger timeclock entries, a
+// check-in ("i") and check-out ("o") line per session. The account is
+// "clientCode:projectCode" and the notes are the description.
+func (s ExportService) WriteTimeclock(w io.Writer, records SessionRecordCollection) error {
+	writer := bufio.NewWriter(w)
+
+	for _, r := range sortByStart(records) {
+		account := fmt.Sprintf("%s:%s", timeclockAccountPart(r.ClientCode), timeclockAccountPart(r.ProjectCode))
+		description := strings.Join(strings.Fields(r.Notes), " ")
+
+		if description != "" {
+			fmt.Fprintf(writer, "i %s %s  %s\n", r.StartDateTime.Local().Format("2006/01/02 15:04:05"), account, description)
+		} else {
+			fmt.Fprintf(writer, "i %s %s\n", r.StartDateTime.Local().Format("2006/01/02 15:04:05"), account)
+		}
+
+		fmt.Fprintf(writer, "o %s\n", r.EndDateTime.Local().Format("2006/01/02 15:04:05"))
+	}
+
+	return writer.Flush()
+}
+
+// WriteOrg writes sessions as Org-mode headings: a heading per client,
+// then per project, then one per session holding its CLOCK entry. The
+// session heading is the session notes.
+func (s ExportService) WriteOrg(w io.Writer, records SessionRecordCollection) error {
+	writer := bufio.NewWriter(w)
+	sorted := sortByStart(records)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ClientName != sorted[j].ClientName {
+			return sorted[i].ClientName < sorted[j].ClientName
+		}
+
+		return sorted[i].ProjectName < sorted[j].ProjectName
+	})
+
+	lastClient := -1
+	lastProject := -1
+
+	for _, r := range sorted {
+		if r.ClientID != lastClient {
+			fmt.Fprintf(writer, "* %s\n", r.ClientName)
+			lastClient = r.ClientID
+			lastProject = -1
+		}
+
+		if r.ProjectID != lastProject {
+			fmt.Fprintf(writer, "** %s\n", r.ProjectName)
+			lastProject = r.ProjectID
+		}
+
+		heading := strings.Join(strings.Fields(r.Notes), " ")
+
+		if heading == "" {
+			heading = fmt.Sprintf("Session %d", r.SessionID)
+		}
+
+		duration := r.EndDateTime.Sub(r.StartDateTime)
+		hours := int(duration.Hours())
+		minutes := int(duration.Minutes()) % 60
+
+		fmt.Fprintf(writer, "*** %s\n", heading)
+		fmt.Fprintf(writer, "    :PROPERTIES:\n")
+		fmt.Fprintf(writer, "    :SESSION_ID: %d\n", r.SessionID)
+		fmt.Fprintf(writer, "    :CATEGORY: %s\n", r.CategoryCode)
+		fmt.Fprintf(writer, "    :END:\n")
+		fmt.Fprintf(writer, "    :LOGBOOK:\n")
+		fmt.Fprintf(writer, "    CLOCK: %s--%s => %2d:%02d\n", orgTimestamp(r.StartDateTime), orgTimestamp(r.EndDateTime), hours, minutes)
+		fmt.Fprintf(writer, "    :END:\n")
+	}
+
+	return writer.Flush()
+}
+
+func orgTimestamp(t time.Time) string {
+	return t.Local().Format("[2006-01-02 Mon 15:04]")
+}
+
+func sortByStart(records SessionRecordCollection) SessionRecordCollection {
+	result := make(SessionRecordCollection, len(records))
+	copy(result, records)
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].StartDateTime.Before(result[j].StartDateTime)
+	})
+
+	return result
+}
+
+/*
+ * Two spaces end an account name in timeclock files, and colons
+ * separate account levels, so neither can appear inside a part.
+ */
+func timeclockAccountPart(value string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(value), " "), ":", "-")
+}
@@ -0,0 +1,32 @@
+package exports
+
+import "time"
+
+// SessionRecord is a flattened view of a session with the client, project,
+// and category resolved. This is what the export formats write out. The
+// duration and amount are rounded as the settings say.
+type SessionRecord struct {
+	SessionID       int        `json:"sessionID"`
+	ClientID        int        `json:"clientID"`
+	ClientCode      string     `json:"clientCode"`
+	ClientName      string     `json:"clientName"`
+	ProjectID       int        `json:"projectID"`
+	ProjectCode     string     `json:"projectCode"`
+	ProjectName     string     `json:"projectName"`
+	CategoryID      int        `json:"categoryID"`
+	CategoryCode    string     `json:"categoryCode"`
+	CategoryName    string     `json:"categoryName"`
+	StartDateTime   time.Time  `json:"startDateTime"`
+	EndDateTime     time.Time  `json:"endDateTime"`
+	DurationSeconds int64      `json:"durationSeconds"`
+	DurationHours   float64    `json:"durationHours"`
+	Rate            float64    `json:"rate"`
+	Amount          float64    `json:"amount"`
+	Notes           string     `json:"notes"`
+	Invoiced        bool       `json:"invoiced"`
+	InvoiceDate     *time.Time `json:"invoiceDate"`
+	Paid            bool       `json:"paid"`
+	PaidDate        *time.Time `json:"paidDate"`
+}
+
+type SessionRecordCollection []SessionRecord
@@ -0,0 +1,162 @@
+package exports
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const timewarriorTimeFormat string = "20060102T150405Z"
+
+// TimewarriorOptions controls how projects and categories become
+// Timewarrior tags. A project's tag is its code, with ProjectPrefix in
+// front, unless ProjectTags maps a tag to that code. ProjectTags is keyed
+// by tag, the same way round as the Timewarrior import's ProjectMap.
+// Categories work the same way.
+type TimewarriorOptions struct {
+	ProjectPrefix  string
+	CategoryPrefix string
+	ProjectTags    map[string]string
+	CategoryTags   map[string]string
+}
+
+// WriteTimewarrior writes sessions as lines of a Timewarrior data file.
+// The session notes become the interval's annotation.
+func (s ExportService) WriteTimewarrior(w io.Writer, records SessionRecordCollection, options TimewarriorOptions) error {
+	writer := bufio.NewWriter(w)
+
+	for _, r := range sortByStart(records) {
+		fmt.Fprintln(writer, timewarriorLine(r, options))
+	}
+
+	return writer.Flush()
+}
+
+// WriteTimewarriorDataDir adds sessions to the monthly data files
+// (YYYY-MM.data) in a Timewarrior data directory. Intervals already in a
+// file are left alone, so exporting twice doesn't double up. The number
+// of intervals added is returned.
+func (s ExportService) WriteTimewarriorDataDir(dir string, records SessionRecordCollection, options TimewarriorOptions) (int, error) {
+	var (
+		err      error
+		existing []byte
+		f        *os.File
+	)
+
+	result := 0
+	months := make(map[string][]string)
+	monthOrder := make([]string, 0, 12)
+
+	for _, r := range sortByStart(records) {
+		month := r.StartDateTime.UTC().Format("2006-01")
+
+		if _, ok := months[month]; !ok {
+			monthOrder = append(monthOrder, month)
+		}
+
+		months[month] = append(months[month], timewarriorLine(r, options))
+	}
+
+	for _, month := range monthOrder {
+		fileName := filepath.Join(dir, month+".data")
+		seen := make(map[string]bool)
+
+		if existing, err = ioutil.ReadFile(fileName); err != nil && !os.IsNotExist(err) {
+			return result, fmt.Errorf("Error reading %s: %w", fileName, err)
+		}
+
+		for _, line := range strings.Split(string(existing), "\n") {
+			seen[strings.TrimSpace(line)] = true
+		}
+
+		if f, err = os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644); err != nil {
+			return result, fmt.Errorf("Error opening %s: %w", fileName, err)
+		}
+
+		for _, line := range months[month] {
+			if seen[line] {
+				continue
+			}
+
+			if _, err = fmt.Fprintln(f, line); err != nil {
+				f.Close()
+				return result, fmt.Errorf("Error writing to %s: %w", fileName, err)
+			}
+
+			seen[line] = true
+			result++
+		}
+
+		if err = f.Close(); err != nil {
+			return result, fmt.Errorf("Error closing %s: %w", fileName, err)
+		}
+	}
+
+	return result, nil
+}
+
+func timewarriorLine(r SessionRecord, options TimewarriorOptions) string {
+	tags := []string{
+		timewarriorTag(r.ProjectCode, options.ProjectPrefix, options.ProjectTags),
+		timewarriorTag(r.CategoryCode, options.CategoryPrefix, options.CategoryTags),
+	}
+
+	quoted := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		if tag != "" {
+			quoted = append(quoted, quoteTimewarrior(tag, false))
+		}
+	}
+
+	result := fmt.Sprintf("inc %s - %s # %s", r.StartDateTime.UTC().Format(timewarriorTimeFormat), r.EndDateTime.UTC().Format(timewarriorTimeFormat), strings.Join(quoted, " "))
+
+	if r.Notes != "" {
+		result += " # " + quoteTimewarrior(r.Notes, true)
+	}
+
+	return result
+}
+
+// timewarriorTag picks the tag for a code. When several tags map to the
+// code the first in sorted order is used, so every export agrees.
+func timewarriorTag(code, prefix string, tags map[string]string) string {
+	result := ""
+
+	for tag, mappedCode := range tags {
+		if mappedCode == code && (result == "" || tag < result) {
+			result = tag
+		}
+	}
+
+	if result != "" || code == "" {
+		return result
+	}
+
+	return prefix + code
+}
+
+/*
+ * quoteTimewarrior puts a value in double quotes when it needs them. A
+ * data file holds one interval per line, so backslashes, quotes, newlines,
+ * and tabs are escaped, and the importer turns them back exactly.
+ */
+func quoteTimewarrior(value string, always bool) string {
+	if !always && !strings.ContainsAny(value, " \"#\\\n\r\t") {
+		return value
+	}
+
+	return `"` + timewarriorEscaper.Replace(value) + `"`
+}
+
+var timewarriorEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
@@ -0,0 +1,40 @@
+package exports
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTimewarriorTags(t *testing.T) {
+	start := time.Date(2020, time.January, 7, 9, 0, 0, 0, time.UTC)
+	records := SessionRecordCollection{{ProjectCode: "web", CategoryCode: "dev", StartDateTime: start, EndDateTime: start.Add(time.Hour)}}
+
+	tests := []struct {
+		name    string
+		options TimewarriorOptions
+		want    string
+	}{
+		{name: "codes", want: "# web dev"},
+		{name: "prefixes", options: TimewarriorOptions{ProjectPrefix: "project:", CategoryPrefix: "category:"}, want: "# project:web category:dev"},
+		{name: "mapped as tag=code", options: TimewarriorOptions{ProjectTags: map[string]string{"website": "web"}, CategoryTags: map[string]string{"coding": "dev"}}, want: "# website coding"},
+		{name: "a mapped tag replaces the prefix", options: TimewarriorOptions{ProjectPrefix: "project:", ProjectTags: map[string]string{"website": "web"}}, want: "# website dev"},
+		{name: "several tags for a code", options: TimewarriorOptions{ProjectTags: map[string]string{"www": "web", "site": "web", "website": "web"}}, want: "# site dev"},
+		{name: "tags for other codes", options: TimewarriorOptions{ProjectTags: map[string]string{"web": "api"}}, want: "# web dev"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			if err := NewExportService(ExportServiceConfig{}).WriteTimewarrior(&out, records, tt.options); err != nil {
+				t.Fatalf("WriteTimewarrior() = %v", err)
+			}
+
+			if got := strings.TrimSpace(out.String()); !strings.HasSuffix(got, tt.want) {
+				t.Errorf("WriteTimewarrior() = %q, want it to end %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"sort"
+	"strings"
+)
+
+// Codes is the codes used by one kind of record, such as every client's.
+// Archived records belong in it too, as they can be unarchived. Codes
+// differing only in case are the same code.
+type Codes []string
+
+// Taken reports whether code is already used.
+func (c Codes) Taken(code string) bool {
+	return c.Match(code) >= 0
+}
+
+/*
+ * Match returns the index of the code matching code, or -1 when none do.
+ * An exact match wins over one differing only in case, so records saved
+ * before codes ignored case can each still be reached.
+ */
+func (c Codes) Match(code string) int {
+	result := -1
+
+	for index, candidate := range c {
+		if candidate == code {
+			return index
+		}
+
+		if result < 0 && strings.EqualFold(candidate, code) {
+			result = index
+		}
+	}
+
+	return result
+}
+
+/*
+ * Duplicates returns the codes used more than once, such as "acme" and
+ * "ACME", grouped together and sorted.
+ */
+func (c Codes) Duplicates() [][]string {
+	groups := make(map[string][]string)
+
+	for _, code := range c {
+		key := strings.ToLower(code)
+		groups[key] = append(groups[key], code)
+	}
+
+	result := make([][]string, 0, 2)
+
+	for _, group := range groups {
+		if len(group) > 1 {
+			sort.Strings(group)
+			result = append(result, group)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i][0]) < strings.ToLower(result[j][0])
+	})
+
+	return result
+}
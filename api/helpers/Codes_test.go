@@ -0,0 +1,57 @@
+package helpers
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCodesMatch(t *testing.T) {
+	codes := Codes{"ACME", "web", "acme", "Api"}
+
+	tests := []struct {
+		code string
+		want int
+	}{
+		{code: "acme", want: 2},
+		{code: "ACME", want: 0},
+		{code: "Acme", want: 0},
+		{code: "WEB", want: 1},
+		{code: "api", want: 3},
+		{code: "ac", want: -1},
+		{code: "", want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := codes.Match(tt.code); got != tt.want {
+				t.Errorf("Match(%q) = %d, want %d", tt.code, got, tt.want)
+			}
+
+			if got := codes.Taken(tt.code); got != (tt.want >= 0) {
+				t.Errorf("Taken(%q) = %v, want %v", tt.code, got, tt.want >= 0)
+			}
+		})
+	}
+}
+
+func TestCodesDuplicates(t *testing.T) {
+	tests := []struct {
+		name  string
+		codes Codes
+		want  string
+	}{
+		{name: "none", codes: Codes{"acme", "globex"}, want: "[]"},
+		{name: "empty", codes: Codes{}, want: "[]"},
+		{name: "differing in case", codes: Codes{"web", "acme", "Web"}, want: "[[Web web]]"},
+		{name: "sorted groups", codes: Codes{"web", "WEB", "api", "Acme", "acme", "ACME"}, want: "[[ACME Acme acme] [WEB web]]"},
+		{name: "exact copies", codes: Codes{"api", "api"}, want: "[[api api]]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprint(tt.codes.Duplicates()); got != tt.want {
+				t.Errorf("Duplicates() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
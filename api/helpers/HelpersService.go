@@ -1,15 +1,17 @@
 package helpers
 
 import (
+	"fmt"
 	"math/rand"
 	"strings"
 	"time"
+	"unicode"
 )
 
 type HelperServicer interface {
 	GenerateID() string
 	GenerateRandom(num int) string
-	CreateAutoCode(name string) string
+	CreateAutoCode(name string, taken func(code string) bool) string
 }
 
 type HelperService struct {
@@ -38,14 +40,39 @@ func (s HelperService) GenerateRandom(num int) string {
 	return string(b)
 }
 
-func (s HelperService) CreateAutoCode(name string) string {
-	result := ""
+// CreateAutoCode makes a code from a name, such as "acme-corp" for "Acme
+// Corp.". When the code is taken a number is added, as in "acme-corp2".
+// Names with no letters or digits get a random code.
+func (s HelperService) CreateAutoCode(name string, taken func(code string) bool) string {
+	base := slugify(name)
 
-	if len(name) > 3 {
-		result = strings.ToLower(name[0:4])
-	} else {
-		result = s.GenerateRandom(4)
+	if base == "" {
+		base = strings.ToLower(s.GenerateRandom(4))
+	}
+
+	result := base
+
+	for index := 2; taken(result); index++ {
+		result = fmt.Sprintf("%s%d", base, index)
 	}
 
 	return result
 }
+
+func slugify(name string) string {
+	var b strings.Builder
+
+	lastDash := false
+
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			lastDash = false
+		} else if !lastDash && b.Len() > 0 {
+			b.WriteRune('-')
+			lastDash = true
+		}
+	}
+
+	return strings.TrimRight(b.String(), "-")
+}
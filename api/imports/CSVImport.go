@@ -0,0 +1,323 @@
+package imports
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adampresley/mytime/api/apperrors"
+)
+
+// CSVMapping describes which columns of a CSV file hold which parts of a
+// time entry. Start and end may either be single date-time columns, or
+// split into date and time columns. When there is no end column the end
+// is worked out from the duration.
+type CSVMapping struct {
+	Client    string
+	Project   string
+	Category  string
+	Notes     string
+	Tags      string
+	Start     string
+	End       string
+	StartDate string
+	StartTime string
+	EndDate   string
+	EndTime   string
+	Duration  string
+
+	DateFormat     string
+	TimeFormat     string
+	DateTimeFormat string
+	TagSeparator   string
+	Delimiter      rune
+}
+
+var CSVPresets = map[string]CSVMapping{
+	"generic": {
+		Client:         "client",
+		Project:        "project",
+		Category:       "category",
+		Notes:          "notes",
+		Tags:           "tags",
+		Start:          "start",
+		End:            "end",
+		Duration:       "duration",
+		DateTimeFormat: time.RFC3339,
+		TagSeparator:   ",",
+		Delimiter:      ',',
+	},
+	"toggl": {
+		Client:       "Client",
+		Project:      "Project",
+		Category:     "Task",
+		Notes:        "Description",
+		Tags:         "Tags",
+		StartDate:    "Start date",
+		StartTime:    "Start time",
+		EndDate:      "End date",
+		EndTime:      "End time",
+		Duration:     "Duration",
+		DateFormat:   "2006-01-02",
+		TimeFormat:   "15:04:05",
+		TagSeparator: ",",
+		Delimiter:    ',',
+	},
+	"clockify": {
+		Client:       "Client",
+		Project:      "Project",
+		Category:     "Task",
+		Notes:        "Description",
+		Tags:         "Tags",
+		StartDate:    "Start Date",
+		StartTime:    "Start Time",
+		EndDate:      "End Date",
+		EndTime:      "End Time",
+		Duration:     "Duration (h)",
+		DateFormat:   "01/02/2006",
+		TimeFormat:   "03:04:05 PM",
+		TagSeparator: ",",
+		Delimiter:    ',',
+	},
+}
+
+/*
+ * Formats tried when a value doesn't match the configured one. Exports
+ * from other tools change with the user's locale settings.
+ */
+var fallbackTimeFormats = []string{"15:04:05", "15:04", "3:04:05 PM", "3:04 PM", "03:04:05 PM", "03:04 PM", "3:04:05PM", "3:04PM"}
+var fallbackDateTimeFormats = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+// SetColumn changes which column holds a field of the mapping. Field
+// names are those used by the generic preset, plus start-date,
+// start-time, end-date, and end-time.
+func (m *CSVMapping) SetColumn(field, column string) error {
+	switch strings.ToLower(field) {
+	case "client":
+		m.Client = column
+	case "project":
+		m.Project = column
+	case "category":
+		m.Category = column
+	case "notes":
+		m.Notes = column
+	case "tags":
+		m.Tags = column
+	case "start":
+		m.Start = column
+	case "end":
+		m.End = column
+	case "start-date":
+		m.StartDate = column
+	case "start-time":
+		m.StartTime = column
+	case "end-date":
+		m.EndDate = column
+	case "end-time":
+		m.EndTime = column
+	case "duration":
+		m.Duration = column
+	default:
+		return apperrors.Validation("unknown column field '%s'", field)
+	}
+
+	return nil
+}
+
+func (s ImportService) ReadCSV(r io.Reader, mapping CSVMapping) (ImportEntryCollection, error) {
+	var (
+		err    error
+		header []string
+		row    []string
+	)
+
+	result := make(ImportEntryCollection, 0, 100)
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	if mapping.Delimiter != 0 {
+		reader.Comma = mapping.Delimiter
+	}
+
+	if header, err = reader.Read(); err != nil {
+		return result, fmt.Errorf("Error reading CSV header: %w", err)
+	}
+
+	columns := make(map[string]int)
+
+	for index, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+
+	if _, ok := columns[strings.ToLower(mapping.Project)]; !ok {
+		return result, apperrors.Validation("The CSV file has no '%s' column for the project", mapping.Project)
+	}
+
+	line := 1
+
+	for {
+		line++
+
+		if row, err = reader.Read(); err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return result, apperrors.Validation("Error reading CSV line %d: %s", line, err.Error())
+		}
+
+		value := func(column string) string {
+			if column == "" {
+				return ""
+			}
+
+			if index, ok := columns[strings.ToLower(column)]; ok && index < len(row) {
+				return strings.TrimSpace(row[index])
+			}
+
+			return ""
+		}
+
+		entry := ImportEntry{
+			Source:   fmt.Sprintf("line %d", line),
+			Client:   value(mapping.Client),
+			Project:  value(mapping.Project),
+			Category: value(mapping.Category),
+			Notes:    value(mapping.Notes),
+			Tags:     splitTags(value(mapping.Tags), mapping.TagSeparator),
+		}
+
+		if entry.Start, err = parseCSVDateTime(value(mapping.Start), value(mapping.StartDate), value(mapping.StartTime), mapping); err != nil {
+			return result, apperrors.Validation("Invalid start on CSV line %d: %s", line, err.Error())
+		}
+
+		if value(mapping.End) != "" || value(mapping.EndTime) != "" {
+			endDate := value(mapping.EndDate)
+
+			if endDate == "" {
+				endDate = value(mapping.StartDate)
+			}
+
+			if entry.End, err = parseCSVDateTime(value(mapping.End), endDate, value(mapping.EndTime), mapping); err != nil {
+				return result, apperrors.Validation("Invalid end on CSV line %d: %s", line, err.Error())
+			}
+		} else {
+			var duration time.Duration
+
+			if duration, err = parseCSVDuration(value(mapping.Duration)); err != nil {
+				return result, apperrors.Validation("Invalid duration on CSV line %d: %s", line, err.Error())
+			}
+
+			entry.End = entry.Start.Add(duration)
+		}
+
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+func parseCSVDateTime(dateTime, date, clock string, mapping CSVMapping) (time.Time, error) {
+	if dateTime != "" {
+		return parseWithFallbacks(dateTime, mapping.DateTimeFormat, fallbackDateTimeFormats)
+	}
+
+	if date == "" {
+		return time.Time{}, fmt.Errorf("no date or date-time value")
+	}
+
+	dateFormats := []string{"2006-01-02", "01/02/2006", "02/01/2006"}
+
+	if clock == "" {
+		return parseWithFallbacks(date, mapping.DateFormat, dateFormats)
+	}
+	layouts := make([]string, 0, len(dateFormats)*len(fallbackTimeFormats))
+
+	for _, d := range dateFormats {
+		for _, t := range fallbackTimeFormats {
+			layouts = append(layouts, d+" "+t)
+		}
+	}
+
+	return parseWithFallbacks(date+" "+clock, mapping.DateFormat+" "+mapping.TimeFormat, layouts)
+}
+
+func parseWithFallbacks(value, layout string, fallbacks []string) (time.Time, error) {
+	var err error
+	var result time.Time
+
+	if strings.TrimSpace(layout) != "" {
+		if result, err = time.ParseInLocation(layout, value, time.Local); err == nil {
+			return result, nil
+		}
+	}
+
+	for _, fallback := range fallbacks {
+		if result, err = time.ParseInLocation(fallback, value, time.Local); err == nil {
+			return result, nil
+		}
+	}
+
+	return result, fmt.Errorf("cannot parse '%s'", value)
+}
+
+/*
+ * Durations come as "1:30:00", "1:30", or decimal hours like "1.5"
+ */
+func parseCSVDuration(value string) (time.Duration, error) {
+	var err error
+	var hours float64
+
+	if value == "" {
+		return 0, fmt.Errorf("no end time or duration")
+	}
+
+	if strings.Contains(value, ":") {
+		parts := strings.Split(value, ":")
+		units := []time.Duration{time.Hour, time.Minute, time.Second}
+		result := time.Duration(0)
+
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("cannot parse '%s'", value)
+		}
+
+		for index, part := range parts {
+			var n int
+
+			if n, err = strconv.Atoi(part); err != nil {
+				return 0, fmt.Errorf("cannot parse '%s'", value)
+			}
+
+			result += time.Duration(n) * units[index]
+		}
+
+		return result, nil
+	}
+
+	if hours, err = strconv.ParseFloat(value, 64); err != nil {
+		return 0, fmt.Errorf("cannot parse '%s'", value)
+	}
+
+	return time.Duration(hours * float64(time.Hour)), nil
+}
+
+func splitTags(value, separator string) []string {
+	result := make([]string, 0, 5)
+
+	if separator == "" {
+		separator = ","
+	}
+
+	for _, tag := range strings.Split(value, separator) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
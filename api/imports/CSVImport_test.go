@@ -0,0 +1,136 @@
+package imports
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadCSV(t *testing.T) {
+	semicolons := CSVPresets["generic"]
+	semicolons.Delimiter = ';'
+
+	tests := []struct {
+		name    string
+		mapping CSVMapping
+		csv     string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "generic with start and end",
+			mapping: CSVPresets["generic"],
+			csv: "client,project,category,notes,tags,start,end\n" +
+				"Acme,web,dev,Fixed a bug,\"urgent, backend\",2020-01-07T09:00:00,2020-01-07T10:30:00\n",
+			want: []string{"Acme/web/dev [urgent,backend] 2020-01-07 09:00 - 2020-01-07 10:30: Fixed a bug"},
+		},
+		{
+			name:    "durations as hours and minutes or decimal hours",
+			mapping: CSVPresets["generic"],
+			csv: "project,start,duration\n" +
+				"web,2020-01-07 09:00,1:30\n" +
+				"web,2020-01-08 09:00,0.25\n" +
+				"web,2020-01-09 09:00,0:00:45\n",
+			want: []string{
+				"/web/ [] 2020-01-07 09:00 - 2020-01-07 10:30",
+				"/web/ [] 2020-01-08 09:00 - 2020-01-08 09:15",
+				"/web/ [] 2020-01-09 09:00 - 2020-01-09 09:00",
+			},
+		},
+		{
+			name:    "toggl with split dates and times",
+			mapping: CSVPresets["toggl"],
+			csv: "\ufeffClient,Project,Task,Description,Tags,Start date,Start time,End date,End time,Duration\n" +
+				"Acme,web,dev,Late night,,2020-01-07,23:30:00,2020-01-08,00:15:00,00:45:00\n",
+			want: []string{"Acme/web/dev [] 2020-01-07 23:30 - 2020-01-08 00:15: Late night"},
+		},
+		{
+			name:    "clockify with a 12 hour clock",
+			mapping: CSVPresets["clockify"],
+			csv: "Client,Project,Task,Description,Tags,Start Date,Start Time,End Date,End Time,Duration (h)\n" +
+				"Acme,web,,Call,meeting,01/07/2020,01:00:00 PM,01/07/2020,02:00:00 PM,1.00\n",
+			want: []string{"Acme/web/ [meeting] 2020-01-07 13:00 - 2020-01-07 14:00: Call"},
+		},
+		{
+			name: "end time with no end date is on the start date",
+			mapping: CSVMapping{
+				Project:   "project",
+				StartDate: "date",
+				StartTime: "from",
+				EndTime:   "to",
+			},
+			csv: "Project,Date,From,To\n" +
+				"web,2020-01-07,9:00,5:30 PM\n",
+			want: []string{"/web/ [] 2020-01-07 09:00 - 2020-01-07 17:30"},
+		},
+		{
+			name:    "other delimiter and column case",
+			mapping: semicolons,
+			csv: "PROJECT;Start;End\n" +
+				"web;2020-01-07 09:00;2020-01-07 10:00\n",
+			want: []string{"/web/ [] 2020-01-07 09:00 - 2020-01-07 10:00"},
+		},
+		{
+			name:    "no project column",
+			mapping: CSVPresets["generic"],
+			csv:     "client,start,end\nAcme,2020-01-07 09:00,2020-01-07 10:00\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad duration",
+			mapping: CSVPresets["generic"],
+			csv:     "project,start,duration\nweb,2020-01-07 09:00,an hour\n",
+			wantErr: true,
+		},
+		{
+			name:    "no end or duration",
+			mapping: CSVPresets["generic"],
+			csv:     "project,start\nweb,2020-01-07 09:00\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad start",
+			mapping: CSVPresets["generic"],
+			csv:     "project,start,end\nweb,yesterday,2020-01-07 10:00\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ImportService{}.ReadCSV(strings.NewReader(tt.csv), tt.mapping)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ReadCSV() = %v, want an error", describeEntries(entries, time.Local))
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ReadCSV() = %v", err)
+			}
+
+			if got := describeEntries(entries, time.Local); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestCSVMappingSetColumn(t *testing.T) {
+	mapping := CSVPresets["generic"]
+
+	if err := mapping.SetColumn("Start-Date", "Day"); err != nil || mapping.StartDate != "Day" {
+		t.Errorf("SetColumn(start-date) = %v, StartDate = %q", err, mapping.StartDate)
+	}
+
+	if err := mapping.SetColumn("colour", "x"); err == nil {
+		t.Errorf("SetColumn(colour) = nil, want an error")
+	}
+
+	if CSVPresets["generic"].StartDate != "" {
+		t.Errorf("SetColumn changed the preset")
+	}
+}
@@ -0,0 +1,332 @@
+package imports
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adampresley/mytime/api/apperrors"
+)
+
+// DefaultICSPattern matches event summaries that start with a project
+// code in square brackets, such as "[web] Weekly sync".
+var DefaultICSPattern = regexp.MustCompile(`^\s*\[([^\]]+)\]\s*`)
+
+// ICSOptions says which events to import. Pattern picks the events and
+// their project code. Only events between From and To are imported,
+// which also bounds recurring events that repeat forever. A zero From
+// has no lower bound, and a zero To is now.
+type ICSOptions struct {
+	Pattern *regexp.Regexp
+	From    time.Time
+	To      time.Time
+}
+
+type icsEvent struct {
+	uid          string
+	summary      string
+	description  string
+	categories   []string
+	start        time.Time
+	end          time.Time
+	duration     string
+	location     *time.Location
+	allDay       bool
+	cancelled    bool
+	rrule        string
+	rdates       []time.Time
+	exdates      []time.Time
+	exdateDays   map[string]bool
+	recurrenceID time.Time
+	occurrence   bool
+}
+
+/*
+ * ReadICS reads the events of an iCalendar file. Only events whose summary
+ * matches the pattern become entries. The pattern's first capture group is
+ * the project code, and the rest of the summary becomes the notes, except
+ * for events written by "mt export ics", whose notes are their
+ * description. Recurring events become one entry per occurrence. All-day
+ * and cancelled events are ignored.
+ */
+func (s ImportService) ReadICS(r io.Reader, options ICSOptions) (ImportEntryCollection, error) {
+	var (
+		err    error
+		events []icsEvent
+	)
+
+	result := make(ImportEntryCollection, 0, 50)
+	to := options.To
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	if events, err = parseICS(r); err != nil {
+		return result, err
+	}
+
+	if events, err = expandICS(events, options.From, to); err != nil {
+		return result, err
+	}
+
+	for _, event := range events {
+		match := options.Pattern.FindStringSubmatch(event.summary)
+
+		if match == nil || len(match) < 2 {
+			continue
+		}
+
+		notes := strings.TrimSpace(options.Pattern.ReplaceAllString(event.summary, ""))
+
+		if notes == "" || isExportedEvent(event) {
+			notes = event.description
+		}
+
+		source := fmt.Sprintf("event %s", event.uid)
+
+		if event.occurrence {
+			source = fmt.Sprintf("event %s on %s", event.uid, event.start.Format("2006-01-02"))
+		}
+
+		result = append(result, ImportEntry{
+			Source:  source,
+			Start:   event.start,
+			End:     event.end,
+			Project: strings.TrimSpace(match[1]),
+			Tags:    event.categories,
+			Notes:   notes,
+		})
+	}
+
+	return result, nil
+}
+
+/*
+ * isExportedEvent reports whether "mt export ics" wrote an event. Its
+ * summary holds the project and client names, and the notes are in the
+ * description.
+ */
+func isExportedEvent(event icsEvent) bool {
+	return strings.HasPrefix(event.uid, "session-") && strings.HasSuffix(event.uid, "@mytime")
+}
+
+func parseICS(r io.Reader) ([]icsEvent, error) {
+	var (
+		err     error
+		current *icsEvent
+	)
+
+	result := make([]icsEvent, 0, 50)
+	lines := make([]string, 0, 500)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	/*
+	 * Unfold continuation lines first
+	 */
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return result, fmt.Errorf("Error reading calendar: %w", err)
+	}
+
+	for _, line := range lines {
+		name, params, value := splitICSLine(line)
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current = &icsEvent{location: time.Local, exdateDays: make(map[string]bool)}
+
+		case name == "END" && value == "VEVENT" && current != nil:
+			if current.end.IsZero() && current.duration != "" {
+				var d time.Duration
+
+				if d, err = parseICSDuration(current.duration); err != nil {
+					return result, apperrors.Validation("Invalid DURATION '%s': %s", current.duration, err.Error())
+				}
+
+				current.end = current.start.Add(d)
+			}
+
+			if current.end.IsZero() {
+				current.end = current.start
+			}
+
+			result = append(result, *current)
+			current = nil
+
+		case current == nil:
+			continue
+
+		case name == "UID":
+			current.uid = value
+
+		case name == "SUMMARY":
+			current.summary = unescapeICSText(value)
+
+		case name == "DESCRIPTION":
+			current.description = unescapeICSText(value)
+
+		case name == "CATEGORIES":
+			for _, c := range strings.Split(value, ",") {
+				if c = strings.TrimSpace(unescapeICSText(c)); c != "" {
+					current.categories = append(current.categories, c)
+				}
+			}
+
+		case name == "DTSTART":
+			if current.start, current.allDay, err = parseICSTime(value, params); err != nil {
+				return result, apperrors.Validation("Invalid DTSTART '%s': %s", value, err.Error())
+			}
+
+			current.location = icsLocation(value, params)
+
+		case name == "DURATION":
+			current.duration = value
+
+		case name == "STATUS":
+			current.cancelled = strings.EqualFold(value, "CANCELLED")
+
+		case name == "RRULE":
+			current.rrule = value
+
+		case name == "RECURRENCE-ID":
+			if current.recurrenceID, _, err = parseICSTime(value, params); err != nil {
+				return result, apperrors.Validation("Invalid RECURRENCE-ID '%s': %s", value, err.Error())
+			}
+
+		case name == "RDATE", name == "EXDATE":
+			for _, v := range strings.Split(value, ",") {
+				t, allDay, err := parseICSTime(v, params)
+
+				if err != nil {
+					return result, apperrors.Validation("Invalid %s '%s': %s", name, v, err.Error())
+				}
+
+				switch {
+				case name == "RDATE":
+					current.rdates = append(current.rdates, t)
+
+				case allDay:
+					current.exdateDays[t.Format("20060102")] = true
+
+				default:
+					current.exdates = append(current.exdates, t)
+				}
+			}
+
+		case name == "DTEND":
+			if current.end, _, err = parseICSTime(value, params); err != nil {
+				return result, apperrors.Validation("Invalid DTEND '%s': %s", value, err.Error())
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func parseICSTime(value string, params map[string]string) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, time.Local)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t.Local(), false, err
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, icsLocation(value, params))
+	return t.Local(), false, err
+}
+
+// icsLocation is the time zone a time was written in. Recurring events
+// repeat at the same time of day in it.
+func icsLocation(value string, params map[string]string) *time.Location {
+	if strings.HasSuffix(value, "Z") {
+		return time.UTC
+	}
+
+	if tzid, ok := params["TZID"]; ok {
+		if location, err := time.LoadLocation(strings.Trim(tzid, `"`)); err == nil {
+			return location
+		}
+	}
+
+	return time.Local
+}
+
+var icsDurationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseICSDuration reads a DURATION such as "PT1H30M" or "P1D".
+func parseICSDuration(value string) (time.Duration, error) {
+	var result time.Duration
+
+	match := icsDurationPattern.FindStringSubmatch(strings.ToUpper(value))
+
+	if match == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("not a duration")
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+
+	for index, unit := range units {
+		if match[index+2] == "" {
+			continue
+		}
+
+		n, _ := strconv.Atoi(match[index+2])
+		result += time.Duration(n) * unit
+	}
+
+	if match[1] == "-" {
+		return 0, fmt.Errorf("a negative duration can't be a session")
+	}
+
+	return result, nil
+}
+
+func splitICSLine(line string) (string, map[string]string, string) {
+	params := make(map[string]string)
+	colon := strings.Index(line, ":")
+
+	if colon < 0 {
+		return strings.ToUpper(line), params, ""
+	}
+
+	nameAndParams := strings.Split(line[:colon], ";")
+
+	for _, p := range nameAndParams[1:] {
+		if kv := strings.SplitN(p, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = kv[1]
+		}
+	}
+
+	return strings.ToUpper(nameAndParams[0]), params, line[colon+1:]
+}
+
+func unescapeICSText(value string) string {
+	replacer := strings.NewReplacer(
+		`\n`, "\n",
+		`\N`, "\n",
+		`\,`, ",",
+		`\;`, ";",
+		`\\`, `\`,
+	)
+
+	return replacer.Replace(value)
+}
@@ -0,0 +1,166 @@
+package imports
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// calendar wraps events, given as lines separated by "|", in a
+// VCALENDAR with CRLF line endings.
+func calendar(events ...string) string {
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0"}
+
+	for _, event := range events {
+		lines = append(lines, "BEGIN:VEVENT")
+		lines = append(lines, strings.Split(event, "|")...)
+		lines = append(lines, "END:VEVENT")
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func TestReadICS(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+
+	singles := calendar(
+		"UID:1|SUMMARY:[web] Fix|  bug|CATEGORIES:dev,urgent|DTSTART:20200107T140000Z|DTEND:20200107T153000Z",
+		"UID:2|SUMMARY:Lunch|DTSTART:20200107T170000Z|DTEND:20200107T180000Z",
+		"UID:3|SUMMARY:[web] Offsite|DTSTART;VALUE=DATE:20200108|DTEND;VALUE=DATE:20200109",
+		"UID:4|SUMMARY:[api]|DESCRIPTION:Standup\\, daily|DTSTART;TZID=America/New_York:20200109T090000|DURATION:PT45M",
+		"UID:5|SUMMARY:[api] Cancelled|STATUS:CANCELLED|DTSTART:20200110T140000Z|DTEND:20200110T150000Z",
+	)
+
+	tests := []struct {
+		name    string
+		ics     string
+		from    time.Time
+		to      time.Time
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "single events",
+			ics:  singles,
+			to:   time.Date(2020, time.December, 31, 0, 0, 0, 0, newYork),
+			want: []string{
+				"/web/ [dev,urgent] 2020-01-07 09:00 - 2020-01-07 10:30: Fix bug",
+				"/api/ [] 2020-01-09 09:00 - 2020-01-09 09:45: Standup, daily",
+			},
+		},
+		{
+			name: "from and to",
+			ics:  singles,
+			from: time.Date(2020, time.January, 8, 0, 0, 0, 0, newYork),
+			to:   time.Date(2020, time.January, 9, 10, 0, 0, 0, newYork),
+			want: []string{"/api/ [] 2020-01-09 09:00 - 2020-01-09 09:45: Standup, daily"},
+		},
+		{
+			name: "exported events keep their notes",
+			ics:  calendar("UID:session-7@mytime|SUMMARY:[web] Web Site (Acme)|DESCRIPTION:Fixed the login\\; bug\\nfor real|DTSTART:20200107T140000Z|DTEND:20200107T150000Z"),
+			to:   time.Date(2020, time.December, 31, 0, 0, 0, 0, newYork),
+			want: []string{"/web/ [] 2020-01-07 09:00 - 2020-01-07 10:00: Fixed the login; bug\nfor real"},
+		},
+		{
+			name: "weekly series across daylight saving with an exception and overrides",
+			ics: calendar(
+				"UID:sync|SUMMARY:[web] Sync|DTSTART;TZID=America/New_York:20200302T090000|DTEND;TZID=America/New_York:20200302T093000|RRULE:FREQ=WEEKLY;COUNT=5|EXDATE;TZID=America/New_York:20200309T090000",
+				"UID:sync|RECURRENCE-ID;TZID=America/New_York:20200316T090000|SUMMARY:[web] Moved sync|DTSTART;TZID=America/New_York:20200317T140000|DTEND;TZID=America/New_York:20200317T150000",
+				"UID:sync|RECURRENCE-ID;TZID=America/New_York:20200323T090000|SUMMARY:[web] Sync|STATUS:CANCELLED|DTSTART;TZID=America/New_York:20200323T090000|DTEND;TZID=America/New_York:20200323T093000",
+			),
+			to: time.Date(2020, time.December, 31, 0, 0, 0, 0, newYork),
+			want: []string{
+				"/web/ [] 2020-03-02 09:00 - 2020-03-02 09:30: Sync",
+				"/web/ [] 2020-03-17 14:00 - 2020-03-17 15:00: Moved sync",
+				"/web/ [] 2020-03-30 09:00 - 2020-03-30 09:30: Sync",
+			},
+		},
+		{
+			name: "endless monthly series stops at to",
+			ics:  calendar("UID:review|SUMMARY:[api] Review|DTSTART;TZID=America/New_York:20200131T160000|DTEND;TZID=America/New_York:20200131T170000|RRULE:FREQ=MONTHLY;BYDAY=-1FR"),
+			to:   time.Date(2020, time.April, 1, 0, 0, 0, 0, newYork),
+			want: []string{
+				"/api/ [] 2020-01-31 16:00 - 2020-01-31 17:00: Review",
+				"/api/ [] 2020-02-28 16:00 - 2020-02-28 17:00: Review",
+				"/api/ [] 2020-03-27 16:00 - 2020-03-27 17:00: Review",
+			},
+		},
+		{
+			name: "interval with a date-only until",
+			ics:  calendar("UID:triage|SUMMARY:[web] Triage|DTSTART;TZID=America/New_York:20200101T090000|DTEND;TZID=America/New_York:20200101T100000|RRULE:FREQ=DAILY;INTERVAL=2;UNTIL=20200105|EXDATE;VALUE=DATE:20200103"),
+			to:   time.Date(2020, time.December, 31, 0, 0, 0, 0, newYork),
+			want: []string{
+				"/web/ [] 2020-01-01 09:00 - 2020-01-01 10:00: Triage",
+				"/web/ [] 2020-01-05 09:00 - 2020-01-05 10:00: Triage",
+			},
+		},
+		{
+			name:    "unsupported repeat rule",
+			ics:     calendar("UID:x|SUMMARY:[web] Odd|DTSTART:20200107T140000Z|DTEND:20200107T150000Z|RRULE:FREQ=MONTHLY;BYSETPOS=1;BYDAY=MO"),
+			to:      time.Date(2020, time.December, 31, 0, 0, 0, 0, newYork),
+			wantErr: true,
+		},
+		{
+			name:    "bad duration",
+			ics:     calendar("UID:x|SUMMARY:[web] Odd|DTSTART:20200107T140000Z|DURATION:an hour"),
+			to:      time.Date(2020, time.December, 31, 0, 0, 0, 0, newYork),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := ICSOptions{Pattern: DefaultICSPattern, From: tt.from, To: tt.to}
+			entries, err := ImportService{}.ReadICS(strings.NewReader(tt.ics), options)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ReadICS() = %v, want an error", describeEntries(entries, newYork))
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ReadICS() = %v", err)
+			}
+
+			if got := describeEntries(entries, newYork); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseICSDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "PT1H30M", want: 90 * time.Minute},
+		{value: "P1D", want: 24 * time.Hour},
+		{value: "P1W", want: 7 * 24 * time.Hour},
+		{value: "+PT15S", want: 15 * time.Second},
+		{value: "P1DT2H", want: 26 * time.Hour},
+		{value: "-PT1H", wantErr: true},
+		{value: "P", wantErr: true},
+		{value: "PT", wantErr: true},
+		{value: "1H", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseICSDuration(tt.value)
+
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseICSDuration(%q) = %v, %v, want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
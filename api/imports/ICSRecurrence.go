@@ -0,0 +1,509 @@
+package imports
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adampresley/mytime/api/apperrors"
+)
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// icsWeekday is one BYDAY entry, such as "MO", "2TU", or "-1FR". A zero
+// ordinal means every such weekday.
+type icsWeekday struct {
+	ordinal int
+	weekday time.Weekday
+}
+
+/*
+ * icsRule is a parsed RRULE. The parts supported are the ones calendar
+ * apps write for meetings: FREQ, INTERVAL, COUNT, UNTIL, BYDAY,
+ * BYMONTHDAY, BYMONTH, and WKST. Rules using anything else are rejected
+ * rather than imported wrongly.
+ */
+type icsRule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []icsWeekday
+	byMonthDay []int
+	byMonth    []time.Month
+	weekStart  time.Weekday
+}
+
+func parseICSRule(value string) (icsRule, error) {
+	var err error
+
+	result := icsRule{interval: 1, weekStart: time.Monday}
+
+	for _, part := range strings.Split(value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+
+		if len(kv) != 2 {
+			continue
+		}
+
+		name, v := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+
+		switch name {
+		case "FREQ":
+			switch v {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				result.freq = v
+
+			default:
+				return result, fmt.Errorf("FREQ=%s is not supported", v)
+			}
+
+		case "INTERVAL":
+			if result.interval, err = strconv.Atoi(v); err != nil || result.interval < 1 {
+				return result, fmt.Errorf("invalid INTERVAL %s", v)
+			}
+
+		case "COUNT":
+			if result.count, err = strconv.Atoi(v); err != nil || result.count < 1 {
+				return result, fmt.Errorf("invalid COUNT %s", v)
+			}
+
+		case "UNTIL":
+			var allDay bool
+
+			if result.until, allDay, err = parseICSTime(v, map[string]string{}); err != nil {
+				return result, fmt.Errorf("invalid UNTIL %s", v)
+			}
+
+			/*
+			 * A date alone includes that whole day
+			 */
+			if allDay {
+				result.until = result.until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+
+		case "BYDAY":
+			for _, day := range strings.Split(v, ",") {
+				var wd icsWeekday
+
+				if wd, err = parseICSWeekday(day); err != nil {
+					return result, err
+				}
+
+				result.byDay = append(result.byDay, wd)
+			}
+
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(v, ",") {
+				var d int
+
+				if d, err = strconv.Atoi(day); err != nil || d == 0 || d < -31 || d > 31 {
+					return result, fmt.Errorf("invalid BYMONTHDAY %s", day)
+				}
+
+				result.byMonthDay = append(result.byMonthDay, d)
+			}
+
+		case "BYMONTH":
+			for _, month := range strings.Split(v, ",") {
+				var m int
+
+				if m, err = strconv.Atoi(month); err != nil || m < 1 || m > 12 {
+					return result, fmt.Errorf("invalid BYMONTH %s", month)
+				}
+
+				result.byMonth = append(result.byMonth, time.Month(m))
+			}
+
+		case "WKST":
+			wd, ok := icsWeekdays[v]
+
+			if !ok {
+				return result, fmt.Errorf("invalid WKST %s", v)
+			}
+
+			result.weekStart = wd
+
+		default:
+			return result, fmt.Errorf("%s is not supported", name)
+		}
+	}
+
+	if result.freq == "" {
+		return result, fmt.Errorf("FREQ is missing")
+	}
+
+	if result.count > 0 && !result.until.IsZero() {
+		return result, fmt.Errorf("COUNT and UNTIL can't both be given")
+	}
+
+	for _, wd := range result.byDay {
+		if wd.ordinal != 0 && (result.freq == "DAILY" || result.freq == "WEEKLY") {
+			return result, fmt.Errorf("BYDAY=%d%s is only allowed in monthly or yearly rules", wd.ordinal, wd.weekday)
+		}
+
+		if result.freq == "YEARLY" && len(result.byMonth) == 0 {
+			return result, fmt.Errorf("BYDAY in a yearly rule without BYMONTH is not supported")
+		}
+	}
+
+	return result, nil
+}
+
+func parseICSWeekday(value string) (icsWeekday, error) {
+	var err error
+	var result icsWeekday
+
+	if len(value) < 2 {
+		return result, fmt.Errorf("invalid BYDAY %s", value)
+	}
+
+	weekday, ok := icsWeekdays[value[len(value)-2:]]
+
+	if !ok {
+		return result, fmt.Errorf("invalid BYDAY %s", value)
+	}
+
+	result.weekday = weekday
+
+	if ordinal := value[:len(value)-2]; ordinal != "" {
+		if result.ordinal, err = strconv.Atoi(strings.TrimPrefix(ordinal, "+")); err != nil || result.ordinal == 0 || result.ordinal < -5 || result.ordinal > 5 {
+			return result, fmt.Errorf("invalid BYDAY %s", value)
+		}
+	}
+
+	return result, nil
+}
+
+/*
+ * occurrences returns the start of every occurrence from dtstart up to
+ * limit, oldest first. dtstart is always the first, and each occurrence
+ * keeps its time of day in dtstart's time zone, so a meeting stays at
+ * 9:00 across a daylight saving change.
+ */
+func (r icsRule) occurrences(dtstart, limit time.Time) []time.Time {
+	result := []time.Time{dtstart}
+
+	for period := 0; ; period++ {
+		periodStart, candidates := r.period(dtstart, period)
+
+		if periodStart.After(limit) {
+			return result
+		}
+
+		for _, c := range candidates {
+			if !c.After(dtstart) {
+				continue
+			}
+
+			if c.After(limit) || (!r.until.IsZero() && c.After(r.until)) || (r.count > 0 && len(result) >= r.count) {
+				return result
+			}
+
+			result = append(result, c)
+		}
+	}
+}
+
+// period returns when the nth period of the rule starts, and the
+// occurrences in it, in order.
+func (r icsRule) period(dtstart time.Time, n int) (time.Time, []time.Time) {
+	year, month, day := dtstart.Date()
+	location := dtstart.Location()
+	days := make([]time.Time, 0, 7)
+
+	switch r.freq {
+	case "DAILY":
+		d := time.Date(year, month, day+n*r.interval, 0, 0, 0, 0, location)
+
+		if r.matchesMonth(d.Month()) && r.matchesWeekday(d.Weekday()) && r.matchesMonthDay(d) {
+			days = append(days, d)
+		}
+
+		return r.at(dtstart, d, days)
+
+	case "WEEKLY":
+		offset := (int(dtstart.Weekday()) - int(r.weekStart) + 7) % 7
+		weekStart := time.Date(year, month, day-offset+7*n*r.interval, 0, 0, 0, 0, location)
+		byDay := r.byDay
+
+		if len(byDay) == 0 {
+			byDay = []icsWeekday{{weekday: dtstart.Weekday()}}
+		}
+
+		for _, wd := range byDay {
+			d := weekStart.AddDate(0, 0, (int(wd.weekday)-int(r.weekStart)+7)%7)
+
+			if r.matchesMonth(d.Month()) {
+				days = append(days, d)
+			}
+		}
+
+		return r.at(dtstart, weekStart, days)
+
+	case "MONTHLY":
+		monthStart := time.Date(year, month+time.Month(n*r.interval), 1, 0, 0, 0, 0, location)
+
+		if r.matchesMonth(monthStart.Month()) {
+			days = r.daysInMonth(dtstart, monthStart)
+		}
+
+		return r.at(dtstart, monthStart, days)
+	}
+
+	yearStart := time.Date(year+n*r.interval, time.January, 1, 0, 0, 0, 0, location)
+	months := r.byMonth
+
+	if len(months) == 0 {
+		months = []time.Month{month}
+	}
+
+	for _, m := range months {
+		days = append(days, r.daysInMonth(dtstart, time.Date(yearStart.Year(), m, 1, 0, 0, 0, 0, location))...)
+	}
+
+	return r.at(dtstart, yearStart, days)
+}
+
+/*
+ * daysInMonth picks the days of a month from BYMONTHDAY and BYDAY. When
+ * both are given a day has to match both. With neither, it is the day of
+ * the month the series started on, and months too short for it are
+ * skipped.
+ */
+func (r icsRule) daysInMonth(dtstart, monthStart time.Time) []time.Time {
+	result := make([]time.Time, 0, 5)
+	last := monthStart.AddDate(0, 1, -1).Day()
+
+	for d := 1; d <= last; d++ {
+		day := monthStart.AddDate(0, 0, d-1)
+
+		switch {
+		case len(r.byMonthDay) == 0 && len(r.byDay) == 0:
+			if d != dtstart.Day() {
+				continue
+			}
+
+		case len(r.byMonthDay) > 0 && !r.matchesMonthDay(day):
+			continue
+
+		case len(r.byDay) > 0 && !r.matchesNthWeekday(day, last):
+			continue
+		}
+
+		result = append(result, day)
+	}
+
+	return result
+}
+
+// at puts dtstart's time of day on each day, sorted.
+func (r icsRule) at(dtstart, periodStart time.Time, days []time.Time) (time.Time, []time.Time) {
+	result := make([]time.Time, 0, len(days))
+
+	for _, d := range days {
+		result = append(result, time.Date(d.Year(), d.Month(), d.Day(), dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, d.Location()))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Before(result[j])
+	})
+
+	return periodStart, result
+}
+
+func (r icsRule) matchesMonth(month time.Month) bool {
+	if len(r.byMonth) == 0 {
+		return true
+	}
+
+	for _, m := range r.byMonth {
+		if m == month {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r icsRule) matchesWeekday(weekday time.Weekday) bool {
+	if len(r.byDay) == 0 {
+		return true
+	}
+
+	for _, wd := range r.byDay {
+		if wd.weekday == weekday {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r icsRule) matchesMonthDay(day time.Time) bool {
+	if len(r.byMonthDay) == 0 {
+		return true
+	}
+
+	last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+
+	for _, d := range r.byMonthDay {
+		if d == day.Day() || (d < 0 && last+d+1 == day.Day()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesNthWeekday checks BYDAY within a month, where "2TU" is the
+// second Tuesday and "-1FR" the last Friday.
+func (r icsRule) matchesNthWeekday(day time.Time, last int) bool {
+	for _, wd := range r.byDay {
+		if wd.weekday != day.Weekday() {
+			continue
+		}
+
+		switch {
+		case wd.ordinal == 0:
+			return true
+
+		case wd.ordinal > 0 && (day.Day()-1)/7+1 == wd.ordinal:
+			return true
+
+		case wd.ordinal < 0 && (last-day.Day())/7+1 == -wd.ordinal:
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+ * expandICS turns each event into its occurrences between from and to.
+ * Recurring events are repeated by their RRULE and RDATEs, less their
+ * EXDATEs, and an occurrence with an override (an event with the same
+ * UID and a RECURRENCE-ID) takes the override's place. Cancelled and
+ * all-day events are dropped. A zero from has no lower bound.
+ */
+func expandICS(events []icsEvent, from, to time.Time) ([]icsEvent, error) {
+	overrides := make(map[string][]icsEvent)
+	masters := make(map[string]bool)
+	result := make([]icsEvent, 0, len(events))
+
+	for _, event := range events {
+		if !event.recurrenceID.IsZero() {
+			overrides[event.uid] = append(overrides[event.uid], event)
+		} else {
+			masters[event.uid] = true
+		}
+	}
+
+	inWindow := func(event icsEvent) bool {
+		return !event.cancelled && !event.allDay && (from.IsZero() || !event.start.Before(from)) && !event.end.After(to)
+	}
+
+	for _, event := range events {
+		if !event.recurrenceID.IsZero() {
+			/*
+			 * An override without its series stands on its own
+			 */
+			if !masters[event.uid] && inWindow(event) {
+				result = append(result, event)
+			}
+
+			continue
+		}
+
+		if event.cancelled || event.allDay {
+			continue
+		}
+
+		if event.rrule == "" && len(event.rdates) == 0 {
+			if inWindow(event) {
+				result = append(result, event)
+			}
+
+			continue
+		}
+
+		starts := []time.Time{event.start}
+
+		if event.rrule != "" {
+			rule, err := parseICSRule(event.rrule)
+
+			if err != nil {
+				return result, apperrors.Validation("Event '%s' has a repeat rule that can't be imported: %s", event.summary, err.Error())
+			}
+
+			starts = rule.occurrences(event.start.In(event.location), to)
+		}
+
+		starts = append(starts, event.rdates...)
+
+		sort.Slice(starts, func(i, j int) bool {
+			return starts[i].Before(starts[j])
+		})
+
+		used := make(map[int]bool)
+		length := event.end.Sub(event.start)
+
+		for index, start := range starts {
+			if (index > 0 && start.Equal(starts[index-1])) || event.excludes(start) {
+				continue
+			}
+
+			occurrence := event
+			occurrence.start = start.Local()
+			occurrence.end = start.Add(length).Local()
+			occurrence.occurrence = true
+
+			for overrideIndex, override := range overrides[event.uid] {
+				if override.recurrenceID.Equal(start) {
+					occurrence = override
+					occurrence.occurrence = true
+					used[overrideIndex] = true
+				}
+			}
+
+			if inWindow(occurrence) {
+				result = append(result, occurrence)
+			}
+		}
+
+		/*
+		 * Overrides of occurrences the rule didn't produce, such as one
+		 * moved into the window from outside it, are still real meetings
+		 */
+		for overrideIndex, override := range overrides[event.uid] {
+			if !used[overrideIndex] && !event.excludes(override.recurrenceID) && inWindow(override) {
+				override.occurrence = true
+				result = append(result, override)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// excludes reports whether an EXDATE removes the occurrence starting at
+// start. An EXDATE that is a date alone removes that whole day.
+func (e icsEvent) excludes(start time.Time) bool {
+	for _, exdate := range e.exdates {
+		if exdate.Equal(start) {
+			return true
+		}
+	}
+
+	return e.exdateDays[start.In(e.location).Format("20060102")]
+}
@@ -0,0 +1,90 @@
+package imports
+
+import (
+	"time"
+
+	"github.com/adampresley/mytime/api/sessions"
+)
+
+const (
+	StatusNew        string = "new"
+	StatusDuplicate  string = "duplicate"
+	StatusOverlap    string = "overlap"
+	StatusUnresolved string = "unresolved"
+)
+
+// ImportEntry is a single time entry read from another tool, before it
+// has been matched against clients, projects, and categories.
+type ImportEntry struct {
+	Source   string
+	Start    time.Time
+	End      time.Time
+	Client   string
+	Project  string
+	Category string
+	Tags     []string
+	Notes    string
+}
+
+type ImportEntryCollection []ImportEntry
+
+type ImportOptions struct {
+	AllowOverlaps  bool
+	CategoryCode   string
+	CategoryMap    map[string]string
+	ClientCode     string
+	CreateClients  bool
+	CreateProjects bool
+	ProjectMap     map[string]string
+}
+
+type PlannedSession struct {
+	Entry        ImportEntry
+	Session      sessions.Session
+	ClientCode   string
+	ProjectCode  string
+	CategoryCode string
+	NewClient    bool
+	NewProject   bool
+	Status       string
+	Reason       string
+}
+
+type PlannedClient struct {
+	Name string
+	Code string
+}
+
+type PlannedProject struct {
+	Name         string
+	Code         string
+	ClientCode   string
+	CategoryCode string
+}
+
+type ImportPlan struct {
+	Sessions    []PlannedSession
+	NewClients  []PlannedClient
+	NewProjects []PlannedProject
+	Options     ImportOptions
+}
+
+type ImportResult struct {
+	ClientsCreated  int
+	ProjectsCreated int
+	SessionsCreated int
+	SessionsSkipped int
+}
+
+// Count returns how many planned sessions have the given status.
+func (p ImportPlan) Count(status string) int {
+	result := 0
+
+	for _, s := range p.Sessions {
+		if s.Status == status {
+			result++
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,663 @@
+package imports
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/adampresley/mytime/api/categories"
+	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/helpers"
+	"github.com/adampresley/mytime/api/projects"
+	"github.com/adampresley/mytime/api/sessions"
+	"github.com/adampresley/mytime/api/storage"
+)
+
+type ImportServicer interface {
+	Apply(plan ImportPlan) (ImportResult, error)
+	Plan(entries ImportEntryCollection, options ImportOptions) (ImportPlan, error)
+	ReadCSV(r io.Reader, mapping CSVMapping) (ImportEntryCollection, error)
+	ReadICS(r io.Reader, options ICSOptions) (ImportEntryCollection, error)
+	ReadTimewarrior(r io.Reader, options TimewarriorOptions) (ImportEntryCollection, error)
+	ReadWatsonFrames(r io.Reader) (ImportEntryCollection, error)
+}
+
+type ImportServiceConfig struct {
+	CategoryService categories.CategoryServicer
+	ClientService   clients.ClientServicer
+	HelperService   helpers.HelperServicer
+	Journal         storage.Journal
+	ProjectService  projects.ProjectServicer
+	SessionService  sessions.SessionServicer
+}
+
+type ImportService struct {
+	CategoryService categories.CategoryServicer
+	ClientService   clients.ClientServicer
+	HelperService   helpers.HelperServicer
+	Journal         storage.Journal
+	ProjectService  projects.ProjectServicer
+	SessionService  sessions.SessionServicer
+}
+
+func NewImportService(config ImportServiceConfig) ImportService {
+	return ImportService{
+		CategoryService: config.CategoryService,
+		ClientService:   config.ClientService,
+		HelperService:   config.HelperService,
+		Journal:         config.Journal,
+		ProjectService:  config.ProjectService,
+		SessionService:  config.SessionService,
+	}
+}
+
+// Apply creates the clients, projects, and sessions described by a plan.
+// Only sessions with a status of "new" are created, plus overlapping
+// sessions when the plan's options allow overlaps. Either everything in
+// the plan is imported or, on error, nothing is.
+func (s ImportService) Apply(plan ImportPlan) (ImportResult, error) {
+	var err error
+	var result ImportResult
+
+	if err = s.Journal.Begin("import"); err != nil {
+		return result, err
+	}
+
+	if result, err = s.apply(plan); err != nil {
+		if rollbackErr := s.Journal.Rollback(); rollbackErr != nil {
+			return ImportResult{}, fmt.Errorf("%w (rolling back also failed: %s)", err, rollbackErr.Error())
+		}
+
+		return ImportResult{}, err
+	}
+
+	return result, s.Journal.Commit()
+}
+
+func (s ImportService) apply(plan ImportPlan) (ImportResult, error) {
+	var (
+		err    error
+		id     int
+		result ImportResult
+	)
+
+	clientIDs := make(map[string]int)
+	projectIDs := make(map[string]int)
+	categoryIDs := make(map[string]int)
+
+	for _, c := range plan.NewClients {
+		client := clients.Client{
+			Name: c.Name,
+			Code: c.Code,
+		}
+
+		if id, err = s.ClientService.CreateClient(client); err != nil {
+			return result, fmt.Errorf("Error creating client %s: %w", c.Code, err)
+		}
+
+		clientIDs[c.Code] = id
+		result.ClientsCreated++
+	}
+
+	for _, p := range plan.NewProjects {
+		project := projects.Project{
+			Name: p.Name,
+			Code: p.Code,
+		}
+
+		if project.ClientID, err = s.lookupClientID(p.ClientCode, clientIDs); err != nil {
+			return result, err
+		}
+
+		if p.CategoryCode != "" {
+			if project.DefaultCategoryID, err = s.lookupCategoryID(p.CategoryCode, categoryIDs); err != nil {
+				return result, err
+			}
+		}
+
+		if id, err = s.ProjectService.CreateProject(project); err != nil {
+			return result, fmt.Errorf("Error creating project %s: %w", p.Code, err)
+		}
+
+		projectIDs[p.Code] = id
+		result.ProjectsCreated++
+	}
+
+	for _, planned := range plan.Sessions {
+		if planned.Status != StatusNew && !(planned.Status == StatusOverlap && plan.Options.AllowOverlaps) {
+			result.SessionsSkipped++
+			continue
+		}
+
+		session := planned.Session
+
+		if planned.NewClient {
+			session.ClientID = clientIDs[planned.ClientCode]
+		}
+
+		if planned.NewProject {
+			session.ProjectID = projectIDs[planned.ProjectCode]
+		}
+
+		if session.CategoryID == 0 {
+			if session.CategoryID, err = s.lookupCategoryID(planned.CategoryCode, categoryIDs); err != nil {
+				return result, err
+			}
+		}
+
+		if _, err = s.SessionService.CreateSession(session); err != nil {
+			return result, fmt.Errorf("Error creating session from %s: %w", planned.Entry.Source, err)
+		}
+
+		result.SessionsCreated++
+	}
+
+	return result, nil
+}
+
+// Plan matches imported entries against existing clients, projects, and
+// categories, and works out which sessions would be created, which are
+// duplicates of sessions already recorded, and which could not be
+// resolved. Nothing is written.
+func (s ImportService) Plan(entries ImportEntryCollection, options ImportOptions) (ImportPlan, error) {
+	var (
+		err error
+		p   *planner
+	)
+
+	result := ImportPlan{
+		Sessions:    make([]PlannedSession, 0, len(entries)),
+		NewClients:  make([]PlannedClient, 0, 5),
+		NewProjects: make([]PlannedProject, 0, 5),
+		Options:     options,
+	}
+
+	if p, err = s.newPlanner(options); err != nil {
+		return result, err
+	}
+
+	for _, entry := range entries {
+		result.Sessions = append(result.Sessions, p.plan(entry))
+	}
+
+	result.NewClients = p.newClients
+	result.NewProjects = p.newProjects
+	return result, nil
+}
+
+func (s ImportService) lookupCategoryID(code string, created map[string]int) (int, error) {
+	var err error
+	var category categories.Category
+
+	if id, ok := created[code]; ok {
+		return id, nil
+	}
+
+	if category, err = s.CategoryService.GetCategoryByCode(code); err != nil {
+		return 0, fmt.Errorf("Error finding category %s: %w", code, err)
+	}
+
+	created[code] = category.CategoryID
+	return category.CategoryID, nil
+}
+
+func (s ImportService) lookupClientID(code string, created map[string]int) (int, error) {
+	var err error
+	var client clients.Client
+
+	if id, ok := created[code]; ok {
+		return id, nil
+	}
+
+	if client, err = s.ClientService.GetClientByCode(code); err != nil {
+		return 0, fmt.Errorf("Error finding client %s: %w", code, err)
+	}
+
+	created[code] = client.ClientID
+	return client.ClientID, nil
+}
+
+func (s ImportService) newPlanner(options ImportOptions) (*planner, error) {
+	var (
+		err                error
+		clientList         clients.ClientCollection
+		archivedClients    clients.ClientCollection
+		projectList        projects.ProjectCollection
+		archivedProjects   projects.ProjectCollection
+		categoryList       categories.CategoryCollection
+		archivedCategories categories.CategoryCollection
+		sessionList        sessions.SessionCollection
+	)
+
+	if clientList, err = s.ClientService.ListClients(clients.ClientSearch{}); err != nil {
+		return nil, err
+	}
+
+	if archivedClients, err = s.ClientService.ListClients(clients.ClientSearch{Archived: true}); err != nil {
+		return nil, err
+	}
+
+	if projectList, err = s.ProjectService.ListProjects(projects.ProjectSearch{}); err != nil {
+		return nil, err
+	}
+
+	if archivedProjects, err = s.ProjectService.ListProjects(projects.ProjectSearch{Archived: true}); err != nil {
+		return nil, err
+	}
+
+	if categoryList, err = s.CategoryService.ListCategories(categories.CategorySearch{}); err != nil {
+		return nil, err
+	}
+
+	if archivedCategories, err = s.CategoryService.ListCategories(categories.CategorySearch{Archived: true}); err != nil {
+		return nil, err
+	}
+
+	if sessionList, err = s.SessionService.ListAllSessions(); err != nil {
+		return nil, err
+	}
+
+	result := &planner{
+		helperService: s.HelperService,
+		options:       options,
+		clients:       append(clientList, archivedClients...),
+		projects:      append(projectList, archivedProjects...),
+		categories:    append(categoryList, archivedCategories...),
+		sessions:      sessionList,
+		newClients:    make([]PlannedClient, 0, 5),
+		newProjects:   make([]PlannedProject, 0, 5),
+		seen:          make(map[string]string),
+	}
+
+	for _, session := range sessionList {
+		key := sessionKey(strconv.Itoa(session.ProjectID), session)
+		result.seen[key] = fmt.Sprintf("already recorded as session %d", session.SessionID)
+	}
+
+	return result, nil
+}
+
+// planner holds the state needed while resolving a batch of entries, so
+// that several entries for the same new project only create it once.
+type planner struct {
+	helperService helpers.HelperServicer
+	options       ImportOptions
+
+	clients    clients.ClientCollection
+	projects   projects.ProjectCollection
+	categories categories.CategoryCollection
+	sessions   sessions.SessionCollection
+
+	newClients  []PlannedClient
+	newProjects []PlannedProject
+	planned     []PlannedSession
+	seen        map[string]string
+}
+
+func (p *planner) plan(entry ImportEntry) PlannedSession {
+	var (
+		reason     string
+		newClient  *PlannedClient
+		newProject *PlannedProject
+	)
+
+	result := PlannedSession{
+		Entry:  entry,
+		Status: StatusUnresolved,
+	}
+
+	if !entry.End.After(entry.Start) {
+		result.Reason = "end time is not after start time"
+		return result
+	}
+
+	/*
+	 * Work out the project, and the client for it
+	 */
+	if project, ok := p.findProject(entry.Project); ok {
+		result.ProjectCode = project.Code
+		result.Session.ProjectID = project.ProjectID
+		result.Session.ClientID = project.ClientID
+
+		for _, c := range p.clients {
+			if c.ClientID == project.ClientID {
+				result.ClientCode = c.Code
+			}
+		}
+	} else if planned, ok := p.findNewProject(entry.Project); ok {
+		result.ProjectCode = planned.Code
+		result.NewProject = true
+
+		if plannedClient, ok := p.findNewClient(planned.ClientCode); ok {
+			result.ClientCode = plannedClient.Code
+			result.NewClient = true
+		} else if client, ok := p.findClient(planned.ClientCode); ok {
+			result.ClientCode = client.Code
+			result.Session.ClientID = client.ClientID
+		}
+	} else {
+		if newProject, newClient, reason = p.proposeProject(entry); reason != "" {
+			result.Reason = reason
+			return result
+		}
+
+		result.ProjectCode = newProject.Code
+		result.ClientCode = newProject.ClientCode
+		result.NewProject = true
+
+		if newClient != nil {
+			result.NewClient = true
+		} else if client, ok := p.findClient(newProject.ClientCode); ok {
+			result.Session.ClientID = client.ClientID
+		}
+	}
+
+	/*
+	 * Now the category. An explicit category or a tag that matches
+	 * a category wins, otherwise fall back to the project's default.
+	 */
+	unmatched := make([]string, 0, len(entry.Tags)+1)
+	candidates := entry.Tags
+
+	if entry.Category != "" {
+		candidates = append([]string{entry.Category}, entry.Tags...)
+	}
+
+	for _, candidate := range candidates {
+		if category, ok := p.findCategory(candidate); ok && result.CategoryCode == "" {
+			result.CategoryCode = category.Code
+			result.Session.CategoryID = category.CategoryID
+		} else {
+			unmatched = append(unmatched, candidate)
+		}
+	}
+
+	if result.CategoryCode == "" {
+		if category, ok := p.defaultCategory(result, newProject); ok {
+			result.CategoryCode = category.Code
+			result.Session.CategoryID = category.CategoryID
+		} else {
+			result.Reason = fmt.Sprintf("no category for project %s. Provide a default category", result.ProjectCode)
+			return result
+		}
+	}
+
+	result.Session.StartDateTime = entry.Start
+	result.Session.EndDateTime = entry.End
+	result.Session.Notes = joinNotes(entry.Notes, unmatched)
+
+	/*
+	 * Finally make sure this isn't already recorded, and doesn't step
+	 * on the toes of another session.
+	 */
+	projectRef := strconv.Itoa(result.Session.ProjectID)
+
+	if result.NewProject {
+		projectRef = "new:" + result.ProjectCode
+	}
+
+	key := sessionKey(projectRef, result.Session)
+
+	if existing, ok := p.seen[key]; ok {
+		result.Status = StatusDuplicate
+		result.Reason = existing
+	} else if overlap := p.findOverlap(result.Session); overlap != "" {
+		result.Status = StatusOverlap
+		result.Reason = overlap
+	} else {
+		result.Status = StatusNew
+	}
+
+	if result.Status != StatusDuplicate {
+		p.seen[key] = fmt.Sprintf("duplicate of %s", entry.Source)
+	}
+
+	if newClient != nil {
+		p.newClients = append(p.newClients, *newClient)
+	}
+
+	if newProject != nil {
+		p.newProjects = append(p.newProjects, *newProject)
+	}
+
+	p.planned = append(p.planned, result)
+	return result
+}
+
+func (p *planner) defaultCategory(planned PlannedSession, newProject *PlannedProject) (categories.Category, bool) {
+	categoryCode := ""
+
+	if planned.NewProject {
+		if newProject == nil {
+			if existing, ok := p.findNewProject(planned.ProjectCode); ok {
+				categoryCode = existing.CategoryCode
+			}
+		} else {
+			categoryCode = newProject.CategoryCode
+		}
+	} else {
+		for _, project := range p.projects {
+			if project.ProjectID == planned.Session.ProjectID {
+				for _, c := range p.categories {
+					if c.CategoryID == project.DefaultCategoryID {
+						return c, true
+					}
+				}
+			}
+		}
+	}
+
+	if categoryCode == "" {
+		categoryCode = p.options.CategoryCode
+	}
+
+	if categoryCode == "" {
+		return categories.Category{}, false
+	}
+
+	return p.findCategory(categoryCode)
+}
+
+func (p *planner) findCategory(key string) (categories.Category, bool) {
+	if mapped, ok := p.options.CategoryMap[key]; ok {
+		key = mapped
+	}
+
+	for _, c := range p.categories {
+		if strings.EqualFold(c.Code, key) {
+			return c, true
+		}
+	}
+
+	for _, c := range p.categories {
+		if strings.EqualFold(c.Name, key) {
+			return c, true
+		}
+	}
+
+	return categories.Category{}, false
+}
+
+func (p *planner) findClient(key string) (clients.Client, bool) {
+	for _, c := range p.clients {
+		if strings.EqualFold(c.Code, key) {
+			return c, true
+		}
+	}
+
+	for _, c := range p.clients {
+		if strings.EqualFold(c.Name, key) {
+			return c, true
+		}
+	}
+
+	return clients.Client{}, false
+}
+
+func (p *planner) findNewClient(key string) (PlannedClient, bool) {
+	for _, c := range p.newClients {
+		if strings.EqualFold(c.Code, key) || strings.EqualFold(c.Name, key) {
+			return c, true
+		}
+	}
+
+	return PlannedClient{}, false
+}
+
+func (p *planner) findNewProject(key string) (PlannedProject, bool) {
+	for _, project := range p.newProjects {
+		if strings.EqualFold(project.Code, key) || strings.EqualFold(project.Name, key) {
+			return project, true
+		}
+	}
+
+	return PlannedProject{}, false
+}
+
+func (p *planner) findOverlap(session sessions.Session) string {
+	for _, existing := range p.sessions {
+		if existing.StartDateTime.Before(session.EndDateTime) && session.StartDateTime.Before(existing.EndDateTime) {
+			return fmt.Sprintf("overlaps session %d", existing.SessionID)
+		}
+	}
+
+	for _, planned := range p.planned {
+		if planned.Status != StatusNew {
+			continue
+		}
+
+		if planned.Session.StartDateTime.Before(session.EndDateTime) && session.StartDateTime.Before(planned.Session.EndDateTime) {
+			return fmt.Sprintf("overlaps %s", planned.Entry.Source)
+		}
+	}
+
+	return ""
+}
+
+func (p *planner) findProject(key string) (projects.Project, bool) {
+	if mapped, ok := p.options.ProjectMap[key]; ok {
+		key = mapped
+	}
+
+	for _, project := range p.projects {
+		if strings.EqualFold(project.Code, key) {
+			return project, true
+		}
+	}
+
+	for _, project := range p.projects {
+		if strings.EqualFold(project.Name, key) {
+			return project, true
+		}
+	}
+
+	return projects.Project{}, false
+}
+
+// proposeProject works out a new project (and possibly a new client) for
+// an entry whose project doesn't exist yet. A non-empty reason is
+// returned when that isn't allowed or possible.
+func (p *planner) proposeProject(entry ImportEntry) (*PlannedProject, *PlannedClient, string) {
+	var newClient *PlannedClient
+
+	if entry.Project == "" {
+		return nil, nil, "entry has no project"
+	}
+
+	if mapped, ok := p.options.ProjectMap[entry.Project]; ok {
+		return nil, nil, fmt.Sprintf("mapped project code %s not found", mapped)
+	}
+
+	if !p.options.CreateProjects {
+		return nil, nil, fmt.Sprintf("project %s not found", entry.Project)
+	}
+
+	clientCode := ""
+
+	if entry.Client != "" {
+		if client, ok := p.findClient(entry.Client); ok {
+			clientCode = client.Code
+		} else if planned, ok := p.findNewClient(entry.Client); ok {
+			clientCode = planned.Code
+		} else if p.options.CreateClients {
+			newClient = &PlannedClient{
+				Name: entry.Client,
+				Code: p.helperService.CreateAutoCode(entry.Client, p.clientCodeTaken),
+			}
+
+			clientCode = newClient.Code
+		} else {
+			return nil, nil, fmt.Sprintf("client %s not found", entry.Client)
+		}
+	} else if p.options.ClientCode != "" {
+		client, ok := p.findClient(p.options.ClientCode)
+
+		if !ok {
+			return nil, nil, fmt.Sprintf("client %s not found", p.options.ClientCode)
+		}
+
+		clientCode = client.Code
+	} else {
+		return nil, nil, fmt.Sprintf("project %s not found, and there is no client to create it under", entry.Project)
+	}
+
+	categoryCode := ""
+
+	if category, ok := p.findCategory(p.options.CategoryCode); ok {
+		categoryCode = category.Code
+	}
+
+	newProject := &PlannedProject{
+		Name:         entry.Project,
+		Code:         p.helperService.CreateAutoCode(entry.Project, p.projectCodeTaken),
+		ClientCode:   clientCode,
+		CategoryCode: categoryCode,
+	}
+
+	return newProject, newClient, ""
+}
+
+func (p *planner) clientCodeTaken(code string) bool {
+	if _, ok := p.findNewClient(code); ok {
+		return true
+	}
+
+	for _, c := range p.clients {
+		if strings.EqualFold(c.Code, code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *planner) projectCodeTaken(code string) bool {
+	if _, ok := p.findNewProject(code); ok {
+		return true
+	}
+
+	for _, project := range p.projects {
+		if strings.EqualFold(project.Code, code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func joinNotes(notes string, tags []string) string {
+	if len(tags) == 0 {
+		return notes
+	}
+
+	if notes == "" {
+		return strings.Join(tags, ", ")
+	}
+
+	return fmt.Sprintf("%s (%s)", notes, strings.Join(tags, ", "))
+}
+
+func sessionKey(projectRef string, session sessions.Session) string {
+	return fmt.Sprintf("%s|%d|%d", projectRef, session.StartDateTime.Unix(), session.EndDateTime.Unix())
+}
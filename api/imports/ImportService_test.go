@@ -0,0 +1,305 @@
+package imports
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adampresley/mytime/api/categories"
+	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/helpers"
+	"github.com/adampresley/mytime/api/projects"
+	"github.com/adampresley/mytime/api/sessions"
+	"github.com/adampresley/mytime/api/storage"
+)
+
+func at(day, hour, minute int) time.Time {
+	return time.Date(2020, time.January, day, hour, minute, 0, 0, time.UTC)
+}
+
+/*
+ * newTestImportService has the client acme with the project web ("Web
+ * Site", default category dev), the archived client old with the
+ * archived project legacy ("Legacy Site", no default category), the
+ * categories dev and meet, and one web session on Jan 6 from 9 to 10.
+ */
+func newTestImportService(t *testing.T) ImportService {
+	t.Helper()
+
+	helperService := helpers.NewHelperService(helpers.HelperServiceConfig{})
+	clientRepository := clients.NewMemoryClientRepository()
+	projectRepository := projects.NewMemoryProjectRepository()
+	categoryRepository := categories.NewMemoryCategoryRepository()
+	sessionRepository := sessions.NewMemorySessionRepository()
+
+	clientRepository.Create(clients.Client{Name: "Acme", Code: "acme"})
+	clientRepository.Create(clients.Client{Name: "Old Co", Code: "old", Archived: true})
+
+	categoryRepository.Create(categories.Category{Name: "Development", Code: "dev"})
+	categoryRepository.Create(categories.Category{Name: "Meetings", Code: "meet"})
+
+	projectRepository.Create(projects.Project{Name: "Web Site", Code: "web", ClientID: 1, DefaultCategoryID: 1})
+	projectRepository.Create(projects.Project{Name: "Legacy Site", Code: "legacy", ClientID: 2, Archived: true})
+
+	sessionRepository.Create(sessions.Session{ClientID: 1, ProjectID: 1, CategoryID: 1, StartDateTime: at(6, 9, 0), EndDateTime: at(6, 10, 0)})
+
+	clientService := clients.NewClientService(clients.ClientServiceConfig{ClientRepository: clientRepository, HelperService: helperService})
+	categoryService := categories.NewCategoryService(categories.CategoryServiceConfig{CategoryRepository: categoryRepository, HelperService: helperService})
+	projectService := projects.NewProjectService(projects.ProjectServiceConfig{ClientService: clientService, HelperService: helperService, ProjectRepository: projectRepository})
+
+	return NewImportService(ImportServiceConfig{
+		CategoryService: categoryService,
+		ClientService:   clientService,
+		HelperService:   helperService,
+		Journal:         storage.NopJournal{},
+		ProjectService:  projectService,
+		SessionService: sessions.NewSessionService(sessions.SessionServiceConfig{
+			ActiveSessionRepository: sessions.NewMemoryActiveSessionRepository(),
+			CategoryService:         categoryService,
+			ClientService:           clientService,
+			HelperService:           helperService,
+			Journal:                 storage.NopJournal{},
+			ProjectService:          projectService,
+			SessionRepository:       sessionRepository,
+		}),
+	})
+}
+
+// describePlan sums up a plan's sessions as "status client/project/category",
+// with the notes or reason after a colon when there are any.
+func describePlan(plan ImportPlan) []string {
+	result := make([]string, 0, len(plan.Sessions))
+
+	for _, s := range plan.Sessions {
+		line := fmt.Sprintf("%s %s/%s/%s", s.Status, s.ClientCode, s.ProjectCode, s.CategoryCode)
+
+		if s.Reason != "" {
+			line += ": " + s.Reason
+		} else if s.Session.Notes != "" {
+			line += ": " + s.Session.Notes
+		}
+
+		result = append(result, line)
+	}
+
+	return result
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name            string
+		entries         ImportEntryCollection
+		options         ImportOptions
+		want            []string
+		wantNewClients  string
+		wantNewProjects string
+	}{
+		{
+			name:    "project by code in any case uses its default category",
+			entries: ImportEntryCollection{{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Project: "WEB"}},
+			want:    []string{"new acme/web/dev"},
+		},
+		{
+			name:    "project by name",
+			entries: ImportEntryCollection{{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Project: "web site"}},
+			want:    []string{"new acme/web/dev"},
+		},
+		{
+			name:    "project map",
+			entries: ImportEntryCollection{{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Project: "site"}},
+			options: ImportOptions{ProjectMap: map[string]string{"site": "web"}},
+			want:    []string{"new acme/web/dev"},
+		},
+		{
+			name:    "mapped project that doesn't exist",
+			entries: ImportEntryCollection{{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Project: "site"}},
+			options: ImportOptions{ProjectMap: map[string]string{"site": "nope"}, CreateProjects: true, ClientCode: "acme"},
+			want:    []string{"unresolved //: mapped project code nope not found"},
+		},
+		{
+			name: "tag picks the category and the rest go in the notes",
+			entries: ImportEntryCollection{
+				{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Project: "web", Tags: []string{"urgent", "Meetings"}, Notes: "Call"},
+			},
+			want: []string{"new acme/web/meet: Call (urgent)"},
+		},
+		{
+			name:    "already recorded",
+			entries: ImportEntryCollection{{Source: "a", Start: at(6, 9, 0), End: at(6, 10, 0), Project: "web"}},
+			want:    []string{"duplicate acme/web/dev: already recorded as session 1"},
+		},
+		{
+			name: "duplicate within the batch",
+			entries: ImportEntryCollection{
+				{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Project: "web"},
+				{Source: "b", Start: at(7, 9, 0), End: at(7, 10, 0), Project: "Web Site"},
+			},
+			want: []string{"new acme/web/dev", "duplicate acme/web/dev: duplicate of a"},
+		},
+		{
+			name:    "overlaps a recorded session",
+			entries: ImportEntryCollection{{Source: "a", Start: at(6, 9, 30), End: at(6, 10, 30), Project: "web"}},
+			want:    []string{"overlap acme/web/dev: overlaps session 1"},
+		},
+		{
+			name: "overlap within the batch",
+			entries: ImportEntryCollection{
+				{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Project: "web"},
+				{Source: "b", Start: at(7, 9, 30), End: at(7, 11, 0), Project: "web"},
+			},
+			want: []string{"new acme/web/dev", "overlap acme/web/dev: overlaps a"},
+		},
+		{
+			name:    "end before start",
+			entries: ImportEntryCollection{{Source: "a", Start: at(7, 10, 0), End: at(7, 9, 0), Project: "web"}},
+			want:    []string{"unresolved //: end time is not after start time"},
+		},
+		{
+			name:    "no category",
+			entries: ImportEntryCollection{{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Project: "legacy"}},
+			want:    []string{"unresolved old/legacy/: no category for project legacy. Provide a default category"},
+		},
+		{
+			name:    "unknown project",
+			entries: ImportEntryCollection{{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Project: "Mobile App"}},
+			want:    []string{"unresolved //: project Mobile App not found"},
+		},
+		{
+			name: "new project is planned once",
+			entries: ImportEntryCollection{
+				{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Project: "Mobile App"},
+				{Source: "b", Start: at(8, 9, 0), End: at(8, 10, 0), Project: "mobile app"},
+			},
+			options:         ImportOptions{CreateProjects: true, ClientCode: "acme", CategoryCode: "dev"},
+			want:            []string{"new acme/mobile-app/dev", "new acme/mobile-app/dev"},
+			wantNewProjects: "mobile-app",
+		},
+		{
+			name:            "new project code skips codes in use",
+			entries:         ImportEntryCollection{{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Project: "Legacy!"}},
+			options:         ImportOptions{CreateProjects: true, ClientCode: "acme", CategoryCode: "dev"},
+			want:            []string{"new acme/legacy2/dev"},
+			wantNewProjects: "legacy2",
+		},
+		{
+			name:    "new project needs a client",
+			entries: ImportEntryCollection{{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Project: "Mobile App"}},
+			options: ImportOptions{CreateProjects: true, CategoryCode: "dev"},
+			want:    []string{"unresolved //: project Mobile App not found, and there is no client to create it under"},
+		},
+		{
+			name: "new clients skip codes in use",
+			entries: ImportEntryCollection{
+				{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Client: "Old!", Project: "Portal"},
+				{Source: "b", Start: at(8, 9, 0), End: at(8, 10, 0), Client: "old!", Project: "Intranet"},
+			},
+			options:         ImportOptions{CreateProjects: true, CreateClients: true, CategoryCode: "meet"},
+			want:            []string{"new old2/portal/meet", "new old2/intranet/meet"},
+			wantNewClients:  "old2",
+			wantNewProjects: "portal,intranet",
+		},
+		{
+			name:    "unknown client without create",
+			entries: ImportEntryCollection{{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Client: "Initech", Project: "Portal"}},
+			options: ImportOptions{CreateProjects: true, CategoryCode: "meet"},
+			want:    []string{"unresolved //: client Initech not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := newTestImportService(t).Plan(tt.entries, tt.options)
+
+			if err != nil {
+				t.Fatalf("Plan() = %v", err)
+			}
+
+			if got := describePlan(plan); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("sessions:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+
+			newClients := make([]string, 0, len(plan.NewClients))
+
+			for _, c := range plan.NewClients {
+				newClients = append(newClients, c.Code)
+			}
+
+			newProjects := make([]string, 0, len(plan.NewProjects))
+
+			for _, p := range plan.NewProjects {
+				newProjects = append(newProjects, p.Code)
+			}
+
+			if got := strings.Join(newClients, ","); got != tt.wantNewClients {
+				t.Errorf("new clients = %s, want %s", got, tt.wantNewClients)
+			}
+
+			if got := strings.Join(newProjects, ","); got != tt.wantNewProjects {
+				t.Errorf("new projects = %s, want %s", got, tt.wantNewProjects)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	service := newTestImportService(t)
+
+	entries := ImportEntryCollection{
+		{Source: "a", Start: at(7, 9, 0), End: at(7, 10, 0), Client: "Initech", Project: "Portal"},
+		{Source: "b", Start: at(6, 9, 0), End: at(6, 10, 0), Project: "web"},
+	}
+
+	plan, err := service.Plan(entries, ImportOptions{CreateProjects: true, CreateClients: true, CategoryCode: "dev"})
+
+	if err != nil {
+		t.Fatalf("Plan() = %v", err)
+	}
+
+	result, err := service.Apply(plan)
+
+	if err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+
+	if result.ClientsCreated != 1 || result.ProjectsCreated != 1 || result.SessionsCreated != 1 || result.SessionsSkipped != 1 {
+		t.Errorf("Apply() = %+v, want 1 client, 1 project, 1 session, and 1 skipped", result)
+	}
+
+	project, err := service.ProjectService.GetProjectByCode("portal")
+
+	if err != nil {
+		t.Fatalf("new project: %v", err)
+	}
+
+	client, _ := service.ClientService.GetClientByCode("initech")
+
+	if project.ClientID != client.ClientID || client.ClientID == 0 {
+		t.Errorf("project client = %d, want %d", project.ClientID, client.ClientID)
+	}
+
+	list, _ := service.SessionService.ListSessions(sessions.SessionSearch{ProjectCode: "portal"})
+
+	if len(list) != 1 || list[0].ClientID != client.ClientID || !list[0].StartDateTime.Equal(at(7, 9, 0)) {
+		t.Errorf("sessions for portal = %+v", list)
+	}
+}
+
+// describeEntries sums up entries read by a parser as
+// "client/project/category [tags] start - end: notes", with the times in
+// location.
+func describeEntries(entries ImportEntryCollection, location *time.Location) []string {
+	result := make([]string, 0, len(entries))
+
+	for _, e := range entries {
+		line := fmt.Sprintf("%s/%s/%s [%s] %s - %s", e.Client, e.Project, e.Category, strings.Join(e.Tags, ","), e.Start.In(location).Format("2006-01-02 15:04"), e.End.In(location).Format("2006-01-02 15:04"))
+
+		if e.Notes != "" {
+			line += ": " + e.Notes
+		}
+
+		result = append(result, line)
+	}
+
+	return result
+}
@@ -0,0 +1,246 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/adampresley/mytime/api/apperrors"
+	"github.com/adampresley/mytime/api/projects"
+)
+
+// TimewarriorOptions says how to find the project and category among an
+// interval's tags. Tags starting with ProjectPrefix or CategoryPrefix
+// name a project or category code. With no project prefix, the first tag
+// that matches a project code (or a key of ProjectMap) is the project.
+// Any other tags are matched against categories as usual.
+type TimewarriorOptions struct {
+	ProjectPrefix  string
+	CategoryPrefix string
+	ProjectMap     map[string]string
+}
+
+type timewarriorInterval struct {
+	Start      string   `json:"start"`
+	End        string   `json:"end"`
+	Tags       []string `json:"tags"`
+	Annotation string   `json:"annotation"`
+}
+
+// ReadTimewarrior reads Timewarrior intervals, either from a data file
+// (the lines starting with "inc") or from the JSON written by
+// "timew export". Open intervals are skipped.
+func (s ImportService) ReadTimewarrior(r io.Reader, options TimewarriorOptions) (ImportEntryCollection, error) {
+	var (
+		err         error
+		b           []byte
+		intervals   []timewarriorInterval
+		projectList projects.ProjectCollection
+		archived    projects.ProjectCollection
+	)
+
+	result := make(ImportEntryCollection, 0, 100)
+
+	if b, err = ioutil.ReadAll(r); err != nil {
+		return result, fmt.Errorf("Error reading Timewarrior data: %w", err)
+	}
+
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err = json.Unmarshal(trimmed, &intervals); err != nil {
+			return result, apperrors.Validation("Error reading Timewarrior export: %s", err.Error())
+		}
+	} else if intervals, err = parseTimewarriorData(b); err != nil {
+		return result, err
+	}
+
+	if projectList, err = s.ProjectService.ListProjects(projects.ProjectSearch{}); err != nil {
+		return result, err
+	}
+
+	if archived, err = s.ProjectService.ListProjects(projects.ProjectSearch{Archived: true}); err != nil {
+		return result, err
+	}
+
+	projectList = append(projectList, archived...)
+
+	for index, interval := range intervals {
+		var start, end time.Time
+
+		if interval.End == "" {
+			continue
+		}
+
+		if start, err = time.Parse("20060102T150405Z", interval.Start); err != nil {
+			return result, apperrors.Validation("Invalid start time '%s': %s", interval.Start, err.Error())
+		}
+
+		if end, err = time.Parse("20060102T150405Z", interval.End); err != nil {
+			return result, apperrors.Validation("Invalid end time '%s': %s", interval.End, err.Error())
+		}
+
+		entry := ImportEntry{
+			Source: fmt.Sprintf("interval %d", index+1),
+			Start:  start.Local(),
+			End:    end.Local(),
+			Notes:  interval.Annotation,
+			Tags:   make([]string, 0, len(interval.Tags)),
+		}
+
+		for _, tag := range interval.Tags {
+			switch {
+			case entry.Project == "" && options.ProjectPrefix != "" && strings.HasPrefix(tag, options.ProjectPrefix):
+				entry.Project = strings.TrimPrefix(tag, options.ProjectPrefix)
+
+			case entry.Project == "" && options.ProjectPrefix == "" && isTimewarriorProjectTag(tag, options, projectList):
+				entry.Project = tag
+
+			case entry.Category == "" && options.CategoryPrefix != "" && strings.HasPrefix(tag, options.CategoryPrefix):
+				entry.Category = strings.TrimPrefix(tag, options.CategoryPrefix)
+
+			default:
+				entry.Tags = append(entry.Tags, tag)
+			}
+		}
+
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+func isTimewarriorProjectTag(tag string, options TimewarriorOptions, projectList projects.ProjectCollection) bool {
+	if _, ok := options.ProjectMap[tag]; ok {
+		return true
+	}
+
+	for _, p := range projectList {
+		if strings.EqualFold(p.Code, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+ * Data file lines look like this, where the annotation is optional:
+ *
+ *    inc 20200115T090000Z - 20200115T100000Z # tag1 "tag two" # "annotation"
+ */
+func parseTimewarriorData(b []byte) ([]timewarriorInterval, error) {
+	result := make([]timewarriorInterval, 0, 100)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		tokens := tokenizeTimewarrior(scanner.Text())
+
+		if len(tokens) < 2 || tokens[0].value != "inc" {
+			continue
+		}
+
+		interval := timewarriorInterval{
+			Start: tokens[1].value,
+			Tags:  make([]string, 0, 5),
+		}
+
+		rest := tokens[2:]
+
+		if len(rest) >= 2 && rest[0].value == "-" && !rest[0].quoted {
+			interval.End = rest[1].value
+			rest = rest[2:]
+		}
+
+		section := 0
+		annotation := make([]string, 0, 1)
+
+		for _, t := range rest {
+			if t.value == "#" && !t.quoted {
+				section++
+				continue
+			}
+
+			switch section {
+			case 1:
+				interval.Tags = append(interval.Tags, t.value)
+			case 2:
+				annotation = append(annotation, t.value)
+			default:
+				return result, apperrors.Validation("Unexpected '%s' on Timewarrior data line %d", t.value, lineNumber)
+			}
+		}
+
+		interval.Annotation = strings.Join(annotation, " ")
+		result = append(result, interval)
+	}
+
+	return result, scanner.Err()
+}
+
+type timewarriorToken struct {
+	value  string
+	quoted bool
+}
+
+func tokenizeTimewarrior(line string) []timewarriorToken {
+	var current strings.Builder
+
+	result := make([]timewarriorToken, 0, 10)
+	inQuotes := false
+	wasQuoted := false
+	escaped := false
+
+	flush := func() {
+		if current.Len() > 0 || wasQuoted {
+			result = append(result, timewarriorToken{value: current.String(), quoted: wasQuoted})
+		}
+
+		current.Reset()
+		wasQuoted = false
+	}
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(unescapeTimewarrior(r))
+			escaped = false
+
+		case r == '\\' && inQuotes:
+			escaped = true
+
+		case r == '"':
+			inQuotes = !inQuotes
+			wasQuoted = true
+
+		case r == ' ' && !inQuotes:
+			flush()
+
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	flush()
+	return result
+}
+
+// unescapeTimewarrior is the character a backslash escape in a quoted
+// value stands for, the reverse of what the exporter writes.
+func unescapeTimewarrior(r rune) rune {
+	switch r {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	}
+
+	return r
+}
@@ -0,0 +1,159 @@
+package imports
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adampresley/mytime/api/exports"
+	"github.com/adampresley/mytime/api/projects"
+)
+
+func TestTimewarriorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		notes string
+	}{
+		{name: "plain", notes: "Fixed the login bug"},
+		{name: "backslashes", notes: `Cleaned up C:\tmp and \\server\share`},
+		{name: "quotes", notes: `Said "done" twice`},
+		{name: "newlines and tabs", notes: "First line\nSecond\tline\r\nThird"},
+		{name: "runs of spaces", notes: "  two  spaces  "},
+		{name: "hashes", notes: "# not a section # really"},
+		{name: "escape lookalikes", notes: `a literal \n and \t`},
+	}
+
+	service := ImportService{
+		ProjectService: projects.NewProjectService(projects.ProjectServiceConfig{
+			ProjectRepository: projects.NewMemoryProjectRepository(),
+		}),
+	}
+
+	start := time.Date(2020, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+
+			records := exports.SessionRecordCollection{
+				{
+					ProjectCode:   `web\app`,
+					CategoryCode:  "dev",
+					StartDateTime: start,
+					EndDateTime:   start.Add(time.Hour),
+					Notes:         tt.notes,
+				},
+			}
+
+			options := exports.TimewarriorOptions{ProjectPrefix: "project:", CategoryPrefix: "category:"}
+
+			if err := (exports.ExportService{}).WriteTimewarrior(&b, records, options); err != nil {
+				t.Fatalf("WriteTimewarrior: %v", err)
+			}
+
+			entries, err := service.ReadTimewarrior(&b, TimewarriorOptions{ProjectPrefix: "project:", CategoryPrefix: "category:"})
+
+			if err != nil {
+				t.Fatalf("ReadTimewarrior: %v", err)
+			}
+
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+
+			got := entries[0]
+
+			if got.Notes != tt.notes {
+				t.Errorf("notes = %q, want %q", got.Notes, tt.notes)
+			}
+
+			if got.Project != `web\app` || got.Category != "dev" {
+				t.Errorf("project, category = %q, %q, want %q, %q", got.Project, got.Category, `web\app`, "dev")
+			}
+
+			if !got.Start.Equal(start) || !got.End.Equal(start.Add(time.Hour)) {
+				t.Errorf("times = %v - %v, want %v - %v", got.Start, got.End, start, start.Add(time.Hour))
+			}
+		})
+	}
+}
+
+func TestReadTimewarrior(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		options TimewarriorOptions
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "data file with prefixes",
+			data: "inc 20200107T090000Z - 20200107T103000Z # project:web category:dev urgent # \"Fixed it\"\n" +
+				"inc 20200108T090000Z - 20200108T100000Z # \"project:api\" \"two words\"\n",
+			options: TimewarriorOptions{ProjectPrefix: "project:", CategoryPrefix: "category:"},
+			want: []string{
+				"/web/dev [urgent] 2020-01-07 09:00 - 2020-01-07 10:30: Fixed it",
+				"/api/ [two words] 2020-01-08 09:00 - 2020-01-08 10:00",
+			},
+		},
+		{
+			name:    "open intervals are skipped",
+			data:    "inc 20200107T090000Z # project:web\n",
+			options: TimewarriorOptions{ProjectPrefix: "project:"},
+			want:    []string{},
+		},
+		{
+			name:    "project found among the tags without a prefix",
+			data:    "inc 20200107T090000Z - 20200107T100000Z # meeting WEB\n",
+			options: TimewarriorOptions{},
+			want:    []string{"/WEB/ [meeting] 2020-01-07 09:00 - 2020-01-07 10:00"},
+		},
+		{
+			name:    "project map",
+			data:    "inc 20200107T090000Z - 20200107T100000Z # site meeting\n",
+			options: TimewarriorOptions{ProjectMap: map[string]string{"site": "web"}},
+			want:    []string{"/site/ [meeting] 2020-01-07 09:00 - 2020-01-07 10:00"},
+		},
+		{
+			name: "timew export JSON",
+			data: `[{"id":1,"start":"20200107T090000Z","end":"20200107T100000Z","tags":["web","dev"],"annotation":"Call"},` +
+				`{"id":2,"start":"20200108T090000Z","tags":["web"]}]`,
+			options: TimewarriorOptions{},
+			want:    []string{"/web/ [dev] 2020-01-07 09:00 - 2020-01-07 10:00: Call"},
+		},
+		{
+			name:    "bad time",
+			data:    "inc 2020-01-07 - 20200107T100000Z # web\n",
+			wantErr: true,
+		},
+		{
+			name:    "text after the annotation",
+			data:    "inc 20200107T090000Z - 20200107T100000Z # web # \"notes\" # more\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestImportService(t)
+			entries, err := service.ReadTimewarrior(strings.NewReader(tt.data), tt.options)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ReadTimewarrior() = %v, want an error", describeEntries(entries, time.UTC))
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ReadTimewarrior() = %v", err)
+			}
+
+			if got := describeEntries(entries, time.UTC); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
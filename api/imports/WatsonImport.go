@@ -0,0 +1,83 @@
+package imports
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/adampresley/mytime/api/apperrors"
+)
+
+/*
+ * Watson stores frames as a JSON array of arrays, each one being
+ * [start, stop, project, id, tags, updatedAt], with times as Unix
+ * timestamps.
+ */
+type watsonFrame struct {
+	Start   int64
+	Stop    int64
+	Project string
+	ID      string
+	Tags    []string
+}
+
+func (f *watsonFrame) UnmarshalJSON(b []byte) error {
+	var err error
+	var fields []json.RawMessage
+
+	if err = json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+
+	if len(fields) < 4 {
+		return fmt.Errorf("expected at least 4 fields in a frame, got %d", len(fields))
+	}
+
+	if err = json.Unmarshal(fields[0], &f.Start); err != nil {
+		return fmt.Errorf("invalid start time: %w", err)
+	}
+
+	if err = json.Unmarshal(fields[1], &f.Stop); err != nil {
+		return fmt.Errorf("invalid stop time: %w", err)
+	}
+
+	if err = json.Unmarshal(fields[2], &f.Project); err != nil {
+		return fmt.Errorf("invalid project: %w", err)
+	}
+
+	if err = json.Unmarshal(fields[3], &f.ID); err != nil {
+		return fmt.Errorf("invalid frame ID: %w", err)
+	}
+
+	if len(fields) > 4 {
+		if err = json.Unmarshal(fields[4], &f.Tags); err != nil {
+			return fmt.Errorf("invalid tags: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s ImportService) ReadWatsonFrames(r io.Reader) (ImportEntryCollection, error) {
+	var err error
+	var frames []watsonFrame
+
+	if err = json.NewDecoder(r).Decode(&frames); err != nil {
+		return nil, apperrors.Validation("Error reading Watson frames: %s", err.Error())
+	}
+
+	result := make(ImportEntryCollection, 0, len(frames))
+
+	for _, frame := range frames {
+		result = append(result, ImportEntry{
+			Source:  fmt.Sprintf("frame %s", frame.ID),
+			Start:   time.Unix(frame.Start, 0),
+			End:     time.Unix(frame.Stop, 0),
+			Project: frame.Project,
+			Tags:    frame.Tags,
+		})
+	}
+
+	return result, nil
+}
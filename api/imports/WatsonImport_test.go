@@ -0,0 +1,71 @@
+package imports
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadWatsonFrames(t *testing.T) {
+	tests := []struct {
+		name    string
+		frames  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "frames with and without tags",
+			frames: `[[1578387600, 1578393000, "web", "a1", ["dev", "urgent"], 1578393000], [1578474000, 1578477600, "api", "b2"]]`,
+			want: []string{
+				"/web/ [dev,urgent] 2020-01-07 09:00 - 2020-01-07 10:30",
+				"/api/ [] 2020-01-08 09:00 - 2020-01-08 10:00",
+			},
+		},
+		{
+			name:   "no frames",
+			frames: `[]`,
+			want:   []string{},
+		},
+		{
+			name:    "too few fields",
+			frames:  `[[1578387600, 1578393000, "web"]]`,
+			wantErr: true,
+		},
+		{
+			name:    "start isn't a timestamp",
+			frames:  `[["today", 1578393000, "web", "a1"]]`,
+			wantErr: true,
+		},
+		{
+			name:    "not frames",
+			frames:  `{"web": 1}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ImportService{}.ReadWatsonFrames(strings.NewReader(tt.frames))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ReadWatsonFrames() = %v, want an error", describeEntries(entries, time.UTC))
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ReadWatsonFrames() = %v", err)
+			}
+
+			if got := describeEntries(entries, time.UTC); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+
+			if len(entries) > 0 && entries[0].Source != "frame a1" {
+				t.Errorf("source = %q, want %q", entries[0].Source, "frame a1")
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package migrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/adampresley/mytime/api/storage"
+	"github.com/spf13/afero"
+)
+
+// Migration is one numbered change to the shape of the data. Migrate must
+// be idempotent: running it on data it has already changed makes no
+// further changes. It returns a description of each change made.
+type Migration struct {
+	Version     int
+	Description string
+	Migrate     func(data *Dataset) ([]string, error)
+}
+
+type MigrationCollection []Migration
+
+type MigrationStatus struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+	Applied     bool   `json:"applied"`
+}
+
+type MigrationResult struct {
+	Version     int
+	Description string
+	Changes     []string
+}
+
+type Record map[string]interface{}
+
+// Dataset gives migrations the raw records of each collection in the
+// data directory, so fields missing from older records can be seen.
+// Changes are kept in memory until the dataset is saved.
+type Dataset struct {
+	dir         string
+	collections map[string][]Record
+	changed     map[string]bool
+}
+
+func newDataset(dir string) *Dataset {
+	return &Dataset{
+		dir:         dir,
+		collections: make(map[string][]Record),
+		changed:     make(map[string]bool),
+	}
+}
+
+// Records returns the records of a collection, named after its entity.
+func (d *Dataset) Records(collection string) ([]Record, error) {
+	var err error
+	var b []byte
+
+	if records, ok := d.collections[collection]; ok {
+		return records, nil
+	}
+
+	records := make([]Record, 0, 100)
+
+	if b, err = ioutil.ReadFile(filepath.Join(d.dir, collection)); err != nil && !os.IsNotExist(err) {
+		return records, fmt.Errorf("Error reading %s: %w", collection, err)
+	}
+
+	if len(b) > 0 {
+		if err = json.Unmarshal(b, &records); err != nil {
+			return records, fmt.Errorf("Error reading %s: %w", collection, err)
+		}
+	}
+
+	d.collections[collection] = records
+	return records, nil
+}
+
+// Changed marks a collection as needing to be saved.
+func (d *Dataset) Changed(collection string) {
+	d.changed[collection] = true
+}
+
+func (d *Dataset) save() error {
+	var err error
+	var b []byte
+
+	for collection := range d.changed {
+		if b, err = json.Marshal(d.collections[collection]); err != nil {
+			return fmt.Errorf("Error saving %s: %w", collection, err)
+		}
+
+		if err = storage.WriteFile(afero.NewOsFs(), filepath.Join(d.dir, collection), b, 0644); err != nil {
+			return fmt.Errorf("Error saving %s: %w", collection, err)
+		}
+
+		delete(d.changed, collection)
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package migrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adampresley/mytime/api/storage"
+	"github.com/spf13/afero"
+)
+
+const SchemaFile string = "schema.json"
+
+type MigrationServicer interface {
+	CurrentVersion() (int, error)
+	Initialize() error
+	LatestVersion() int
+	Pending() (MigrationCollection, error)
+	Run(dryRun bool) ([]MigrationResult, error)
+	Status() ([]MigrationStatus, error)
+}
+
+type MigrationServiceConfig struct {
+	DataDirectory string
+	Locker        storage.Locker
+	Migrations    MigrationCollection
+}
+
+type MigrationService struct {
+	DataDirectory string
+	Locker        storage.Locker
+	Migrations    MigrationCollection
+}
+
+type schema struct {
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func NewMigrationService(config MigrationServiceConfig) MigrationService {
+	return MigrationService{
+		DataDirectory: config.DataDirectory,
+		Locker:        config.Locker,
+		Migrations:    config.Migrations,
+	}
+}
+
+// CurrentVersion returns the schema version recorded in the data
+// directory. Data from before versioning existed is version 0.
+func (s MigrationService) CurrentVersion() (int, error) {
+	var (
+		err    error
+		b      []byte
+		result schema
+	)
+
+	if b, err = ioutil.ReadFile(filepath.Join(s.DataDirectory, SchemaFile)); err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("Error reading schema version: %w", err)
+	}
+
+	if err = json.Unmarshal(b, &result); err != nil {
+		return 0, fmt.Errorf("Error reading schema version: %w", err)
+	}
+
+	return result.Version, nil
+}
+
+// Initialize records the latest schema version in a data directory that
+// has no data yet, so a new profile starts out up to date rather than
+// being migrated the first time it is used.
+func (s MigrationService) Initialize() error {
+	var err error
+	var collections []string
+
+	if _, err = os.Stat(filepath.Join(s.DataDirectory, SchemaFile)); err == nil {
+		return nil
+	}
+
+	if collections, err = storage.Collections(s.DataDirectory); err != nil {
+		return err
+	}
+
+	if len(collections) > 0 {
+		return nil
+	}
+
+	return s.setVersion(s.LatestVersion())
+}
+
+func (s MigrationService) LatestVersion() int {
+	result := 0
+
+	for _, m := range s.Migrations {
+		if m.Version > result {
+			result = m.Version
+		}
+	}
+
+	return result
+}
+
+func (s MigrationService) Pending() (MigrationCollection, error) {
+	var err error
+	var current int
+
+	result := make(MigrationCollection, 0, len(s.Migrations))
+
+	if current, err = s.CurrentVersion(); err != nil {
+		return result, err
+	}
+
+	if current > s.LatestVersion() {
+		return result, fmt.Errorf("Your data is at schema version %d, but this version of My Time only knows up to %d. Please upgrade My Time", current, s.LatestVersion())
+	}
+
+	for _, m := range s.Migrations {
+		if m.Version > current {
+			result = append(result, m)
+		}
+	}
+
+	return result, nil
+}
+
+// Run applies pending migrations in order, recording the new schema
+// version after each one. With dryRun nothing is written, but the
+// changes each migration would make are still returned.
+func (s MigrationService) Run(dryRun bool) ([]MigrationResult, error) {
+	var (
+		err     error
+		pending MigrationCollection
+	)
+
+	result := make([]MigrationResult, 0, 5)
+	data := newDataset(s.DataDirectory)
+
+	if err = s.Locker.Lock(); err != nil {
+		return result, err
+	}
+
+	defer s.Locker.Unlock()
+
+	if pending, err = s.Pending(); err != nil {
+		return result, err
+	}
+
+	for _, m := range pending {
+		var changes []string
+
+		if changes, err = m.Migrate(data); err != nil {
+			return result, fmt.Errorf("Error running migration %d (%s): %w", m.Version, m.Description, err)
+		}
+
+		result = append(result, MigrationResult{
+			Version:     m.Version,
+			Description: m.Description,
+			Changes:     changes,
+		})
+
+		if dryRun {
+			continue
+		}
+
+		if err = data.save(); err != nil {
+			return result, fmt.Errorf("Error saving migration %d: %w", m.Version, err)
+		}
+
+		if err = s.setVersion(m.Version); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func (s MigrationService) Status() ([]MigrationStatus, error) {
+	var err error
+	var current int
+
+	result := make([]MigrationStatus, 0, len(s.Migrations))
+
+	if current, err = s.CurrentVersion(); err != nil {
+		return result, err
+	}
+
+	for _, m := range s.Migrations {
+		result = append(result, MigrationStatus{
+			Version:     m.Version,
+			Description: m.Description,
+			Applied:     m.Version <= current,
+		})
+	}
+
+	return result, nil
+}
+
+func (s MigrationService) setVersion(version int) error {
+	b, err := json.Marshal(schema{Version: version, UpdatedAt: time.Now()})
+
+	if err != nil {
+		return fmt.Errorf("Error writing schema version: %w", err)
+	}
+
+	if err = storage.WriteFile(afero.NewOsFs(), filepath.Join(s.DataDirectory, SchemaFile), b, 0644); err != nil {
+		return fmt.Errorf("Error writing schema version: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package migrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// All is every migration, in order. Add new ones to the end with the next
+// version number, and never change one that has shipped.
+var All = MigrationCollection{
+	{
+		Version:     1,
+		Description: "Fill in fields missing from records written by older versions",
+		Migrate:     fillMissingFields,
+	},
+	{
+		Version:     2,
+		Description: "Make session invoice and paid dates agree with the invoiced and paid flags",
+		Migrate:     reconcileSessionDates,
+	},
+}
+
+/*
+ * The fields each collection had when version 1 shipped, with their
+ * defaults. These are frozen here rather than taken from the entity
+ * structs, so a field added later doesn't change what this migration
+ * does. New fields get a migration of their own.
+ */
+var version1Defaults = map[string]string{
+	"Client":        `{"clientID":0,"name":"","code":"","address":"","contactName":"","contactEmail":"","paymentTerms":0,"taxID":"","notes":"","archived":false}`,
+	"Project":       `{"projectID":0,"name":"","code":"","clientID":0,"defaultCategoryID":0,"archived":false}`,
+	"Category":      `{"categoryID":0,"name":"","code":"","rate":0,"archived":false}`,
+	"Session":       `{"sessionID":0,"clientID":0,"projectID":0,"categoryID":0,"startDateTime":"0001-01-01T00:00:00Z","endDateTime":"0001-01-01T00:00:00Z","notes":"","invoiced":false,"invoiceDate":"0001-01-01T00:00:00Z","paid":false,"paidDate":"0001-01-01T00:00:00Z"}`,
+	"ActiveSession": `{"activeSessionID":"","projectID":0,"clientID":0,"categoryID":0,"startTime":"0001-01-01T00:00:00Z","endTime":"0001-01-01T00:00:00Z","notes":""}`,
+}
+
+/*
+ * Version 1. Records saved before a field existed don't have it at all,
+ * which simdb treats differently from a zero value when querying.
+ */
+func fillMissingFields(data *Dataset) ([]string, error) {
+	var err error
+
+	result := make([]string, 0, 10)
+
+	for _, collection := range []string{"Client", "Project", "Category", "Session", "ActiveSession"} {
+		var (
+			defaults Record
+			records  []Record
+		)
+
+		if err = json.Unmarshal([]byte(version1Defaults[collection]), &defaults); err != nil {
+			return result, err
+		}
+
+		if records, err = data.Records(collection); err != nil {
+			return result, err
+		}
+
+		fields := make([]string, 0, len(defaults))
+
+		for field := range defaults {
+			fields = append(fields, field)
+		}
+
+		sort.Strings(fields)
+
+		for index, record := range records {
+			for _, field := range fields {
+				if _, ok := record[field]; !ok {
+					record[field] = defaults[field]
+					data.Changed(collection)
+					result = append(result, fmt.Sprintf("%s record %d: added missing field %s", collection, index+1, field))
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+/*
+ * Version 2. A zero invoice or paid date only means "not set" when the
+ * matching flag is off, and a paid session must also have been invoiced.
+ */
+func reconcileSessionDates(data *Dataset) ([]string, error) {
+	var err error
+	var records []Record
+
+	result := make([]string, 0, 10)
+	zero := time.Time{}.Format(time.RFC3339)
+
+	if records, err = data.Records("Session"); err != nil {
+		return result, err
+	}
+
+	for _, record := range records {
+		id := record["sessionID"]
+		invoiced, _ := record["invoiced"].(bool)
+		paid, _ := record["paid"].(bool)
+
+		if paid && !invoiced {
+			record["invoiced"] = true
+			record["invoiceDate"] = record["paidDate"]
+			invoiced = true
+			result = append(result, fmt.Sprintf("Session %v: marked as invoiced on its paid date, as it is paid", id))
+		}
+
+		if !invoiced && !isZeroTime(record["invoiceDate"]) {
+			record["invoiceDate"] = zero
+			result = append(result, fmt.Sprintf("Session %v: cleared invoice date, as it is not invoiced", id))
+		}
+
+		if !paid && !isZeroTime(record["paidDate"]) {
+			record["paidDate"] = zero
+			result = append(result, fmt.Sprintf("Session %v: cleared paid date, as it is not paid", id))
+		}
+	}
+
+	if len(result) > 0 {
+		data.Changed("Session")
+	}
+
+	return result, nil
+}
+
+func isZeroTime(value interface{}) bool {
+	s, ok := value.(string)
+
+	if !ok || s == "" {
+		return true
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	return err != nil || t.IsZero()
+}
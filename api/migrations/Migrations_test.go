@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFillMissingFields(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mytime-migrations")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	if err = ioutil.WriteFile(filepath.Join(dir, "Project"), []byte(`[{"projectID":1,"name":"Web","code":"web","clientID":1,"extra":"kept"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := newDataset(dir)
+	changes, err := fillMissingFields(data)
+
+	if err != nil {
+		t.Fatalf("fillMissingFields() = %v", err)
+	}
+
+	if len(changes) != 2 {
+		t.Errorf("changes = %v, want archived and defaultCategoryID added", changes)
+	}
+
+	if err = data.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "Project"))
+	records := make([]Record, 0, 1)
+
+	if err = json.Unmarshal(b, &records); err != nil {
+		t.Fatal(err)
+	}
+
+	if records[0]["archived"] != false || records[0]["defaultCategoryID"] != float64(0) || records[0]["extra"] != "kept" || records[0]["code"] != "web" {
+		t.Errorf("record = %v", records[0])
+	}
+
+	if changes, _ = fillMissingFields(newDataset(dir)); len(changes) != 0 {
+		t.Errorf("second run changes = %v, want none", changes)
+	}
+}
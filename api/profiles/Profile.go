@@ -0,0 +1,10 @@
+package profiles
+
+// Profile is a named, separate set of data and configuration.
+type Profile struct {
+	Name          string `json:"name"`
+	DataDirectory string `json:"dataDirectory"`
+	Current       bool   `json:"current"`
+}
+
+type ProfileCollection []Profile
@@ -0,0 +1,193 @@
+package profiles
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/adampresley/mytime/api/apperrors"
+	"github.com/adampresley/mytime/api/storage"
+	"github.com/spf13/afero"
+)
+
+const (
+	// DefaultProfile keeps its data in the home directory itself, which is
+	// where everything lived before profiles existed.
+	DefaultProfile    string = "default"
+	ProfilesDirectory string = "profiles"
+
+	currentProfileFile string = ".profile"
+)
+
+var (
+	ErrProfileExists   = errors.New("A profile with that name already exists")
+	ErrProfileNotFound = errors.New("Profile not found")
+
+	profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+)
+
+type ProfileServicer interface {
+	CreateProfile(name string) (Profile, error)
+	CurrentProfile() (string, error)
+	DataDirectory(name string) (string, error)
+	ListProfiles() (ProfileCollection, error)
+	UseProfile(name string) error
+}
+
+type ProfileServiceConfig struct {
+	HomeDirectory string
+}
+
+type ProfileService struct {
+	HomeDirectory string
+}
+
+func NewProfileService(config ProfileServiceConfig) ProfileService {
+	return ProfileService{
+		HomeDirectory: config.HomeDirectory,
+	}
+}
+
+// CreateProfile makes an empty data directory for a new profile.
+func (s ProfileService) CreateProfile(name string) (Profile, error) {
+	var err error
+
+	if !profileNamePattern.MatchString(name) {
+		return Profile{}, apperrors.Validation("Profile names may only contain letters, numbers, dashes, and underscores")
+	}
+
+	if strings.EqualFold(name, DefaultProfile) {
+		return Profile{}, ErrProfileExists
+	}
+
+	dir := filepath.Join(s.HomeDirectory, ProfilesDirectory, name)
+
+	if _, err = os.Stat(dir); err == nil {
+		return Profile{}, ErrProfileExists
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return Profile{}, fmt.Errorf("Error creating profile directory: %w", err)
+	}
+
+	return Profile{
+		Name:          name,
+		DataDirectory: dir,
+	}, nil
+}
+
+// CurrentProfile returns the profile selected with UseProfile, or the
+// default profile if none has been.
+func (s ProfileService) CurrentProfile() (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(s.HomeDirectory, currentProfileFile))
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return DefaultProfile, nil
+		}
+
+		return "", fmt.Errorf("Error reading current profile: %w", err)
+	}
+
+	if name := strings.TrimSpace(string(b)); name != "" {
+		return name, nil
+	}
+
+	return DefaultProfile, nil
+}
+
+func (s ProfileService) DataDirectory(name string) (string, error) {
+	if name == "" || name == DefaultProfile {
+		return s.HomeDirectory, nil
+	}
+
+	dir := filepath.Join(s.HomeDirectory, ProfilesDirectory, name)
+
+	if !profileNamePattern.MatchString(name) {
+		return dir, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return dir, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+
+	return dir, nil
+}
+
+// ListProfiles returns the default profile followed by the others in name
+// order.
+func (s ProfileService) ListProfiles() (ProfileCollection, error) {
+	var (
+		err     error
+		current string
+		infos   []os.FileInfo
+	)
+
+	result := make(ProfileCollection, 0, 5)
+
+	if current, err = s.CurrentProfile(); err != nil {
+		return result, err
+	}
+
+	result = append(result, Profile{
+		Name:          DefaultProfile,
+		DataDirectory: s.HomeDirectory,
+		Current:       current == DefaultProfile,
+	})
+
+	if infos, err = ioutil.ReadDir(filepath.Join(s.HomeDirectory, ProfilesDirectory)); err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+
+		return result, fmt.Errorf("Error reading profiles: %w", err)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+
+	for _, info := range infos {
+		if !info.IsDir() || !profileNamePattern.MatchString(info.Name()) {
+			continue
+		}
+
+		result = append(result, Profile{
+			Name:          info.Name(),
+			DataDirectory: filepath.Join(s.HomeDirectory, ProfilesDirectory, info.Name()),
+			Current:       current == info.Name(),
+		})
+	}
+
+	return result, nil
+}
+
+// UseProfile makes a profile the one used when no other is asked for.
+func (s ProfileService) UseProfile(name string) error {
+	var err error
+
+	if _, err = s.DataDirectory(name); err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(s.HomeDirectory, 0755); err != nil {
+		return fmt.Errorf("Error creating home directory: %w", err)
+	}
+
+	fileName := filepath.Join(s.HomeDirectory, currentProfileFile)
+
+	if name == DefaultProfile {
+		if err = os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Error saving current profile: %w", err)
+		}
+
+		return nil
+	}
+
+	return storage.WriteFile(afero.NewOsFs(), fileName, []byte(name+"\n"), 0644)
+}
@@ -0,0 +1,119 @@
+package projects
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/adampresley/mytime/api/helpers"
+	"github.com/adampresley/mytime/api/storage"
+)
+
+// MemoryProjectRepository keeps projects in memory.
+type MemoryProjectRepository struct {
+	mutex   *sync.RWMutex
+	records map[int]Project
+}
+
+func NewMemoryProjectRepository() MemoryProjectRepository {
+	return MemoryProjectRepository{
+		mutex:   &sync.RWMutex{},
+		records: make(map[int]Project),
+	}
+}
+
+func (r MemoryProjectRepository) Create(project Project) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	project.ProjectID = 1
+
+	for id := range r.records {
+		if id >= project.ProjectID {
+			project.ProjectID = id + 1
+		}
+	}
+
+	r.records[project.ProjectID] = project
+	return project.ProjectID, nil
+}
+
+func (r MemoryProjectRepository) Delete(project Project) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.records[project.ProjectID]; !ok {
+		return fmt.Errorf("failed to delete, unable to find any Project record with projectID %d", project.ProjectID)
+	}
+
+	delete(r.records, project.ProjectID)
+	return nil
+}
+
+// GetByCode ignores case, as SimdbProjectRepository does.
+func (r MemoryProjectRepository) GetByCode(code string) (Project, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	ids := r.sortedIDs()
+	codes := make(helpers.Codes, 0, len(ids))
+
+	for _, id := range ids {
+		codes = append(codes, r.records[id].Code)
+	}
+
+	if index := codes.Match(code); index >= 0 {
+		return r.records[ids[index]], nil
+	}
+
+	return Project{}, storage.ErrNotFound
+}
+
+func (r MemoryProjectRepository) GetByID(id int) (Project, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if project, ok := r.records[id]; ok {
+		return project, nil
+	}
+
+	return Project{}, storage.ErrNotFound
+}
+
+func (r MemoryProjectRepository) List(archived bool) (ProjectCollection, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make(ProjectCollection, 0, len(r.records))
+
+	for _, id := range r.sortedIDs() {
+		if r.records[id].Archived == archived {
+			result = append(result, r.records[id])
+		}
+	}
+
+	return result, nil
+}
+
+func (r MemoryProjectRepository) Update(project Project) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.records[project.ProjectID]; !ok {
+		return fmt.Errorf("failed to update, unable to find any Project record with projectID %d", project.ProjectID)
+	}
+
+	r.records[project.ProjectID] = project
+	return nil
+}
+
+func (r MemoryProjectRepository) sortedIDs() []int {
+	result := make([]int, 0, len(r.records))
+
+	for id := range r.records {
+		result = append(result, id)
+	}
+
+	sort.Ints(result)
+	return result
+}
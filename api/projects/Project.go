@@ -18,5 +18,6 @@ type ProjectCollection []Project
 type ProjectSearch struct {
 	Archived bool
 	Client   string
+	ClientID int
 	Name     string
 }
@@ -0,0 +1,11 @@
+package projects
+
+// ProjectRepository stores and retrieves projects.
+type ProjectRepository interface {
+	Create(project Project) (int, error)
+	Delete(project Project) error
+	GetByCode(code string) (Project, error)
+	GetByID(id int) (Project, error)
+	List(archived bool) (ProjectCollection, error)
+	Update(project Project) error
+}
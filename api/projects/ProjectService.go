@@ -1,77 +1,101 @@
 package projects
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/adampresley/mytime/api/apperrors"
 	"github.com/adampresley/mytime/api/clients"
 	"github.com/adampresley/mytime/api/helpers"
-	"github.com/adampresley/simdb"
+	"github.com/adampresley/mytime/api/storage"
 )
 
 type ProjectServicer interface {
 	ArchiveProject(code string) error
 	CreateProject(project Project) (int, error)
+	DeleteProject(code string) error
 	GetProjectByCode(code string) (Project, error)
 	GetProjectByID(id int) (Project, error)
 	ListProjects(search ProjectSearch) (ProjectCollection, error)
+	UnarchiveProject(code string) error
 	UpdateProject(project Project) error
 }
 
 type ProjectServiceConfig struct {
-	ClientService clients.ClientServicer
-	DB            *simdb.Driver
-	HelperService helpers.HelperServicer
+	ClientService     clients.ClientServicer
+	HelperService     helpers.HelperServicer
+	ProjectRepository ProjectRepository
 }
 
 type ProjectService struct {
-	ClientService clients.ClientServicer
-	DB            *simdb.Driver
-	HelperService helpers.HelperServicer
+	ClientService     clients.ClientServicer
+	HelperService     helpers.HelperServicer
+	ProjectRepository ProjectRepository
 }
 
 func NewProjectService(config ProjectServiceConfig) ProjectService {
 	return ProjectService{
-		ClientService: config.ClientService,
-		DB:            config.DB,
-		HelperService: config.HelperService,
+		ClientService:     config.ClientService,
+		HelperService:     config.HelperService,
+		ProjectRepository: config.ProjectRepository,
 	}
 }
 
 func (s ProjectService) ArchiveProject(code string) error {
-	var err error
-	var project Project
+	return s.setArchived(code, true)
+}
 
-	if project, err = s.GetProjectByCode(code); err != nil {
-		return err
+func (s ProjectService) CreateProject(project Project) (int, error) {
+	var (
+		err   error
+		id    int
+		codes helpers.Codes
+	)
+
+	if codes, err = s.codes(0); err != nil {
+		return 0, err
 	}
 
-	project.Archived = true
+	if project.Code == "" {
+		project.Code = s.HelperService.CreateAutoCode(project.Name, codes.Taken)
+	}
 
-	if err = s.DB.Open(Project{}).Update(project); err != nil {
-		return fmt.Errorf("Error updating s.DB: %w", err)
+	if codes.Taken(project.Code) {
+		return 0, apperrors.DuplicateCode("Project", project.Code)
 	}
 
-	return nil
+	if id, err = s.ProjectRepository.Create(project); err != nil {
+		return 0, fmt.Errorf("Error creating new project: %w", err)
+	}
+
+	return id, nil
 }
 
-func (s ProjectService) CreateProject(project Project) (int, error) {
+func (s ProjectService) DeleteProject(code string) error {
 	var err error
+	var project Project
 
-	project.ProjectID = s.DB.Open(Project{}).GetNextNumericID()
+	if project, err = s.GetProjectByCode(code); err != nil {
+		return err
+	}
 
-	if err = s.DB.Insert(project); err != nil {
-		return 0, fmt.Errorf("Error creating new project: %w", err)
+	if err = s.ProjectRepository.Delete(project); err != nil {
+		return fmt.Errorf("Error deleting project: %w", err)
 	}
 
-	return project.ProjectID, nil
+	return nil
 }
 
 func (s ProjectService) GetProjectByCode(code string) (Project, error) {
 	var err error
 	var project Project
 
-	if err = s.DB.Open(Project{}).Where("code", "=", code).First().AsEntity(&project); err != nil {
+	if project, err = s.ProjectRepository.GetByCode(code); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return project, apperrors.NotFound("Project", "code", code)
+		}
+
 		return project, fmt.Errorf("Error querying for project: %w", err)
 	}
 
@@ -82,7 +106,11 @@ func (s ProjectService) GetProjectByID(id int) (Project, error) {
 	var err error
 	var project Project
 
-	if err = s.DB.Open(Project{}).Where("projectID", "=", id).First().AsEntity(&project); err != nil {
+	if project, err = s.ProjectRepository.GetByID(id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return project, apperrors.NotFound("Project", "ID", id)
+		}
+
 		return project, fmt.Errorf("Error querying for project: %w", err)
 	}
 
@@ -91,35 +119,56 @@ func (s ProjectService) GetProjectByID(id int) (Project, error) {
 
 func (s ProjectService) ListProjects(search ProjectSearch) (ProjectCollection, error) {
 	var err error
-	var d *simdb.Driver
-
-	result := make(ProjectCollection, 0, 10)
+	var result ProjectCollection
 
-	d = s.DB.Open(Project{})
-
-	if search.Archived {
-		d = d.Where("archived", "=", true)
-	} else {
-		d = d.Where("archived", "=", false)
+	if result, err = s.ProjectRepository.List(search.Archived); err != nil {
+		return result, fmt.Errorf("Error querying for Projects: %w", err)
 	}
 
 	if search.Name != "" {
-		d = d.Where("name", "contains", search.Name)
-	}
-
-	if err = d.Get().AsEntity(&result); err != nil {
-		return result, fmt.Errorf("Error querying for Projects: %w", err)
+		result = s.filterProjectsByName(result, search.Name)
 	}
 
 	if search.Client != "" {
 		result = s.filterProjectsByClient(result, search.Client)
 	}
 
+	if search.ClientID > 0 {
+		filtered := make(ProjectCollection, 0, len(result))
+
+		for _, p := range result {
+			if p.ClientID == search.ClientID {
+				filtered = append(filtered, p)
+			}
+		}
+
+		result = filtered
+	}
+
 	return result, nil
 }
 
+func (s ProjectService) UnarchiveProject(code string) error {
+	return s.setArchived(code, false)
+}
+
 func (s ProjectService) UpdateProject(project Project) error {
-	return s.DB.Update(project)
+	var err error
+	var codes helpers.Codes
+
+	if project.Code == "" {
+		return apperrors.Validation("Project code can't be empty")
+	}
+
+	if codes, err = s.codes(project.ProjectID); err != nil {
+		return err
+	}
+
+	if codes.Taken(project.Code) {
+		return apperrors.DuplicateCode("Project", project.Code)
+	}
+
+	return s.ProjectRepository.Update(project)
 }
 
 func (s ProjectService) filterProjectsByClient(projects ProjectCollection, client string) ProjectCollection {
@@ -136,3 +185,80 @@ func (s ProjectService) filterProjectsByClient(projects ProjectCollection, clien
 
 	return result
 }
+
+func (s ProjectService) filterProjectsByName(projects ProjectCollection, name string) ProjectCollection {
+	result := make(ProjectCollection, 0, 50)
+
+	lowerName := strings.ToLower(name)
+
+	for _, p := range projects {
+		if strings.Contains(strings.ToLower(p.Name), lowerName) {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
+
+// codes returns the codes of every project but exceptID.
+func (s ProjectService) codes(exceptID int) (helpers.Codes, error) {
+	var err error
+	var all ProjectCollection
+
+	if all, err = s.listAll(); err != nil {
+		return nil, err
+	}
+
+	result := make(helpers.Codes, 0, len(all))
+
+	for _, c := range all {
+		if c.ProjectID != exceptID {
+			result = append(result, c.Code)
+		}
+	}
+
+	return result, nil
+}
+
+// listAll returns active and archived projects.
+func (s ProjectService) listAll() (ProjectCollection, error) {
+	var err error
+	var active, archived ProjectCollection
+
+	if active, err = s.ProjectRepository.List(false); err != nil {
+		return active, fmt.Errorf("Error querying for projects: %w", err)
+	}
+
+	if archived, err = s.ProjectRepository.List(true); err != nil {
+		return active, fmt.Errorf("Error querying for projects: %w", err)
+	}
+
+	return append(active, archived...), nil
+}
+
+// setArchived archives or unarchives a project. Asking for the state it is
+// already in is a conflict, so a mistyped code doesn't go unnoticed.
+func (s ProjectService) setArchived(code string, archived bool) error {
+	var err error
+	var project Project
+
+	if project, err = s.GetProjectByCode(code); err != nil {
+		return err
+	}
+
+	if project.Archived == archived {
+		if archived {
+			return apperrors.Conflict("Project %s is already archived", code)
+		}
+
+		return apperrors.Conflict("Project %s is not archived", code)
+	}
+
+	project.Archived = archived
+
+	if err = s.ProjectRepository.Update(project); err != nil {
+		return fmt.Errorf("Error updating project: %w", err)
+	}
+
+	return nil
+}
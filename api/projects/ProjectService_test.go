@@ -0,0 +1,150 @@
+package projects
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/adampresley/mytime/api/apperrors"
+	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/helpers"
+)
+
+/*
+ * newTestProjectService has two clients, acme (1) and globex (2), with
+ * projects web and api for acme, and an archived project legacy for
+ * globex.
+ */
+func newTestProjectService(t *testing.T) ProjectService {
+	t.Helper()
+
+	clientRepository := clients.NewMemoryClientRepository()
+	clientRepository.Create(clients.Client{Name: "Acme", Code: "acme"})
+	clientRepository.Create(clients.Client{Name: "Globex", Code: "globex"})
+
+	service := NewProjectService(ProjectServiceConfig{
+		ClientService:     clients.NewClientService(clients.ClientServiceConfig{ClientRepository: clientRepository}),
+		HelperService:     helpers.NewHelperService(helpers.HelperServiceConfig{}),
+		ProjectRepository: NewMemoryProjectRepository(),
+	})
+
+	for _, p := range []Project{
+		{Name: "Web Site", Code: "web", ClientID: 1},
+		{Name: "Public Interface", Code: "api", ClientID: 1},
+		{Name: "Legacy Site", Code: "legacy", ClientID: 2, Archived: true},
+	} {
+		if _, err := service.ProjectRepository.Create(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return service
+}
+
+func TestCreateProjectCodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		project       Project
+		wantCode      string
+		wantDuplicate bool
+	}{
+		{name: "new code", project: Project{Name: "Mobile", Code: "mobile", ClientID: 2}, wantCode: "mobile"},
+		{name: "taken code", project: Project{Name: "Web", Code: "web", ClientID: 2}, wantDuplicate: true},
+		{name: "taken code in another case", project: Project{Name: "Web", Code: "Web", ClientID: 2}, wantDuplicate: true},
+		{name: "taken by an archived project", project: Project{Name: "Legacy", Code: "legacy", ClientID: 1}, wantDuplicate: true},
+		{name: "auto code", project: Project{Name: "Data Warehouse", ClientID: 2}, wantCode: "data-warehouse"},
+		{name: "auto code already taken", project: Project{Name: "API", ClientID: 2}, wantCode: "api2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var duplicate *apperrors.DuplicateCodeError
+
+			service := newTestProjectService(t)
+			id, err := service.CreateProject(tt.project)
+
+			if tt.wantDuplicate {
+				if !errors.As(err, &duplicate) {
+					t.Errorf("CreateProject() = %v, want a duplicate code error", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("CreateProject() = %v", err)
+			}
+
+			got, _ := service.GetProjectByID(id)
+
+			if got.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", got.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUpdateProjectCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		wantErr bool
+	}{
+		{name: "keeps its own code", code: "web"},
+		{name: "new code", code: "www"},
+		{name: "taken by another project", code: "API", wantErr: true},
+		{name: "taken by an archived project", code: "legacy", wantErr: true},
+		{name: "empty code", code: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestProjectService(t)
+			project, _ := service.GetProjectByID(1)
+			project.Code = tt.code
+
+			if err := service.UpdateProject(project); (err != nil) != tt.wantErr {
+				t.Errorf("UpdateProject() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListProjects(t *testing.T) {
+	tests := []struct {
+		name   string
+		search ProjectSearch
+		want   string
+	}{
+		{name: "active", search: ProjectSearch{}, want: "web,api"},
+		{name: "archived", search: ProjectSearch{Archived: true}, want: "legacy"},
+		{name: "name", search: ProjectSearch{Name: "Site"}, want: "web"},
+		{name: "name ignores case", search: ProjectSearch{Name: "site"}, want: "web"},
+		{name: "name doesn't match codes", search: ProjectSearch{Name: "api"}, want: ""},
+		{name: "client name", search: ProjectSearch{Client: "acm"}, want: "web,api"},
+		{name: "client code", search: ProjectSearch{Client: "GLOBEX", Archived: true}, want: "legacy"},
+		{name: "client ID", search: ProjectSearch{ClientID: 2}, want: ""},
+		{name: "name and client", search: ProjectSearch{Name: "interface", Client: "acme"}, want: "api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestProjectService(t)
+			result, err := service.ListProjects(tt.search)
+
+			if err != nil {
+				t.Fatalf("ListProjects() = %v", err)
+			}
+
+			got := make([]string, 0, len(result))
+
+			for _, p := range result {
+				got = append(got, p.Code)
+			}
+
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("ListProjects() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
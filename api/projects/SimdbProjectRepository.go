@@ -0,0 +1,110 @@
+package projects
+
+import (
+	"github.com/adampresley/mytime/api/helpers"
+	"github.com/adampresley/mytime/api/storage"
+	"github.com/adampresley/simdb"
+)
+
+// SimdbProjectRepository keeps projects in a simdb JSON collection.
+type SimdbProjectRepository struct {
+	DB     *simdb.Driver
+	Fs     storage.AtomicFs
+	Locker storage.Locker
+}
+
+func NewSimdbProjectRepository(db *simdb.Driver, fs storage.AtomicFs, locker storage.Locker) SimdbProjectRepository {
+	return SimdbProjectRepository{
+		DB:     db,
+		Fs:     fs,
+		Locker: locker,
+	}
+}
+
+func (r SimdbProjectRepository) Create(project Project) (int, error) {
+	if err := r.Locker.Lock(); err != nil {
+		return 0, err
+	}
+
+	defer r.Locker.Unlock()
+
+	project.ProjectID = r.DB.Open(Project{}).GetNextNumericID()
+	return project.ProjectID, r.Fs.Committed(r.DB.Insert(project))
+}
+
+// GetByCode ignores case. simdb can't compare without case, so its
+// contains, which ignores case, narrows the records down first.
+func (r SimdbProjectRepository) GetByCode(code string) (Project, error) {
+	if err := r.Locker.RLock(); err != nil {
+		return Project{}, err
+	}
+
+	defer r.Locker.RUnlock()
+
+	candidates := make(ProjectCollection, 0, 2)
+
+	if err := r.DB.Open(Project{}).Where("code", "contains", code).Get().AsEntity(&candidates); err != nil {
+		return Project{}, storage.FromSimdbError(err)
+	}
+
+	codes := make(helpers.Codes, 0, len(candidates))
+
+	for _, c := range candidates {
+		codes = append(codes, c.Code)
+	}
+
+	if index := codes.Match(code); index >= 0 {
+		return candidates[index], nil
+	}
+
+	return Project{}, storage.ErrNotFound
+}
+
+func (r SimdbProjectRepository) Delete(project Project) error {
+	if err := r.Locker.Lock(); err != nil {
+		return err
+	}
+
+	defer r.Locker.Unlock()
+
+	type Project struct{ storage.SimdbKey }
+
+	key := Project{storage.SimdbKey{Field: "projectID", Value: float64(project.ProjectID)}}
+	return r.Fs.Committed(r.DB.Open(Project{}).Delete(key))
+}
+
+func (r SimdbProjectRepository) GetByID(id int) (Project, error) {
+	if err := r.Locker.RLock(); err != nil {
+		return Project{}, err
+	}
+
+	defer r.Locker.RUnlock()
+
+	var project Project
+
+	err := r.DB.Open(Project{}).Where("projectID", "=", id).First().AsEntity(&project)
+	return project, storage.FromSimdbError(err)
+}
+
+func (r SimdbProjectRepository) List(archived bool) (ProjectCollection, error) {
+	if err := r.Locker.RLock(); err != nil {
+		return nil, err
+	}
+
+	defer r.Locker.RUnlock()
+
+	result := make(ProjectCollection, 0, 10)
+
+	err := r.DB.Open(Project{}).Where("archived", "=", archived).Get().AsEntity(&result)
+	return result, storage.FromSimdbError(err)
+}
+
+func (r SimdbProjectRepository) Update(project Project) error {
+	if err := r.Locker.Lock(); err != nil {
+		return err
+	}
+
+	defer r.Locker.Unlock()
+
+	return r.Fs.Committed(r.DB.Open(Project{}).Update(project))
+}
@@ -0,0 +1,146 @@
+package sessions
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/adampresley/mytime/api/storage"
+)
+
+// MemorySessionRepository keeps sessions in memory.
+type MemorySessionRepository struct {
+	mutex   *sync.RWMutex
+	records map[int]Session
+}
+
+// MemoryActiveSessionRepository keeps the running timer in memory.
+type MemoryActiveSessionRepository struct {
+	mutex   *sync.RWMutex
+	records *[]ActiveSession
+}
+
+func NewMemorySessionRepository() MemorySessionRepository {
+	return MemorySessionRepository{
+		mutex:   &sync.RWMutex{},
+		records: make(map[int]Session),
+	}
+}
+
+func NewMemoryActiveSessionRepository() MemoryActiveSessionRepository {
+	records := make([]ActiveSession, 0, 1)
+
+	return MemoryActiveSessionRepository{
+		mutex:   &sync.RWMutex{},
+		records: &records,
+	}
+}
+
+func (r MemorySessionRepository) Create(session Session) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	session.SessionID = 1
+
+	for id := range r.records {
+		if id >= session.SessionID {
+			session.SessionID = id + 1
+		}
+	}
+
+	r.records[session.SessionID] = session
+	return session.SessionID, nil
+}
+
+func (r MemorySessionRepository) Delete(session Session) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.records[session.SessionID]; !ok {
+		return fmt.Errorf("failed to delete, unable to find any Session record with sessionID %d", session.SessionID)
+	}
+
+	delete(r.records, session.SessionID)
+	return nil
+}
+
+func (r MemorySessionRepository) GetByID(id int) (Session, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if session, ok := r.records[id]; ok {
+		return session, nil
+	}
+
+	return Session{}, storage.ErrNotFound
+}
+
+func (r MemorySessionRepository) List() (SessionCollection, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	ids := make([]int, 0, len(r.records))
+
+	for id := range r.records {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+	result := make(SessionCollection, 0, len(ids))
+
+	for _, id := range ids {
+		result = append(result, r.records[id])
+	}
+
+	return result, nil
+}
+
+func (r MemorySessionRepository) Update(session Session) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.records[session.SessionID]; !ok {
+		return fmt.Errorf("failed to update, unable to find any Session record with sessionID %d", session.SessionID)
+	}
+
+	r.records[session.SessionID] = session
+	return nil
+}
+
+func (r MemoryActiveSessionRepository) Create(activeSession ActiveSession) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	*r.records = append(*r.records, activeSession)
+	return nil
+}
+
+func (r MemoryActiveSessionRepository) Delete(activeSession ActiveSession) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	remaining := make([]ActiveSession, 0, len(*r.records))
+
+	for _, as := range *r.records {
+		if as.ActiveSessionID != activeSession.ActiveSessionID {
+			remaining = append(remaining, as)
+		}
+	}
+
+	if len(remaining) == len(*r.records) {
+		return fmt.Errorf("failed to delete, unable to find any ActiveSession record with activeSessionID %s", activeSession.ActiveSessionID)
+	}
+
+	*r.records = remaining
+	return nil
+}
+
+func (r MemoryActiveSessionRepository) List() ([]ActiveSession, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]ActiveSession, len(*r.records))
+	copy(result, *r.records)
+
+	return result, nil
+}
@@ -25,11 +25,25 @@ type SessionCollection []Session
 type SessionSearch struct {
 	CategoryCode string
 	ClientCode   string
+	From         time.Time
 	Invoiced     bool
 	Paid         bool
 	ProjectCode  string
 	SessionID    int
 	SessionIDs   []int
+	To           time.Time
+}
+
+// ClientMismatch is a session whose ClientID isn't the client of its
+// project. Active is set when it is the running timer, which has no
+// session ID yet.
+type ClientMismatch struct {
+	SessionID       int  `json:"sessionID"`
+	Active          bool `json:"active"`
+	ProjectID       int  `json:"projectID"`
+	ClientID        int  `json:"clientID"`
+	ProjectClientID int  `json:"projectClientID"`
+	Invoiced        bool `json:"invoiced"`
 }
 
 type ActiveSession struct {
@@ -0,0 +1,17 @@
+package sessions
+
+// SessionRepository stores and retrieves recorded sessions.
+type SessionRepository interface {
+	Create(session Session) (int, error)
+	Delete(session Session) error
+	GetByID(id int) (Session, error)
+	List() (SessionCollection, error)
+	Update(session Session) error
+}
+
+// ActiveSessionRepository stores the timer that is currently running.
+type ActiveSessionRepository interface {
+	Create(activeSession ActiveSession) error
+	Delete(activeSession ActiveSession) error
+	List() ([]ActiveSession, error)
+}
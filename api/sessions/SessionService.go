@@ -1,52 +1,67 @@
 package sessions
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/adampresley/mytime/api/apperrors"
 	"github.com/adampresley/mytime/api/categories"
 	"github.com/adampresley/mytime/api/clients"
 	"github.com/adampresley/mytime/api/helpers"
 	"github.com/adampresley/mytime/api/projects"
-	"github.com/adampresley/simdb"
+	"github.com/adampresley/mytime/api/storage"
 )
 
 type SessionServicer interface {
 	CloseSession(sessionID int) error
-	CreateSession(session Session) (string, error)
+	CreateSession(session Session) (int, error)
 	DeleteActiveSessions() error
+	DeleteSession(sessionID int) error
+	FindClientMismatches() ([]ClientMismatch, error)
 	HasActiveSession() (bool, error)
 	GetActiveSession() (ActiveSession, error)
 	GetSessionByID(sessionID int) (Session, error)
 	InvoiceSessions(sessionIDs []int) []error
 	InvoiceSession(sessionID int) error
+	ListAllSessions() (SessionCollection, error)
 	ListSessions(search SessionSearch) (SessionCollection, error)
+	MoveProjectSessions(projectID, clientID int, includeInvoiced bool) (int, error)
+	RepairClientMismatches(mismatches []ClientMismatch) error
 	StartActiveSession(projectID, categoryID, clientID int, notes string) (ActiveSession, time.Time, error)
+	UpdateActiveSession(activeSession ActiveSession) error
+	UpdateSession(session Session) error
 }
 
 type SessionServiceConfig struct {
-	CategoryService categories.CategoryServicer
-	ClientService   clients.ClientServicer
-	DB              *simdb.Driver
-	HelperService   helpers.HelperServicer
-	ProjectService  projects.ProjectServicer
+	ActiveSessionRepository ActiveSessionRepository
+	CategoryService         categories.CategoryServicer
+	ClientService           clients.ClientServicer
+	HelperService           helpers.HelperServicer
+	Journal                 storage.Journal
+	ProjectService          projects.ProjectServicer
+	SessionRepository       SessionRepository
 }
 
 type SessionService struct {
-	CategoryService categories.CategoryServicer
-	ClientService   clients.ClientServicer
-	DB              *simdb.Driver
-	HelperService   helpers.HelperServicer
-	ProjectService  projects.ProjectServicer
+	ActiveSessionRepository ActiveSessionRepository
+	CategoryService         categories.CategoryServicer
+	ClientService           clients.ClientServicer
+	HelperService           helpers.HelperServicer
+	Journal                 storage.Journal
+	ProjectService          projects.ProjectServicer
+	SessionRepository       SessionRepository
 }
 
 func NewSessionService(config SessionServiceConfig) SessionService {
 	return SessionService{
-		CategoryService: config.CategoryService,
-		ClientService:   config.ClientService,
-		DB:              config.DB,
-		HelperService:   config.HelperService,
-		ProjectService:  config.ProjectService,
+		ActiveSessionRepository: config.ActiveSessionRepository,
+		CategoryService:         config.CategoryService,
+		ClientService:           config.ClientService,
+		HelperService:           config.HelperService,
+		Journal:                 config.Journal,
+		ProjectService:          config.ProjectService,
+		SessionRepository:       config.SessionRepository,
 	}
 }
 
@@ -55,11 +70,11 @@ func (s SessionService) CloseSession(sessionID int) error {
 	var session Session
 
 	if session, err = s.GetSessionByID(sessionID); err != nil {
-		return fmt.Errorf("Cannot find session %d: %w", sessionID, err)
+		return err
 	}
 
 	if session.Invoiced && session.Paid {
-		return fmt.Errorf("Session already closed!")
+		return apperrors.Conflict("Session already closed!")
 	}
 
 	session.Invoiced = true
@@ -67,7 +82,7 @@ func (s SessionService) CloseSession(sessionID int) error {
 	session.Paid = true
 	session.PaidDate = time.Now()
 
-	err = s.DB.Open(Session{}).Update(session)
+	err = s.SessionRepository.Update(session)
 
 	if err != nil {
 		return fmt.Errorf("Error updating session %d: %w", sessionID, err)
@@ -77,21 +92,19 @@ func (s SessionService) CloseSession(sessionID int) error {
 }
 
 func (s SessionService) CreateSession(session Session) (int, error) {
-	session.SessionID = s.DB.Open(Session{}).GetNextNumericID()
-
-	return session.SessionID, s.DB.Open(Session{}).Insert(session)
+	return s.SessionRepository.Create(session)
 }
 
 func (s SessionService) DeleteActiveSessions() error {
 	var err error
 	var activeSessions []ActiveSession
 
-	if err = s.DB.Open(ActiveSession{}).Get().AsEntity(&activeSessions); err != nil {
+	if activeSessions, err = s.ActiveSessionRepository.List(); err != nil {
 		return fmt.Errorf("Error getting a list of active sessions: %w", err)
 	}
 
 	for _, as := range activeSessions {
-		if err = s.DB.Open(ActiveSession{}).Delete(as); err != nil {
+		if err = s.ActiveSessionRepository.Delete(as); err != nil {
 			return fmt.Errorf("Error deleting active session %s: %w", as.ActiveSessionID, err)
 		}
 	}
@@ -99,6 +112,105 @@ func (s SessionService) DeleteActiveSessions() error {
 	return nil
 }
 
+func (s SessionService) DeleteSession(sessionID int) error {
+	var err error
+	var session Session
+
+	if session, err = s.GetSessionByID(sessionID); err != nil {
+		return err
+	}
+
+	if err = s.SessionRepository.Delete(session); err != nil {
+		return fmt.Errorf("Error deleting session: %w", err)
+	}
+
+	return nil
+}
+
+/*
+ * FindClientMismatches looks for sessions, and the running timer, whose
+ * ClientID doesn't match their project's client. Sessions whose project
+ * no longer exists are left alone.
+ */
+func (s SessionService) FindClientMismatches() ([]ClientMismatch, error) {
+	var (
+		err              error
+		all              SessionCollection
+		hasActiveSession bool
+		activeSession    ActiveSession
+	)
+
+	result := make([]ClientMismatch, 0, 10)
+	projectClients := make(map[int]int)
+
+	projectClient := func(projectID int) (int, bool, error) {
+		if clientID, ok := projectClients[projectID]; ok {
+			return clientID, clientID != 0, nil
+		}
+
+		project, err := s.ProjectService.GetProjectByID(projectID)
+
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				projectClients[projectID] = 0
+				return 0, false, nil
+			}
+
+			return 0, false, err
+		}
+
+		projectClients[projectID] = project.ClientID
+		return project.ClientID, true, nil
+	}
+
+	if all, err = s.ListAllSessions(); err != nil {
+		return result, err
+	}
+
+	for _, session := range all {
+		clientID, found, err := projectClient(session.ProjectID)
+
+		if err != nil {
+			return result, err
+		}
+
+		if found && clientID != session.ClientID {
+			result = append(result, ClientMismatch{
+				SessionID:       session.SessionID,
+				ProjectID:       session.ProjectID,
+				ClientID:        session.ClientID,
+				ProjectClientID: clientID,
+				Invoiced:        session.Invoiced,
+			})
+		}
+	}
+
+	if hasActiveSession, err = s.HasActiveSession(); err != nil || !hasActiveSession {
+		return result, err
+	}
+
+	if activeSession, err = s.GetActiveSession(); err != nil {
+		return result, err
+	}
+
+	clientID, found, err := projectClient(activeSession.ProjectID)
+
+	if err != nil {
+		return result, err
+	}
+
+	if found && clientID != activeSession.ClientID {
+		result = append(result, ClientMismatch{
+			Active:          true,
+			ProjectID:       activeSession.ProjectID,
+			ClientID:        activeSession.ClientID,
+			ProjectClientID: clientID,
+		})
+	}
+
+	return result, nil
+}
+
 func (s SessionService) GetActiveSession() (ActiveSession, error) {
 	var (
 		err              error
@@ -111,15 +223,15 @@ func (s SessionService) GetActiveSession() (ActiveSession, error) {
 	}
 
 	if !hasActiveSession {
-		return ActiveSession{}, fmt.Errorf("There is no active session")
+		return ActiveSession{}, apperrors.Conflict("There is no active session")
 	}
 
-	if err = s.DB.Open(ActiveSession{}).Get().AsEntity(&activeSessions); err != nil {
+	if activeSessions, err = s.ActiveSessionRepository.List(); err != nil {
 		return ActiveSession{}, err
 	}
 
 	if len(activeSessions) < 1 {
-		return ActiveSession{}, fmt.Errorf("There is no active sessions")
+		return ActiveSession{}, apperrors.Conflict("There is no active session")
 	}
 
 	return activeSessions[0], nil
@@ -129,34 +241,72 @@ func (s SessionService) GetSessionByID(sessionID int) (Session, error) {
 	var err error
 	var session Session
 
-	err = s.DB.Open(Session{}).Where("sessionID", "=", sessionID).First().AsEntity(&session)
-	return session, err
+	if session, err = s.SessionRepository.GetByID(sessionID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return session, apperrors.NotFound("Session", "ID", sessionID)
+		}
+
+		return session, fmt.Errorf("Error finding session %d: %w", sessionID, err)
+	}
+
+	return session, nil
 }
 
 func (s SessionService) HasActiveSession() (bool, error) {
 	var err error
 	var sessions []ActiveSession
 
-	if err = s.DB.Open(ActiveSession{}).Get().AsEntity(&sessions); err != nil {
+	if sessions, err = s.ActiveSessionRepository.List(); err != nil {
 		return false, err
 	}
 
 	return len(sessions) > 0, nil
 }
 
+/*
+ * InvoiceSessions invoices all of the sessions or none of them. The
+ * result has an error for each session that couldn't be invoiced, and if
+ * the journal can't be begun, rolled back, or committed, that error is
+ * given for every session.
+ */
 func (s SessionService) InvoiceSessions(sessionIDs []int) []error {
+	var err error
+
 	result := make([]error, len(sessionIDs))
 
-	for index, sessionID := range sessionIDs {
-		err := s.InvoiceSession(sessionID)
+	everySession := func(err error) []error {
+		for index := range result {
+			result[index] = fmt.Errorf("Session ID: %d - %w", sessionIDs[index], err)
+		}
 
-		if err != nil {
+		return result
+	}
+
+	if err = s.Journal.Begin("invoice sessions"); err != nil {
+		return everySession(err)
+	}
+
+	failed := false
+
+	for index, sessionID := range sessionIDs {
+		if err = s.InvoiceSession(sessionID); err != nil {
 			result[index] = fmt.Errorf("Session ID: %d - %w", sessionID, err)
-		} else {
-			result[index] = err
+			failed = true
 		}
 	}
 
+	if failed {
+		if err = s.Journal.Rollback(); err != nil {
+			return everySession(fmt.Errorf("Problem undoing the invoicing: %w", err))
+		}
+
+		return result
+	}
+
+	if err = s.Journal.Commit(); err != nil {
+		return everySession(err)
+	}
+
 	return result
 }
 
@@ -169,11 +319,11 @@ func (s SessionService) InvoiceSession(sessionID int) error {
 	}
 
 	if session.Invoiced {
-		return fmt.Errorf("Session already invoiced")
+		return apperrors.Conflict("Session already invoiced")
 	}
 
 	if session.Paid {
-		return fmt.Errorf("Cannot invoice a session that is already paid")
+		return apperrors.Conflict("Cannot invoice a session that is already paid")
 	}
 
 	session.Invoiced = true
@@ -182,27 +332,22 @@ func (s SessionService) InvoiceSession(sessionID int) error {
 	return s.UpdateSession(session)
 }
 
-func (s SessionService) ListSessions(search SessionSearch) (SessionCollection, error) {
+func (s SessionService) ListAllSessions() (SessionCollection, error) {
 	var err error
+	var result SessionCollection
 
-	// allSessions := make(SessionCollection, 0, 100)
-	result := make(SessionCollection, 0, 100)
-
-	d := s.DB.Open(Session{})
-
-	if search.Paid {
-		d = d.Where("paid", "=", true)
-	} else {
-		d = d.Where("paid", "=", false)
+	if result, err = s.SessionRepository.List(); err != nil {
+		return result, fmt.Errorf("Error querying for sessions: %w", err)
 	}
 
-	if search.Invoiced {
-		d = d.Where("invoiced", "=", true)
-	} else {
-		d = d.Where("invoiced", "=", false)
-	}
+	return result, nil
+}
+
+func (s SessionService) ListSessions(search SessionSearch) (SessionCollection, error) {
+	var err error
+	var result SessionCollection
 
-	if err = d.Get().AsEntity(&result); err != nil {
+	if result, err = s.SessionRepository.List(); err != nil {
 		return result, fmt.Errorf("Error querying for sessions: %w", err)
 	}
 
@@ -221,51 +366,55 @@ func (s SessionService) ListSessions(search SessionSearch) (SessionCollection, e
 		return r
 	}
 
-	if search.CategoryCode != "" {
-		result = filter(func(session Session) bool {
-			var category categories.Category
+	result = filter(func(session Session) bool {
+		return session.Paid == search.Paid && session.Invoiced == search.Invoiced
+	})
 
-			if category, err = s.CategoryService.GetCategoryByID(session.CategoryID); err != nil {
-				return false
-			}
+	if search.CategoryCode != "" {
+		var category categories.Category
 
-			if category.Code == search.CategoryCode {
-				return true
-			}
+		if category, err = s.CategoryService.GetCategoryByCode(search.CategoryCode); err != nil {
+			return result, err
+		}
 
-			return false
+		result = filter(func(session Session) bool {
+			return session.CategoryID == category.CategoryID
 		})
 	}
 
 	if search.ClientCode != "" {
-		result = filter(func(session Session) bool {
-			var client clients.Client
+		var client clients.Client
 
-			if client, err = s.ClientService.GetClientByID(session.ClientID); err != nil {
-				return false
-			}
-
-			if client.Code == search.ClientCode {
-				return true
-			}
+		if client, err = s.ClientService.GetClientByCode(search.ClientCode); err != nil {
+			return result, err
+		}
 
-			return false
+		result = filter(func(session Session) bool {
+			return session.ClientID == client.ClientID
 		})
 	}
 
 	if search.ProjectCode != "" {
-		result = filter(func(session Session) bool {
-			var project projects.Project
+		var project projects.Project
 
-			if project, err = s.ProjectService.GetProjectByID(session.ProjectID); err != nil {
-				return false
-			}
+		if project, err = s.ProjectService.GetProjectByCode(search.ProjectCode); err != nil {
+			return result, err
+		}
 
-			if project.Code == search.ProjectCode {
-				return true
-			}
+		result = filter(func(session Session) bool {
+			return session.ProjectID == project.ProjectID
+		})
+	}
 
-			return false
+	if !search.From.IsZero() {
+		result = filter(func(session Session) bool {
+			return !session.StartDateTime.Before(search.From)
+		})
+	}
+
+	if !search.To.IsZero() {
+		result = filter(func(session Session) bool {
+			return session.StartDateTime.Before(search.To)
 		})
 	}
 
@@ -293,6 +442,92 @@ func (s SessionService) ListSessions(search SessionSearch) (SessionCollection, e
 	return result, err
 }
 
+/*
+ * MoveProjectSessions gives a project's sessions a new client, after the
+ * project has moved to it. Invoiced sessions were billed to the old
+ * client, so they only move when asked. The running timer always moves.
+ */
+func (s SessionService) MoveProjectSessions(projectID, clientID int, includeInvoiced bool) (int, error) {
+	var (
+		err              error
+		all              SessionCollection
+		hasActiveSession bool
+		activeSession    ActiveSession
+	)
+
+	moved := 0
+
+	if all, err = s.ListAllSessions(); err != nil {
+		return 0, err
+	}
+
+	for _, session := range all {
+		if session.ProjectID != projectID || session.ClientID == clientID || (session.Invoiced && !includeInvoiced) {
+			continue
+		}
+
+		session.ClientID = clientID
+
+		if err = s.UpdateSession(session); err != nil {
+			return moved, fmt.Errorf("Error updating session %d: %w", session.SessionID, err)
+		}
+
+		moved++
+	}
+
+	if hasActiveSession, err = s.HasActiveSession(); err != nil || !hasActiveSession {
+		return moved, err
+	}
+
+	if activeSession, err = s.GetActiveSession(); err != nil {
+		return moved, err
+	}
+
+	if activeSession.ProjectID == projectID && activeSession.ClientID != clientID {
+		activeSession.ClientID = clientID
+
+		if err = s.UpdateActiveSession(activeSession); err != nil {
+			return moved, err
+		}
+	}
+
+	return moved, nil
+}
+
+func (s SessionService) RepairClientMismatches(mismatches []ClientMismatch) error {
+	var err error
+	var session Session
+	var activeSession ActiveSession
+
+	for _, mismatch := range mismatches {
+		if mismatch.Active {
+			if activeSession, err = s.GetActiveSession(); err != nil {
+				return err
+			}
+
+			activeSession.ClientID = mismatch.ProjectClientID
+
+			if err = s.UpdateActiveSession(activeSession); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if session, err = s.GetSessionByID(mismatch.SessionID); err != nil {
+			return err
+		}
+
+		session.ClientID = mismatch.ProjectClientID
+
+		if err = s.UpdateSession(session); err != nil {
+			return fmt.Errorf("Error updating session %d: %w", session.SessionID, err)
+		}
+	}
+
+	return nil
+}
+
 func (s SessionService) StartActiveSession(projectID, categoryID, clientID int, notes string) (ActiveSession, time.Time, error) {
 	var err error
 	var id string
@@ -309,13 +544,36 @@ func (s SessionService) StartActiveSession(projectID, categoryID, clientID int,
 		Notes:           notes,
 	}
 
-	if err = s.DB.Open(ActiveSession{}).Insert(session); err != nil {
+	if err = s.ActiveSessionRepository.Create(session); err != nil {
 		return session, time.Now(), err
 	}
 
 	return session, startTime, nil
 }
 
+// UpdateActiveSession changes the running timer. Active sessions can't be
+// updated in place, so it is replaced with one keeping the same ID.
+func (s SessionService) UpdateActiveSession(activeSession ActiveSession) error {
+	var err error
+	var current ActiveSession
+
+	if current, err = s.GetActiveSession(); err != nil {
+		return err
+	}
+
+	if err = s.ActiveSessionRepository.Delete(current); err != nil {
+		return fmt.Errorf("Error updating active session: %w", err)
+	}
+
+	activeSession.ActiveSessionID = current.ActiveSessionID
+
+	if err = s.ActiveSessionRepository.Create(activeSession); err != nil {
+		return fmt.Errorf("Error updating active session: %w", err)
+	}
+
+	return nil
+}
+
 func (s SessionService) UpdateSession(session Session) error {
-	return s.DB.Open(Session{}).Update(session)
+	return s.SessionRepository.Update(session)
 }
@@ -0,0 +1,217 @@
+package sessions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adampresley/mytime/api/apperrors"
+	"github.com/adampresley/mytime/api/categories"
+	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/helpers"
+	"github.com/adampresley/mytime/api/projects"
+	"github.com/adampresley/mytime/api/storage"
+)
+
+func day(d int) time.Time {
+	return time.Date(2020, time.January, d, 9, 0, 0, 0, time.UTC)
+}
+
+/*
+ * newTestSessionService has clients acme (1) and globex (2), projects web
+ * and api for acme and legacy for globex, categories dev and meet, and
+ * these sessions:
+ *
+ *    1  web     dev   Jan 6
+ *    2  api     meet  Jan 7
+ *    3  legacy  dev   Jan 8  invoiced
+ *    4  legacy  dev   Jan 9  invoiced and paid
+ */
+func newTestSessionService(t *testing.T) SessionService {
+	t.Helper()
+
+	helperService := helpers.NewHelperService(helpers.HelperServiceConfig{})
+	clientRepository := clients.NewMemoryClientRepository()
+	projectRepository := projects.NewMemoryProjectRepository()
+	categoryRepository := categories.NewMemoryCategoryRepository()
+	sessionRepository := NewMemorySessionRepository()
+
+	clientRepository.Create(clients.Client{Name: "Acme", Code: "acme"})
+	clientRepository.Create(clients.Client{Name: "Globex", Code: "globex"})
+
+	projectRepository.Create(projects.Project{Name: "Web", Code: "web", ClientID: 1})
+	projectRepository.Create(projects.Project{Name: "API", Code: "api", ClientID: 1})
+	projectRepository.Create(projects.Project{Name: "Legacy", Code: "legacy", ClientID: 2})
+
+	categoryRepository.Create(categories.Category{Name: "Development", Code: "dev"})
+	categoryRepository.Create(categories.Category{Name: "Meetings", Code: "meet"})
+
+	for _, s := range []Session{
+		{ClientID: 1, ProjectID: 1, CategoryID: 1, StartDateTime: day(6), EndDateTime: day(6).Add(time.Hour)},
+		{ClientID: 1, ProjectID: 2, CategoryID: 2, StartDateTime: day(7), EndDateTime: day(7).Add(time.Hour)},
+		{ClientID: 2, ProjectID: 3, CategoryID: 1, StartDateTime: day(8), EndDateTime: day(8).Add(time.Hour), Invoiced: true},
+		{ClientID: 2, ProjectID: 3, CategoryID: 1, StartDateTime: day(9), EndDateTime: day(9).Add(time.Hour), Invoiced: true, Paid: true},
+	} {
+		if _, err := sessionRepository.Create(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clientService := clients.NewClientService(clients.ClientServiceConfig{
+		ClientRepository: clientRepository,
+		HelperService:    helperService,
+	})
+
+	return NewSessionService(SessionServiceConfig{
+		ActiveSessionRepository: NewMemoryActiveSessionRepository(),
+		CategoryService: categories.NewCategoryService(categories.CategoryServiceConfig{
+			CategoryRepository: categoryRepository,
+			HelperService:      helperService,
+		}),
+		ClientService: clientService,
+		HelperService: helperService,
+		Journal:       storage.NopJournal{},
+		ProjectService: projects.NewProjectService(projects.ProjectServiceConfig{
+			ClientService:     clientService,
+			HelperService:     helperService,
+			ProjectRepository: projectRepository,
+		}),
+		SessionRepository: sessionRepository,
+	})
+}
+
+func sessionIDs(list SessionCollection) string {
+	ids := make([]string, 0, len(list))
+
+	for _, s := range list {
+		ids = append(ids, fmt.Sprint(s.SessionID))
+	}
+
+	return strings.Join(ids, ",")
+}
+
+func TestListSessions(t *testing.T) {
+	tests := []struct {
+		name         string
+		search       SessionSearch
+		want         string
+		wantNotFound bool
+	}{
+		{name: "uninvoiced", search: SessionSearch{}, want: "1,2"},
+		{name: "invoiced", search: SessionSearch{Invoiced: true}, want: "3"},
+		{name: "paid", search: SessionSearch{Invoiced: true, Paid: true}, want: "4"},
+		{name: "client", search: SessionSearch{ClientCode: "acme"}, want: "1,2"},
+		{name: "client with invoiced", search: SessionSearch{ClientCode: "globex", Invoiced: true}, want: "3"},
+		{name: "project", search: SessionSearch{ProjectCode: "api"}, want: "2"},
+		{name: "project code in another case", search: SessionSearch{ProjectCode: "WEB"}, want: "1"},
+		{name: "unknown project", search: SessionSearch{ProjectCode: "nope"}, wantNotFound: true},
+		{name: "unknown client", search: SessionSearch{ClientCode: "nope"}, wantNotFound: true},
+		{name: "category", search: SessionSearch{CategoryCode: "dev"}, want: "1"},
+		{name: "client and category codes in another case", search: SessionSearch{ClientCode: "Acme", CategoryCode: "MEET"}, want: "2"},
+		{name: "from is inclusive", search: SessionSearch{From: day(7)}, want: "2"},
+		{name: "to is exclusive", search: SessionSearch{To: day(7)}, want: "1"},
+		{name: "from and to", search: SessionSearch{From: day(6), To: day(8)}, want: "1,2"},
+		{name: "session ID", search: SessionSearch{SessionID: 2}, want: "2"},
+		{name: "session IDs keep the invoiced filter", search: SessionSearch{SessionIDs: []int{1, 3}}, want: "1"},
+		{name: "project and category", search: SessionSearch{ProjectCode: "web", CategoryCode: "meet"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestSessionService(t)
+			result, err := service.ListSessions(tt.search)
+
+			if tt.wantNotFound {
+				if !errors.Is(err, storage.ErrNotFound) {
+					t.Errorf("ListSessions() = %v, want not found", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ListSessions() = %v", err)
+			}
+
+			if got := sessionIDs(result); got != tt.want {
+				t.Errorf("ListSessions() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeJournal records the journal calls made, and fails Commit with
+// commitErr.
+type fakeJournal struct {
+	calls     *[]string
+	commitErr error
+}
+
+func (j fakeJournal) Begin(operation string) error {
+	*j.calls = append(*j.calls, "begin")
+	return nil
+}
+
+func (j fakeJournal) Commit() error {
+	*j.calls = append(*j.calls, "commit")
+	return j.commitErr
+}
+
+func (j fakeJournal) Rollback() error {
+	*j.calls = append(*j.calls, "rollback")
+	return nil
+}
+
+func TestInvoiceSessions(t *testing.T) {
+	tests := []struct {
+		name       string
+		ids        []int
+		commitErr  error
+		wantErrors string
+		wantCalls  string
+	}{
+		{name: "all invoiced", ids: []int{1, 2}, wantErrors: "-,-", wantCalls: "begin,commit"},
+		{name: "one already invoiced", ids: []int{1, 3}, wantErrors: "-,conflict", wantCalls: "begin,rollback"},
+		{name: "one missing", ids: []int{9, 2}, wantErrors: "not found,-", wantCalls: "begin,rollback"},
+		{name: "commit fails", ids: []int{1, 2}, commitErr: fmt.Errorf("disk full"), wantErrors: "disk full,disk full", wantCalls: "begin,commit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make([]string, 0, 2)
+
+			service := newTestSessionService(t)
+			service.Journal = fakeJournal{calls: &calls, commitErr: tt.commitErr}
+
+			got := make([]string, 0, len(tt.ids))
+
+			for _, err := range service.InvoiceSessions(tt.ids) {
+				var conflict *apperrors.ConflictError
+				var notFound *apperrors.NotFoundError
+
+				switch {
+				case err == nil:
+					got = append(got, "-")
+				case errors.As(err, &conflict):
+					got = append(got, "conflict")
+				case errors.As(err, &notFound):
+					got = append(got, "not found")
+				case errors.Is(err, tt.commitErr):
+					got = append(got, tt.commitErr.Error())
+				default:
+					got = append(got, err.Error())
+				}
+			}
+
+			if strings.Join(got, ",") != tt.wantErrors {
+				t.Errorf("errors = %s, want %s", strings.Join(got, ","), tt.wantErrors)
+			}
+
+			if strings.Join(calls, ",") != tt.wantCalls {
+				t.Errorf("journal calls = %s, want %s", strings.Join(calls, ","), tt.wantCalls)
+			}
+		})
+	}
+}
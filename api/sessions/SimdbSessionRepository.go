@@ -0,0 +1,130 @@
+package sessions
+
+import (
+	"github.com/adampresley/mytime/api/storage"
+	"github.com/adampresley/simdb"
+)
+
+// SimdbSessionRepository keeps sessions in a simdb JSON collection.
+type SimdbSessionRepository struct {
+	DB     *simdb.Driver
+	Fs     storage.AtomicFs
+	Locker storage.Locker
+}
+
+// SimdbActiveSessionRepository keeps the running timer in a simdb JSON
+// collection.
+type SimdbActiveSessionRepository struct {
+	DB     *simdb.Driver
+	Fs     storage.AtomicFs
+	Locker storage.Locker
+}
+
+func NewSimdbSessionRepository(db *simdb.Driver, fs storage.AtomicFs, locker storage.Locker) SimdbSessionRepository {
+	return SimdbSessionRepository{
+		DB:     db,
+		Fs:     fs,
+		Locker: locker,
+	}
+}
+
+func NewSimdbActiveSessionRepository(db *simdb.Driver, fs storage.AtomicFs, locker storage.Locker) SimdbActiveSessionRepository {
+	return SimdbActiveSessionRepository{
+		DB:     db,
+		Fs:     fs,
+		Locker: locker,
+	}
+}
+
+func (r SimdbSessionRepository) Create(session Session) (int, error) {
+	if err := r.Locker.Lock(); err != nil {
+		return 0, err
+	}
+
+	defer r.Locker.Unlock()
+
+	session.SessionID = r.DB.Open(Session{}).GetNextNumericID()
+	return session.SessionID, r.Fs.Committed(r.DB.Open(Session{}).Insert(session))
+}
+
+func (r SimdbSessionRepository) Delete(session Session) error {
+	if err := r.Locker.Lock(); err != nil {
+		return err
+	}
+
+	defer r.Locker.Unlock()
+
+	type Session struct{ storage.SimdbKey }
+
+	key := Session{storage.SimdbKey{Field: "sessionID", Value: float64(session.SessionID)}}
+	return r.Fs.Committed(r.DB.Open(Session{}).Delete(key))
+}
+
+func (r SimdbSessionRepository) GetByID(id int) (Session, error) {
+	if err := r.Locker.RLock(); err != nil {
+		return Session{}, err
+	}
+
+	defer r.Locker.RUnlock()
+
+	var session Session
+
+	err := r.DB.Open(Session{}).Where("sessionID", "=", id).First().AsEntity(&session)
+	return session, storage.FromSimdbError(err)
+}
+
+func (r SimdbSessionRepository) List() (SessionCollection, error) {
+	if err := r.Locker.RLock(); err != nil {
+		return nil, err
+	}
+
+	defer r.Locker.RUnlock()
+
+	result := make(SessionCollection, 0, 100)
+
+	err := r.DB.Open(Session{}).Get().AsEntity(&result)
+	return result, storage.FromSimdbError(err)
+}
+
+func (r SimdbSessionRepository) Update(session Session) error {
+	if err := r.Locker.Lock(); err != nil {
+		return err
+	}
+
+	defer r.Locker.Unlock()
+
+	return r.Fs.Committed(r.DB.Open(Session{}).Update(session))
+}
+
+func (r SimdbActiveSessionRepository) Create(activeSession ActiveSession) error {
+	if err := r.Locker.Lock(); err != nil {
+		return err
+	}
+
+	defer r.Locker.Unlock()
+
+	return r.Fs.Committed(r.DB.Open(ActiveSession{}).Insert(activeSession))
+}
+
+func (r SimdbActiveSessionRepository) Delete(activeSession ActiveSession) error {
+	if err := r.Locker.Lock(); err != nil {
+		return err
+	}
+
+	defer r.Locker.Unlock()
+
+	return r.Fs.Committed(r.DB.Open(ActiveSession{}).Delete(activeSession))
+}
+
+func (r SimdbActiveSessionRepository) List() ([]ActiveSession, error) {
+	if err := r.Locker.RLock(); err != nil {
+		return nil, err
+	}
+
+	defer r.Locker.RUnlock()
+
+	result := make([]ActiveSession, 0, 1)
+
+	err := r.DB.Open(ActiveSession{}).Get().AsEntity(&result)
+	return result, storage.FromSimdbError(err)
+}
@@ -0,0 +1,191 @@
+package settings
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindBool
+	KindChoice
+	KindDuration
+	KindInt
+	KindLayout
+)
+
+// Definition describes one setting in config.yml: its key, what it does,
+// its default, and which values are allowed.
+type Definition struct {
+	Key         string
+	Description string
+	Default     string
+	Kind        Kind
+	Choices     []string
+	Min         int
+	Max         int
+}
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Definitions is the settings schema, in key order.
+var Definitions = []Definition{
+	{
+		Key:         "backup.keep",
+		Description: "Number of automatic backups to keep",
+		Default:     "10",
+		Kind:        KindInt,
+		Min:         1,
+		Max:         1000,
+	},
+	{
+		Key:         "color",
+		Description: "Use colour in output. The NO_COLOR environment variable also turns it off",
+		Default:     "true",
+		Kind:        KindBool,
+	},
+	{
+		Key:         "currency",
+		Description: "Three letter ISO 4217 code used when showing amounts, such as USD or EUR",
+		Default:     "USD",
+		Kind:        KindString,
+	},
+	{
+		Key:         "date.format",
+		Description: "Go layout used to show dates, such as \"Mon Jan _2 2006\" or \"2006-01-02\"",
+		Default:     "Mon Jan _2 2006",
+		Kind:        KindLayout,
+	},
+	{
+		Key:         "interactive.interrupt",
+		Description: "What interactive mode does with the timer when interrupted, such as by Ctrl-C or a closed terminal",
+		Default:     "ask",
+		Kind:        KindChoice,
+		Choices:     []string{"ask", "save", "discard", "keep"},
+	},
+	{
+		Key:         "lock.wait",
+		Description: "How long to wait for another mt process to finish with the data, such as 5s",
+		Default:     "5s",
+		Kind:        KindDuration,
+	},
+	{
+		Key:         "precision",
+		Description: "Decimal places shown for hours and amounts",
+		Default:     "2",
+		Kind:        KindInt,
+		Min:         0,
+		Max:         6,
+	},
+	{
+		Key:         "rounding.increment",
+		Description: "Round durations to this many minutes when reporting. 0 turns rounding off",
+		Default:     "0",
+		Kind:        KindInt,
+		Min:         0,
+		Max:         1440,
+	},
+	{
+		Key:         "rounding.mode",
+		Description: "How durations are rounded to the increment",
+		Default:     "nearest",
+		Kind:        KindChoice,
+		Choices:     []string{"nearest", "up", "down"},
+	},
+	{
+		Key:         "time.clock",
+		Description: "Show times on a 12 or 24 hour clock",
+		Default:     "12h",
+		Kind:        KindChoice,
+		Choices:     []string{"12h", "24h"},
+	},
+	{
+		Key:         "week.start",
+		Description: "First day of the week",
+		Default:     "monday",
+		Kind:        KindChoice,
+		Choices:     []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"},
+	},
+}
+
+// Lookup finds the definition for a key.
+func Lookup(key string) (Definition, bool) {
+	for _, d := range Definitions {
+		if d.Key == key {
+			return d, true
+		}
+	}
+
+	return Definition{}, false
+}
+
+// Normalize tidies a value before it is validated and saved, such as
+// lower casing choices and upper casing currency codes.
+func (d Definition) Normalize(value string) string {
+	value = strings.TrimSpace(value)
+
+	switch d.Kind {
+	case KindBool, KindChoice:
+		return strings.ToLower(value)
+	}
+
+	if d.Key == "currency" {
+		return strings.ToUpper(value)
+	}
+
+	return value
+}
+
+// Validate returns an error describing why a value isn't allowed.
+func (d Definition) Validate(value string) error {
+	switch d.Kind {
+	case KindBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+
+	case KindChoice:
+		for _, choice := range d.Choices {
+			if value == choice {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("must be one of %s", strings.Join(d.Choices, ", "))
+
+	case KindDuration:
+		if duration, err := time.ParseDuration(value); err != nil || duration < 0 {
+			return fmt.Errorf("must be a duration such as 5s or 1m")
+		}
+
+	case KindInt:
+		number, err := strconv.Atoi(value)
+
+		if err != nil || number < d.Min || number > d.Max {
+			return fmt.Errorf("must be a whole number from %d to %d", d.Min, d.Max)
+		}
+
+	case KindLayout:
+		/*
+		 * Any string is technically a layout. One that shows the same text
+		 * for two different days has no date in it, so is a mistake.
+		 */
+		first := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+
+		if value == "" || first.Format(value) == first.AddDate(1, 1, 1).Format(value) {
+			return fmt.Errorf("must be a Go date layout, such as \"Mon Jan _2 2006\" or \"2006-01-02\"")
+		}
+
+	default:
+		if d.Key == "currency" && !currencyPattern.MatchString(value) {
+			return fmt.Errorf("must be a three letter currency code, such as USD")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package settings
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var currencySymbols = map[string]string{
+	"AUD": "A$",
+	"CAD": "C$",
+	"CHF": "CHF ",
+	"CNY": "¥",
+	"EUR": "€",
+	"GBP": "£",
+	"INR": "₹",
+	"JPY": "¥",
+	"NZD": "NZ$",
+	"USD": "$",
+}
+
+// Settings holds the validated values from config.yml, with defaults for
+// anything not set. Commands use it to format what they show.
+type Settings struct {
+	BackupKeep        int
+	Clock             string
+	Color             bool
+	Currency          string
+	DateFormat        string
+	Interrupt         string
+	LockWait          time.Duration
+	Precision         int
+	RoundingIncrement int
+	RoundingMode      string
+	WeekStart         time.Weekday
+}
+
+// Defaults returns the settings used when config.yml is empty.
+func Defaults() Settings {
+	result, _ := fromValues(map[string]string{})
+	return result
+}
+
+func (s Settings) FormatDate(t time.Time) string {
+	return t.Format(s.DateFormat)
+}
+
+func (s Settings) FormatDateTime(t time.Time) string {
+	return s.FormatDate(t) + " " + s.FormatTime(t)
+}
+
+// FormatDuration shows a duration as hours, minutes, and seconds. Hours
+// keep counting past a day.
+func (s Settings) FormatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+func (s Settings) FormatHours(d time.Duration) string {
+	return strconv.FormatFloat(d.Hours(), 'f', s.Precision, 64)
+}
+
+func (s Settings) FormatMoney(amount float64) string {
+	formatted := strconv.FormatFloat(amount, 'f', s.Precision, 64)
+
+	if symbol, ok := currencySymbols[s.Currency]; ok {
+		if strings.HasPrefix(formatted, "-") {
+			return "-" + symbol + formatted[1:]
+		}
+
+		return symbol + formatted
+	}
+
+	return formatted + " " + s.Currency
+}
+
+func (s Settings) FormatTime(t time.Time) string {
+	if s.Clock == "24h" {
+		return t.Format("15:04")
+	}
+
+	return t.Format("3:04 PM")
+}
+
+func (s Settings) FormatTimeWithSeconds(t time.Time) string {
+	if s.Clock == "24h" {
+		return t.Format("15:04:05")
+	}
+
+	return t.Format("3:04:05 PM")
+}
+
+// Round applies the rounding rules to a duration. With no increment set
+// the duration is returned as is.
+func (s Settings) Round(d time.Duration) time.Duration {
+	if s.RoundingIncrement <= 0 {
+		return d
+	}
+
+	increment := time.Duration(s.RoundingIncrement) * time.Minute
+	remainder := d % increment
+
+	switch s.RoundingMode {
+	case "up":
+		if remainder == 0 {
+			return d
+		}
+
+		return d - remainder + increment
+
+	case "down":
+		return d - remainder
+	}
+
+	return d.Round(increment)
+}
+
+// RoundToPrecision rounds hours or an amount to the precision setting's
+// number of decimal places.
+func (s Settings) RoundToPrecision(value float64) float64 {
+	shift := math.Pow(10, float64(s.Precision))
+	return math.Round(value*shift) / shift
+}
+
+// StartOfWeek returns midnight on the first day of the week containing t.
+func (s Settings) StartOfWeek(t time.Time) time.Time {
+	days := (int(t.Weekday()) - int(s.WeekStart) + 7) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-days, 0, 0, 0, 0, t.Location())
+}
+
+func fromValues(values map[string]string) (Settings, error) {
+	var err error
+	var result Settings
+
+	/*
+	 * Values are normalized as they were when validated, so "24H" or
+	 * "UP" in a hand-edited file work as they passed
+	 */
+	get := func(key string) string {
+		d, _ := Lookup(key)
+
+		if value, ok := values[key]; ok {
+			return d.Normalize(value)
+		}
+
+		return d.Default
+	}
+
+	result.BackupKeep, _ = strconv.Atoi(get("backup.keep"))
+	result.Clock = get("time.clock")
+	result.Color, _ = strconv.ParseBool(get("color"))
+	result.Currency = get("currency")
+	result.DateFormat = get("date.format")
+	result.Interrupt = get("interactive.interrupt")
+	result.Precision, _ = strconv.Atoi(get("precision"))
+	result.RoundingIncrement, _ = strconv.Atoi(get("rounding.increment"))
+	result.RoundingMode = get("rounding.mode")
+
+	if result.LockWait, err = time.ParseDuration(get("lock.wait")); err != nil {
+		return result, err
+	}
+
+	weekStart := get("week.start")
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), weekStart) {
+			result.WeekStart = day
+		}
+	}
+
+	return result, nil
+}
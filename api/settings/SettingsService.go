@@ -0,0 +1,294 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adampresley/mytime/api/apperrors"
+	"github.com/adampresley/mytime/api/storage"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+)
+
+type SettingsServicer interface {
+	Get(key string) (Value, error)
+	List() ([]Value, error)
+	Load() (Settings, error)
+	Set(key, value string) error
+	Unset(key string) error
+	ValidateFile(fileName string) error
+}
+
+type SettingsServiceConfig struct {
+	ConfigFile string
+}
+
+type SettingsService struct {
+	ConfigFile string
+}
+
+// Value is a setting's definition along with its current value, and
+// whether that value comes from config.yml or is the default.
+type Value struct {
+	Definition
+	Value string
+	IsSet bool
+}
+
+// ErrUnknownSetting is returned for keys that aren't in the schema.
+var ErrUnknownSetting = errors.New("Unknown setting")
+
+func NewSettingsService(config SettingsServiceConfig) SettingsService {
+	return SettingsService{
+		ConfigFile: config.ConfigFile,
+	}
+}
+
+func (s SettingsService) Get(key string) (Value, error) {
+	var err error
+	var values []Value
+
+	if values, err = s.List(); err != nil {
+		return Value{}, err
+	}
+
+	for _, v := range values {
+		if v.Key == key {
+			return v, nil
+		}
+	}
+
+	return Value{}, fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+}
+
+// List returns every setting in the schema with its current value. Values
+// are returned as written, even when they aren't valid.
+func (s SettingsService) List() ([]Value, error) {
+	var err error
+	var values map[string]string
+
+	result := make([]Value, 0, len(Definitions))
+
+	if values, err = readFile(s.ConfigFile); err != nil {
+		return result, err
+	}
+
+	for _, d := range Definitions {
+		value, ok := values[d.Key]
+
+		if !ok {
+			value = d.Default
+		}
+
+		result = append(result, Value{
+			Definition: d,
+			Value:      value,
+			IsSet:      ok,
+		})
+	}
+
+	return result, nil
+}
+
+// Load reads and validates config.yml. A missing file gives the defaults.
+// Every problem in the file is reported, not just the first.
+func (s SettingsService) Load() (Settings, error) {
+	var err error
+	var values map[string]string
+
+	if values, err = readFile(s.ConfigFile); err != nil {
+		return Defaults(), err
+	}
+
+	if err = validate(values); err != nil {
+		return Defaults(), fmt.Errorf("Invalid settings in %s: %w", s.ConfigFile, err)
+	}
+
+	return fromValues(values)
+}
+
+// Set validates a value and saves it to config.yml.
+func (s SettingsService) Set(key, value string) error {
+	var err error
+	var values map[string]string
+
+	d, ok := Lookup(key)
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+
+	value = d.Normalize(value)
+
+	if err = d.Validate(value); err != nil {
+		return apperrors.Validation("%s %s", key, err.Error())
+	}
+
+	if values, err = readFile(s.ConfigFile); err != nil {
+		return err
+	}
+
+	values[key] = value
+	return writeFile(s.ConfigFile, values)
+}
+
+// Unset removes a setting from config.yml, so its default applies.
+func (s SettingsService) Unset(key string) error {
+	var err error
+	var values map[string]string
+
+	if _, ok := Lookup(key); !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+
+	if values, err = readFile(s.ConfigFile); err != nil {
+		return err
+	}
+
+	delete(values, key)
+	return writeFile(s.ConfigFile, values)
+}
+
+// ValidateFile checks a config file without loading it, such as one that
+// has just been edited.
+func (s SettingsService) ValidateFile(fileName string) error {
+	values, err := readFile(fileName)
+
+	if err != nil {
+		return err
+	}
+
+	return validate(values)
+}
+
+/*
+ * config.yml nests keys, so "rounding.mode" is written as mode under
+ * rounding. Values are flattened to dotted keys when read.
+ */
+func readFile(fileName string) (map[string]string, error) {
+	var (
+		err  error
+		b    []byte
+		data map[interface{}]interface{}
+	)
+
+	result := make(map[string]string)
+
+	if b, err = ioutil.ReadFile(fileName); err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+
+		return result, fmt.Errorf("Error reading %s: %w", fileName, err)
+	}
+
+	if err = yaml.Unmarshal(b, &data); err != nil {
+		return result, fmt.Errorf("Error reading %s: %w", fileName, err)
+	}
+
+	flatten("", data, result)
+	return result, nil
+}
+
+func flatten(prefix string, data map[interface{}]interface{}, result map[string]string) {
+	for key, value := range data {
+		name := fmt.Sprint(key)
+
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		if nested, ok := value.(map[interface{}]interface{}); ok {
+			flatten(name, nested, result)
+			continue
+		}
+
+		if value == nil {
+			result[name] = ""
+			continue
+		}
+
+		result[name] = fmt.Sprint(value)
+	}
+}
+
+func validate(values map[string]string) error {
+	problems := make([]string, 0, 5)
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		d, ok := Lookup(key)
+
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is not a known setting", key))
+			continue
+		}
+
+		if err := d.Validate(d.Normalize(values[key])); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %s", key, err.Error()))
+		}
+	}
+
+	if len(problems) > 0 {
+		return apperrors.Validation(strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func writeFile(fileName string, values map[string]string) error {
+	var err error
+	var b []byte
+
+	data := make(map[string]interface{})
+
+	for key, value := range values {
+		d, _ := Lookup(key)
+		parts := strings.Split(key, ".")
+		parent := data
+
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := parent[part].(map[string]interface{})
+
+			if !ok {
+				child = make(map[string]interface{})
+				parent[part] = child
+			}
+
+			parent = child
+		}
+
+		parent[parts[len(parts)-1]] = typedValue(d, value)
+	}
+
+	if b, err = yaml.Marshal(data); err != nil {
+		return fmt.Errorf("Error writing %s: %w", fileName, err)
+	}
+
+	return storage.WriteFile(afero.NewOsFs(), fileName, b, 0644)
+}
+
+func typedValue(d Definition, value string) interface{} {
+	switch d.Kind {
+	case KindBool:
+		return value == "true"
+
+	case KindInt:
+		if number, err := strconv.Atoi(value); err == nil {
+			return number
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,50 @@
+package settings
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadNormalizes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mytime-settings")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "config.yml")
+	config := "time:\n  clock: 24H\nrounding:\n  increment: 15\n  mode: UP\ncurrency: eur\ninteractive:\n  interrupt: Save\ncolor: FALSE\n"
+
+	if err = ioutil.WriteFile(configFile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewSettingsService(SettingsServiceConfig{ConfigFile: configFile}).Load()
+
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+
+	when := time.Date(2020, time.January, 7, 14, 5, 0, 0, time.UTC)
+
+	if formatted := got.FormatTime(when); formatted != "14:05" {
+		t.Errorf("FormatTime() = %s, want 14:05", formatted)
+	}
+
+	if rounded := got.Round(61 * time.Minute); rounded != 75*time.Minute {
+		t.Errorf("Round(61m) = %v, want 1h15m", rounded)
+	}
+
+	if money := got.FormatMoney(12.5); money != "€12.50" {
+		t.Errorf("FormatMoney(12.5) = %s, want €12.50", money)
+	}
+
+	if got.Interrupt != "save" || got.Color {
+		t.Errorf("interrupt, color = %s, %v, want save, false", got.Interrupt, got.Color)
+	}
+}
@@ -0,0 +1,147 @@
+package settings
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDefinitionValidate(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{key: "backup.keep", value: "10"},
+		{key: "backup.keep", value: "0", wantErr: true},
+		{key: "backup.keep", value: "1001", wantErr: true},
+		{key: "backup.keep", value: "ten", wantErr: true},
+		{key: "color", value: "false"},
+		{key: "color", value: "maybe", wantErr: true},
+		{key: "currency", value: "EUR"},
+		{key: "currency", value: "EURO", wantErr: true},
+		{key: "date.format", value: "2006-01-02"},
+		{key: "date.format", value: "hello", wantErr: true},
+		{key: "date.format", value: "", wantErr: true},
+		{key: "lock.wait", value: "1m"},
+		{key: "lock.wait", value: "-5s", wantErr: true},
+		{key: "lock.wait", value: "soon", wantErr: true},
+		{key: "rounding.mode", value: "up"},
+		{key: "rounding.mode", value: "sideways", wantErr: true},
+		{key: "week.start", value: "sunday"},
+		{key: "week.start", value: "someday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			d, ok := Lookup(tt.key)
+
+			if !ok {
+				t.Fatalf("no definition for %s", tt.key)
+			}
+
+			err := d.Validate(tt.value)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q) = %v, want error %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateNormalizes(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]string
+		wantErr bool
+	}{
+		{name: "empty", values: map[string]string{}},
+		{name: "lower case currency", values: map[string]string{"currency": "eur"}},
+		{name: "upper case choice", values: map[string]string{"time.clock": "24H"}},
+		{name: "unknown key", values: map[string]string{"colour": "true"}, wantErr: true},
+		{name: "one bad value", values: map[string]string{"precision": "2", "rounding.increment": "-1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(tt.values)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		name      string
+		increment int
+		mode      string
+		in        time.Duration
+		want      time.Duration
+	}{
+		{name: "off", increment: 0, mode: "nearest", in: 7 * time.Minute, want: 7 * time.Minute},
+		{name: "nearest down", increment: 15, mode: "nearest", in: 7 * time.Minute, want: 0},
+		{name: "nearest up", increment: 15, mode: "nearest", in: 8 * time.Minute, want: 15 * time.Minute},
+		{name: "nearest half way", increment: 15, mode: "nearest", in: 7*time.Minute + 30*time.Second, want: 15 * time.Minute},
+		{name: "up", increment: 15, mode: "up", in: 61 * time.Minute, want: 75 * time.Minute},
+		{name: "up exact", increment: 15, mode: "up", in: 60 * time.Minute, want: 60 * time.Minute},
+		{name: "down", increment: 6, mode: "down", in: 71 * time.Minute, want: 66 * time.Minute},
+		{name: "down exact", increment: 6, mode: "down", in: 72 * time.Minute, want: 72 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Settings{RoundingIncrement: tt.increment, RoundingMode: tt.mode}
+
+			if got := s.Round(tt.in); got != tt.want {
+				t.Errorf("Round(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundToPrecision(t *testing.T) {
+	tests := []struct {
+		precision int
+		in        float64
+		want      float64
+	}{
+		{precision: 2, in: 1.116666, want: 1.12},
+		{precision: 4, in: 1.116666, want: 1.1167},
+		{precision: 0, in: 110.5, want: 111},
+		{precision: 2, in: 0.1 + 0.2, want: 0.3},
+	}
+
+	for _, tt := range tests {
+		s := Settings{Precision: tt.precision}
+
+		if got := s.RoundToPrecision(tt.in); got != tt.want {
+			t.Errorf("RoundToPrecision(%v) with precision %d = %v, want %v", tt.in, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestStartOfWeek(t *testing.T) {
+	wednesday := time.Date(2020, time.January, 15, 13, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		weekStart time.Weekday
+		want      time.Time
+	}{
+		{weekStart: time.Monday, want: time.Date(2020, time.January, 13, 0, 0, 0, 0, time.UTC)},
+		{weekStart: time.Sunday, want: time.Date(2020, time.January, 12, 0, 0, 0, 0, time.UTC)},
+		{weekStart: time.Wednesday, want: time.Date(2020, time.January, 15, 0, 0, 0, 0, time.UTC)},
+		{weekStart: time.Thursday, want: time.Date(2020, time.January, 9, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.weekStart.String(), func(t *testing.T) {
+			s := Settings{WeekStart: tt.weekStart}
+
+			if got := s.StartOfWeek(wednesday); !got.Equal(tt.want) {
+				t.Errorf("StartOfWeek() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+// AtomicFs wraps a file system so that files opened for writing are
+// buffered in memory, then swapped into place in one step when they are
+// synced. A crash part way through a write leaves the previous contents
+// intact rather than a truncated file.
+type AtomicFs struct {
+	afero.Fs
+
+	failure *commitFailure
+}
+
+type commitFailure struct {
+	err   error
+	mutex sync.Mutex
+}
+
+func NewAtomicFs(fs afero.Fs) AtomicFs {
+	return AtomicFs{
+		Fs:      fs,
+		failure: &commitFailure{},
+	}
+}
+
+/*
+ * Committed returns err, the result of a simdb write, or when that is nil
+ * the first error swapping a file into place since the last call. simdb
+ * ignores the errors from syncing and closing the files it writes, so
+ * repositories pass their writes through here.
+ */
+func (a AtomicFs) Committed(err error) error {
+	a.failure.mutex.Lock()
+	defer a.failure.mutex.Unlock()
+
+	if err == nil {
+		err = a.failure.err
+	}
+
+	a.failure.err = nil
+	return err
+}
+
+func (a AtomicFs) Create(name string) (afero.File, error) {
+	return a.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+func (a AtomicFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return a.Fs.OpenFile(name, flag, perm)
+	}
+
+	/*
+	 * The real file is only ever opened for reading. Truncating and
+	 * appending are applied to the buffer instead.
+	 */
+	f, err := a.Fs.OpenFile(name, (flag&^(os.O_WRONLY|os.O_TRUNC|os.O_APPEND))|os.O_RDWR, perm)
+
+	if err != nil {
+		return f, err
+	}
+
+	result := &atomicFile{
+		File:    f,
+		failure: a.failure,
+		fs:      a.Fs,
+	}
+
+	if flag&os.O_TRUNC != 0 {
+		result.data = []byte{}
+		result.loaded = true
+		result.dirty = true
+	}
+
+	if flag&os.O_APPEND != 0 {
+		if err = result.load(); err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		result.offset = int64(len(result.data))
+	}
+
+	return result, nil
+}
+
+// WriteFile replaces a file's contents by writing a temporary file next
+// to it, syncing it to disk and renaming it over the original. Existing
+// files keep their permissions; perm is used for new ones.
+func WriteFile(fs afero.Fs, name string, data []byte, perm os.FileMode) error {
+	var (
+		err  error
+		info os.FileInfo
+		temp afero.File
+	)
+
+	if info, err = fs.Stat(name); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(name)
+
+	if temp, err = afero.TempFile(fs, dir, "."+filepath.Base(name)+".tmp*"); err != nil {
+		return fmt.Errorf("Error writing %s: %w", name, err)
+	}
+
+	tempName := temp.Name()
+
+	if _, err = temp.Write(data); err == nil {
+		err = temp.Sync()
+	}
+
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = fs.Chmod(tempName, perm)
+	}
+
+	if err == nil {
+		err = fs.Rename(tempName, name)
+	}
+
+	if err != nil {
+		_ = fs.Remove(tempName)
+		return fmt.Errorf("Error writing %s: %w", name, err)
+	}
+
+	/*
+	 * Sync the directory so the rename itself survives a crash. Not every
+	 * platform allows this, so failures are ignored.
+	 */
+	if d, dirErr := fs.Open(dir); dirErr == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+type atomicFile struct {
+	afero.File
+
+	closed  bool
+	data    []byte
+	dirty   bool
+	failure *commitFailure
+	fs      afero.Fs
+	loaded  bool
+	offset  int64
+}
+
+// Close closes the original file. Anything written and not yet synced is
+// thrown away.
+func (f *atomicFile) Close() error {
+	f.dirty = false
+	return f.closeOriginal()
+}
+
+func (f *atomicFile) Read(p []byte) (int, error) {
+	if !f.loaded {
+		return f.File.Read(p)
+	}
+
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+
+	return n, err
+}
+
+func (f *atomicFile) ReadAt(p []byte, off int64) (int, error) {
+	if !f.loaded {
+		return f.File.ReadAt(p, off)
+	}
+
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, f.data[off:])
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (f *atomicFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.data))
+	}
+
+	if offset < 0 {
+		return f.offset, fmt.Errorf("Error seeking %s: negative position", f.File.Name())
+	}
+
+	f.offset = offset
+	return offset, nil
+}
+
+/*
+ * Sync swaps the buffered data into place. The first failure is also kept
+ * for AtomicFs.Committed, as simdb doesn't check what Sync returns.
+ */
+func (f *atomicFile) Sync() error {
+	var err error
+
+	if !f.dirty {
+		return nil
+	}
+
+	name := f.File.Name()
+
+	/*
+	 * Close the original before replacing it, as Windows won't rename
+	 * over a file that is still open.
+	 */
+	if err = f.closeOriginal(); err == nil {
+		err = WriteFile(f.fs, name, f.data, 0644)
+	}
+
+	if err != nil {
+		f.failure.mutex.Lock()
+
+		if f.failure.err == nil {
+			f.failure.err = err
+		}
+
+		f.failure.mutex.Unlock()
+		return err
+	}
+
+	f.dirty = false
+	return nil
+}
+
+func (f *atomicFile) Truncate(size int64) error {
+	if err := f.load(); err != nil {
+		return err
+	}
+
+	f.resize(size)
+	f.dirty = true
+
+	return nil
+}
+
+func (f *atomicFile) Write(p []byte) (int, error) {
+	n, err := f.WriteAt(p, f.offset)
+	f.offset += int64(n)
+
+	return n, err
+}
+
+func (f *atomicFile) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+
+	if end := off + int64(len(p)); end > int64(len(f.data)) {
+		f.resize(end)
+	}
+
+	f.dirty = true
+	return copy(f.data[off:], p), nil
+}
+
+func (f *atomicFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *atomicFile) load() error {
+	var err error
+
+	if f.loaded {
+		return nil
+	}
+
+	if f.offset, err = f.File.Seek(0, io.SeekCurrent); err != nil {
+		return err
+	}
+
+	if _, err = f.File.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if f.data, err = ioutil.ReadAll(f.File); err != nil {
+		return err
+	}
+
+	f.loaded = true
+	return nil
+}
+
+func (f *atomicFile) closeOriginal() error {
+	if f.closed {
+		return nil
+	}
+
+	f.closed = true
+	return f.File.Close()
+}
+
+func (f *atomicFile) resize(size int64) {
+	if size <= int64(len(f.data)) {
+		f.data = f.data[:size]
+		return
+	}
+
+	f.data = append(f.data, make([]byte, size-int64(len(f.data)))...)
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestAtomicFsWrites(t *testing.T) {
+	tests := []struct {
+		name  string
+		flag  int
+		write func(f afero.File) error
+		want  string
+	}{
+		{
+			name: "truncate",
+			flag: os.O_WRONLY | os.O_TRUNC,
+			write: func(f afero.File) error {
+				_, err := f.WriteString("new")
+				return err
+			},
+			want: "new",
+		},
+		{
+			name: "append",
+			flag: os.O_WRONLY | os.O_APPEND,
+			write: func(f afero.File) error {
+				_, err := f.WriteString(" and more")
+				return err
+			},
+			want: "original and more",
+		},
+		{
+			name: "overwrite in place",
+			flag: os.O_RDWR,
+			write: func(f afero.File) error {
+				_, err := f.WriteAt([]byte("OR"), 0)
+				return err
+			},
+			want: "ORiginal",
+		},
+		{
+			name: "seek and write past the end",
+			flag: os.O_RDWR,
+			write: func(f afero.File) error {
+				if _, err := f.Seek(2, io.SeekEnd); err != nil {
+					return err
+				}
+
+				_, err := f.WriteString("!")
+				return err
+			},
+			want: "original\x00\x00!",
+		},
+		{
+			name: "truncate to a size",
+			flag: os.O_RDWR,
+			write: func(f afero.File) error {
+				return f.Truncate(4)
+			},
+			want: "orig",
+		},
+		{
+			name: "nothing written",
+			flag: os.O_RDWR,
+			write: func(f afero.File) error {
+				return nil
+			},
+			want: "original",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := afero.NewMemMapFs()
+			fs := NewAtomicFs(base)
+
+			if err := afero.WriteFile(base, "/data/Client", []byte("original"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := fs.OpenFile("/data/Client", tt.flag, 0644)
+
+			if err != nil {
+				t.Fatalf("OpenFile: %v", err)
+			}
+
+			if err = tt.write(f); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			if got, _ := afero.ReadFile(base, "/data/Client"); string(got) != "original" {
+				t.Errorf("before Sync the file holds %q, want it untouched", got)
+			}
+
+			if err = f.Sync(); err != nil {
+				t.Fatalf("Sync: %v", err)
+			}
+
+			if err = f.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			if got, _ := afero.ReadFile(base, "/data/Client"); string(got) != tt.want {
+				t.Errorf("after Sync the file holds %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAtomicFsCloseWithoutSync(t *testing.T) {
+	base := afero.NewMemMapFs()
+	fs := NewAtomicFs(base)
+
+	if err := afero.WriteFile(base, "/data/Client", []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fs.Create("/data/Client")
+
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err = f.WriteString("new"); err != nil {
+		t.Fatalf("WriteString: %v", err)
+	}
+
+	if err = f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got, _ := afero.ReadFile(base, "/data/Client"); string(got) != "original" {
+		t.Errorf("the file holds %q, want it untouched", got)
+	}
+}
+
+// renameFailsFs fails every rename, as when the directory can't be
+// written to.
+type renameFailsFs struct {
+	afero.Fs
+}
+
+func (fs renameFailsFs) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrPermission}
+}
+
+func TestAtomicFsCommitted(t *testing.T) {
+	fs := NewAtomicFs(renameFailsFs{afero.NewMemMapFs()})
+
+	f, err := fs.Create("/data/Client")
+
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	f.WriteString("new")
+
+	if err = f.Sync(); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Sync() = %v, want the rename's error", err)
+	}
+
+	f.Close()
+
+	if err = fs.Committed(nil); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Committed(nil) = %v, want the rename's error", err)
+	}
+
+	if err = fs.Committed(nil); err != nil {
+		t.Errorf("Committed(nil) a second time = %v, want nil", err)
+	}
+}
+
+func TestAtomicFsReadsBufferedData(t *testing.T) {
+	fs := NewAtomicFs(afero.NewMemMapFs())
+
+	f, err := fs.Create("/data/Project")
+
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err = f.WriteString("[1,2,3]"); err != nil {
+		t.Fatalf("WriteString: %v", err)
+	}
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+
+	got, err := ioutil.ReadAll(f)
+
+	if err != nil || string(got) != "[1,2,3]" {
+		t.Errorf("ReadAll = %q, %v, want %q", got, err, "[1,2,3]")
+	}
+
+	f.Close()
+}
+
+func TestWriteFileKeepsPermissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mytime-storage")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "Client")
+
+	if err = ioutil.WriteFile(name, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = WriteFile(afero.NewOsFs(), name, []byte("new"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	info, _ := os.Stat(name)
+
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("permissions = %v, want %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, ".*.tmp*")); len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+}
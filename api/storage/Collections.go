@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Collections lists the collection files in a data directory. These are
+// the files simdb writes, named after their entity with no extension.
+func Collections(dataDirectory string) ([]string, error) {
+	var err error
+	var infos []os.FileInfo
+
+	result := make([]string, 0, 10)
+
+	if infos, err = ioutil.ReadDir(dataDirectory); err != nil {
+		return result, fmt.Errorf("Error reading data directory: %w", err)
+	}
+
+	for _, info := range infos {
+		if info.Mode().IsRegular() && filepath.Ext(info.Name()) == "" && !strings.HasPrefix(info.Name(), ".") {
+			result = append(result, info.Name())
+		}
+	}
+
+	return result, nil
+}
+
+// CheckCollections makes sure every collection file can be read. simdb
+// treats a file it can't parse as empty, which would quietly hide
+// damaged data.
+func CheckCollections(dataDirectory string) error {
+	var err error
+	var names []string
+	var b []byte
+
+	if names, err = Collections(dataDirectory); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if b, err = ioutil.ReadFile(filepath.Join(dataDirectory, name)); err != nil {
+			return fmt.Errorf("Error reading %s: %w", name, err)
+		}
+
+		if len(b) == 0 {
+			continue
+		}
+
+		records := make([]json.RawMessage, 0, 100)
+
+		if err = json.Unmarshal(b, &records); err != nil {
+			return fmt.Errorf("%w: %s (%s)", ErrCorrupt, name, err.Error())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/adampresley/simdb"
+)
+
+var (
+	// ErrNotFound is returned by repositories when no record matches.
+	ErrNotFound = errors.New("No records found")
+
+	// ErrCorrupt is returned when a data file exists but can't be read.
+	ErrCorrupt = errors.New("Data file is damaged")
+)
+
+// FromSimdbError translates simdb's errors into the storage errors that
+// services and commands check for.
+func FromSimdbError(err error) error {
+	if errors.Is(err, simdb.ErrZeroRecords) {
+		return ErrNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// JournalFileName is the write-ahead journal kept in the data directory
+// while an operation that changes several records is running.
+const JournalFileName string = "journal.json"
+
+// ErrOperationInProgress is returned when an operation is started while
+// another one has not yet committed or rolled back.
+var ErrOperationInProgress = errors.New("Another operation is already in progress")
+
+// Journal makes an operation that changes several records all or
+// nothing. Begin records the state of the data before the operation, and
+// Commit discards it. If the process dies in between, the recorded state
+// is put back the next time My Time starts.
+type Journal interface {
+	Begin(operation string) error
+	Commit() error
+	Rollback() error
+}
+
+// JournalRecovery describes an interrupted operation that was rolled back.
+type JournalRecovery struct {
+	Operation string
+	StartedAt time.Time
+}
+
+// FileJournal is a Journal that snapshots the collection files in the data
+// directory. It holds the exclusive lock from Begin until Commit or
+// Rollback.
+type FileJournal struct {
+	DataDirectory string
+	Locker        Locker
+}
+
+// NopJournal is a Journal that does nothing, for stores such as the
+// in-memory repositories that can't be interrupted part way.
+type NopJournal struct{}
+
+type journalEntry struct {
+	Operation string            `json:"operation"`
+	StartedAt time.Time         `json:"startedAt"`
+	Files     map[string][]byte `json:"files"`
+}
+
+func NewFileJournal(dataDirectory string, locker Locker) FileJournal {
+	return FileJournal{
+		DataDirectory: dataDirectory,
+		Locker:        locker,
+	}
+}
+
+func (j FileJournal) Begin(operation string) error {
+	var (
+		err   error
+		b     []byte
+		names []string
+	)
+
+	if err = j.Locker.Lock(); err != nil {
+		return err
+	}
+
+	if _, err = os.Stat(j.path()); err == nil {
+		j.Locker.Unlock()
+		return ErrOperationInProgress
+	}
+
+	entry := journalEntry{
+		Operation: operation,
+		StartedAt: time.Now(),
+		Files:     make(map[string][]byte),
+	}
+
+	if names, err = Collections(j.DataDirectory); err != nil {
+		j.Locker.Unlock()
+		return err
+	}
+
+	for _, name := range names {
+		if entry.Files[name], err = ioutil.ReadFile(filepath.Join(j.DataDirectory, name)); err != nil {
+			j.Locker.Unlock()
+			return fmt.Errorf("Error journaling %s: %w", name, err)
+		}
+	}
+
+	if b, err = json.Marshal(entry); err != nil {
+		j.Locker.Unlock()
+		return fmt.Errorf("Error writing journal: %w", err)
+	}
+
+	if err = WriteFile(afero.NewOsFs(), j.path(), b, 0644); err != nil {
+		j.Locker.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+func (j FileJournal) Commit() error {
+	defer j.Locker.Unlock()
+
+	if err := os.Remove(j.path()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Error removing journal: %w", err)
+	}
+
+	return nil
+}
+
+func (j FileJournal) Rollback() error {
+	defer j.Locker.Unlock()
+
+	_, err := j.restore()
+	return err
+}
+
+// Recover puts back the data from before an operation that never
+// committed, and removes temporary files left by interrupted writes. The
+// returned recovery is nil when there was nothing to roll back.
+func (j FileJournal) Recover() (*JournalRecovery, error) {
+	var err error
+	var leftovers []string
+
+	if err = j.Locker.Lock(); err != nil {
+		return nil, err
+	}
+
+	defer j.Locker.Unlock()
+
+	if leftovers, err = filepath.Glob(filepath.Join(j.DataDirectory, ".*.tmp*")); err == nil {
+		for _, name := range leftovers {
+			_ = os.Remove(name)
+		}
+	}
+
+	if _, err = os.Stat(j.path()); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	return j.restore()
+}
+
+/*
+ * restore writes every journaled file back, and removes collections that
+ * were created after the journal was written. The journal itself goes
+ * last, so a crash while restoring just restores again next time.
+ */
+func (j FileJournal) restore() (*JournalRecovery, error) {
+	var (
+		err     error
+		b       []byte
+		entry   journalEntry
+		current []string
+	)
+
+	if b, err = ioutil.ReadFile(j.path()); err != nil {
+		return nil, fmt.Errorf("Error reading journal: %w", err)
+	}
+
+	if err = json.Unmarshal(b, &entry); err != nil {
+		return nil, fmt.Errorf("Error reading journal: %w", err)
+	}
+
+	for name, data := range entry.Files {
+		if err = WriteFile(afero.NewOsFs(), filepath.Join(j.DataDirectory, name), data, 0644); err != nil {
+			return nil, fmt.Errorf("Error rolling back %s: %w", name, err)
+		}
+	}
+
+	if current, err = Collections(j.DataDirectory); err != nil {
+		return nil, err
+	}
+
+	for _, name := range current {
+		if _, ok := entry.Files[name]; !ok {
+			if err = os.Remove(filepath.Join(j.DataDirectory, name)); err != nil {
+				return nil, fmt.Errorf("Error rolling back %s: %w", name, err)
+			}
+		}
+	}
+
+	if err = os.Remove(j.path()); err != nil {
+		return nil, fmt.Errorf("Error removing journal: %w", err)
+	}
+
+	return &JournalRecovery{
+		Operation: entry.Operation,
+		StartedAt: entry.StartedAt,
+	}, nil
+}
+
+func (j FileJournal) path() string {
+	return filepath.Join(j.DataDirectory, JournalFileName)
+}
+
+func (NopJournal) Begin(operation string) error {
+	return nil
+}
+
+func (NopJournal) Commit() error {
+	return nil
+}
+
+func (NopJournal) Rollback() error {
+	return nil
+}
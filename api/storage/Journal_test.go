@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func journalDirectory(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "mytime-journal")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for name, data := range map[string]string{"Client": `[{"clientID":1}]`, "Project": `[]`} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(name)
+
+	if os.IsNotExist(err) {
+		return "<missing>"
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestFileJournal(t *testing.T) {
+	tests := []struct {
+		name        string
+		finish      func(j FileJournal) error
+		wantClient  string
+		wantSession string
+	}{
+		{
+			name:        "commit keeps the changes",
+			finish:      func(j FileJournal) error { return j.Commit() },
+			wantClient:  `[{"clientID":2}]`,
+			wantSession: `[{"sessionID":1}]`,
+		},
+		{
+			name:        "rollback puts the data back",
+			finish:      func(j FileJournal) error { return j.Rollback() },
+			wantClient:  `[{"clientID":1}]`,
+			wantSession: "<missing>",
+		},
+		{
+			name: "recover after a crash puts the data back",
+			finish: func(j FileJournal) error {
+				j.Locker.Unlock()
+
+				recovery, err := NewFileJournal(j.DataDirectory, NewFileLocker(j.DataDirectory, time.Second)).Recover()
+
+				if err == nil && (recovery == nil || recovery.Operation != "test") {
+					err = errors.New("no recovery reported for the test operation")
+				}
+
+				return err
+			},
+			wantClient:  `[{"clientID":1}]`,
+			wantSession: "<missing>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := journalDirectory(t)
+			j := NewFileJournal(dir, NewFileLocker(dir, time.Second))
+
+			if err := j.Begin("test"); err != nil {
+				t.Fatalf("Begin: %v", err)
+			}
+
+			if err := ioutil.WriteFile(filepath.Join(dir, "Client"), []byte(`[{"clientID":2}]`), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := ioutil.WriteFile(filepath.Join(dir, "Session"), []byte(`[{"sessionID":1}]`), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.finish(j); err != nil {
+				t.Fatalf("finish: %v", err)
+			}
+
+			if got := readFile(t, filepath.Join(dir, "Client")); got != tt.wantClient {
+				t.Errorf("Client = %s, want %s", got, tt.wantClient)
+			}
+
+			if got := readFile(t, filepath.Join(dir, "Session")); got != tt.wantSession {
+				t.Errorf("Session = %s, want %s", got, tt.wantSession)
+			}
+
+			if got := readFile(t, filepath.Join(dir, "Project")); got != "[]" {
+				t.Errorf("Project = %s, want []", got)
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, JournalFileName)); !os.IsNotExist(err) {
+				t.Errorf("the journal is still there")
+			}
+		})
+	}
+}
+
+func TestFileJournalBeginTwice(t *testing.T) {
+	dir := journalDirectory(t)
+	j := NewFileJournal(dir, NewFileLocker(dir, time.Second))
+
+	if err := j.Begin("first"); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+
+	if err := j.Begin("second"); !errors.Is(err, ErrOperationInProgress) {
+		t.Errorf("second Begin = %v, want %v", err, ErrOperationInProgress)
+	}
+
+	if err := j.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+}
+
+func TestFileJournalRecoverWithNothingToDo(t *testing.T) {
+	dir := journalDirectory(t)
+	leftover := filepath.Join(dir, ".Client.tmp123")
+
+	if err := ioutil.WriteFile(leftover, []byte("half"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	recovery, err := NewFileJournal(dir, NewFileLocker(dir, time.Second)).Recover()
+
+	if err != nil || recovery != nil {
+		t.Errorf("Recover = %v, %v, want nil, nil", recovery, err)
+	}
+
+	if _, err = os.Stat(leftover); !os.IsNotExist(err) {
+		t.Errorf("temporary file %s was not removed", leftover)
+	}
+}
@@ -0,0 +1,39 @@
+//go:build windows
+// +build windows
+
+package storage
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+var errWouldBlock = errors.New("lock is held")
+
+func tryLockFile(file *os.File, exclusive bool) error {
+	var flags uint32 = windows.LOCKFILE_FAIL_IMMEDIATELY
+
+	if exclusive {
+		flags |= windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+
+	/*
+	 * Windows won't convert a lock in place, so release any lock this
+	 * handle already holds before taking the new one.
+	 */
+	_ = unlockFile(file)
+
+	err := windows.LockFileEx(windows.Handle(file.Fd()), flags, 0, 1, 0, &windows.Overlapped{})
+
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return errWouldBlock
+	}
+
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
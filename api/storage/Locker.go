@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LockFileName is the file in the data directory that mt processes take
+// advisory locks on.
+const LockFileName string = ".lock"
+
+// ErrLocked is returned when another process holds the data directory lock
+// for longer than the configured wait.
+var ErrLocked = errors.New("The data directory is in use by another mt process")
+
+// Locker coordinates access to the data directory. Readers share the lock,
+// writers hold it exclusively.
+type Locker interface {
+	Lock() error
+	RLock() error
+	RUnlock()
+	Unlock()
+}
+
+type lockMode int
+
+const (
+	unlocked lockMode = iota
+	sharedLock
+	exclusiveLock
+)
+
+// FileLocker is a Locker backed by an OS advisory lock on a file in the
+// data directory. It is reentrant within a process, so a command can hold
+// the exclusive lock across several repository calls.
+type FileLocker struct {
+	mutex *sync.Mutex
+	path  string
+	state *fileLockState
+	wait  time.Duration
+}
+
+type fileLockState struct {
+	exclusive int
+	file      *os.File
+	mode      lockMode
+	shared    int
+}
+
+// NewFileLocker creates a locker on the lock file in dataDirectory. When the
+// lock is held elsewhere it is retried for up to wait before giving up
+// with ErrLocked.
+func NewFileLocker(dataDirectory string, wait time.Duration) FileLocker {
+	return FileLocker{
+		mutex: &sync.Mutex{},
+		path:  filepath.Join(dataDirectory, LockFileName),
+		state: &fileLockState{},
+		wait:  wait,
+	}
+}
+
+// Lock takes the exclusive lock, upgrading a shared lock already held by
+// this process.
+func (l FileLocker) Lock() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.state.exclusive++
+
+	if err := l.apply(); err != nil {
+		l.state.exclusive--
+		return err
+	}
+
+	return nil
+}
+
+// RLock takes a shared lock. It is satisfied immediately when this process
+// already holds the lock in either mode.
+func (l FileLocker) RLock() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.state.shared++
+
+	if err := l.apply(); err != nil {
+		l.state.shared--
+		return err
+	}
+
+	return nil
+}
+
+func (l FileLocker) RUnlock() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.state.shared > 0 {
+		l.state.shared--
+	}
+
+	_ = l.apply()
+}
+
+func (l FileLocker) Unlock() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.state.exclusive > 0 {
+		l.state.exclusive--
+	}
+
+	_ = l.apply()
+}
+
+/*
+ * apply brings the OS lock in line with the counts held in this process.
+ * An exclusive lock is kept while any writer remains, and is downgraded to
+ * a shared one once only readers are left.
+ */
+func (l FileLocker) apply() error {
+	var err error
+
+	want := unlocked
+
+	if l.state.exclusive > 0 {
+		want = exclusiveLock
+	} else if l.state.shared > 0 {
+		want = sharedLock
+	}
+
+	if want == l.state.mode {
+		return nil
+	}
+
+	if want == unlocked {
+		err = unlockFile(l.state.file)
+		l.state.file.Close()
+
+		l.state.file = nil
+		l.state.mode = unlocked
+		return err
+	}
+
+	if l.state.file == nil {
+		if l.state.file, err = os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644); err != nil {
+			return fmt.Errorf("Error opening lock file: %w", err)
+		}
+	}
+
+	deadline := time.Now().Add(l.wait)
+
+	for {
+		if err = tryLockFile(l.state.file, want == exclusiveLock); err == nil {
+			l.state.mode = want
+			return nil
+		}
+
+		if !errors.Is(err, errWouldBlock) {
+			return fmt.Errorf("Error locking data directory: %w", err)
+		}
+
+		if time.Now().After(deadline) {
+			if l.state.mode == unlocked {
+				l.state.file.Close()
+				l.state.file = nil
+			}
+
+			return ErrLocked
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,17 @@
+package storage
+
+/*
+ * SimdbKey is the entity handed to simdb's Delete, which finds the record
+ * to remove by comparing its ID with !=. Numbers in the collection are
+ * decoded as float64, so an int ID would never match. simdb also names
+ * the collection after the entity's type, so embed SimdbKey in a type
+ * named like the collection's entity.
+ */
+type SimdbKey struct {
+	Field string
+	Value float64
+}
+
+func (k SimdbKey) ID() (string, interface{}) {
+	return k.Field, k.Value
+}
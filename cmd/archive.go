@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/projects"
+	"github.com/spf13/cobra"
+)
+
+// codeArgs requires the code of the record a command works on.
+func codeArgs(entity, action string) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return fmt.Errorf("Please provide the code for the %s you wish to %s", entity, action)
+		}
+
+		return nil
+	}
+}
+
+// setArchived archives or unarchives one project or category with set.
+func setArchived(entity, code, action string, set func(code string) error) error {
+	unlock, err := lockData()
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	if err = set(code); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s %s %sd\n", entity, au.Green(code), action)
+	return nil
+}
+
+// clientAndProjects looks up a client and its projects that aren't yet
+// archived, or unarchived.
+func clientAndProjects(code string, archived bool) (clients.Client, projects.ProjectCollection, error) {
+	var (
+		err            error
+		client         clients.Client
+		clientProjects projects.ProjectCollection
+	)
+
+	if client, err = clientService.GetClientByCode(code); err != nil {
+		return client, clientProjects, err
+	}
+
+	clientProjects, err = projectService.ListProjects(projects.ProjectSearch{Archived: !archived, ClientID: client.ClientID})
+	return client, clientProjects, err
+}
+
+/*
+ * setClientArchived archives or unarchives a client and, when asked, its
+ * projects. It all happens in one journaled operation, so a client is
+ * never left half done.
+ */
+func setClientArchived(code string, archived bool, withProjects bool) error {
+	var (
+		err            error
+		client         clients.Client
+		clientProjects projects.ProjectCollection
+	)
+
+	action := "archive"
+	setProject := projectService.ArchiveProject
+
+	if !archived {
+		action = "unarchive"
+		setProject = projectService.UnarchiveProject
+	}
+
+	/*
+	 * Only archiving asks. Unarchiving a client's projects has to be
+	 * asked for with --projects. The question comes before the data is
+	 * locked, so other mt processes don't wait on the answer.
+	 */
+	if archived && !withProjects {
+		if client, clientProjects, err = clientAndProjects(code, archived); err != nil {
+			return err
+		}
+
+		if len(clientProjects) > 0 {
+			withProjects = confirm(fmt.Sprintf("Also archive the %d projects of %s?", len(clientProjects), client.Name))
+		}
+	}
+
+	unlock, err := lockData()
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	if client, clientProjects, err = clientAndProjects(code, archived); err != nil {
+		return err
+	}
+
+	if err = journal.Begin(action + " client"); err != nil {
+		return err
+	}
+
+	if archived {
+		err = clientService.ArchiveClient(client.Code)
+	} else {
+		err = clientService.UnarchiveClient(client.Code)
+	}
+
+	if err != nil {
+		journal.Rollback()
+		return err
+	}
+
+	if withProjects {
+		for _, p := range clientProjects {
+			if err = setProject(p.Code); err != nil {
+				journal.Rollback()
+				return fmt.Errorf("Problem with project %s, so nothing was changed: %w", p.Code, err)
+			}
+		}
+	}
+
+	if err = journal.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Client %s %sd\n", au.Green(client.Code), action)
+
+	if withProjects {
+		for _, p := range clientProjects {
+			fmt.Printf("Project %s %sd\n", au.Green(p.Code), action)
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	var (
+		archiveProjects   bool
+		unarchiveProjects bool
+	)
+
+	archiveCmd := &cobra.Command{
+		Use:     "archive",
+		Aliases: []string{"ar"},
+		Short:   `Archives a client, project, or category`,
+		Long: `Archives a client, project, or category. Archived records are left out of lists, and can't be
+timed against, but their sessions are kept. Use unarchive to bring them back.`,
+	}
+
+	unarchiveCmd := &cobra.Command{
+		Use:     "unarchive",
+		Aliases: []string{"unar"},
+		Short:   `Brings back an archived client, project, or category`,
+	}
+
+	archiveClientCmd := &cobra.Command{
+		Use:     "client",
+		Aliases: []string{"c"},
+		Short:   `Archives a client, and optionally its projects`,
+		Example: `mt archive client "clientCode"
+mt archive client "clientCode" --projects`,
+		Args: codeArgs("client", "archive"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setClientArchived(args[0], true, archiveProjects)
+		},
+	}
+
+	unarchiveClientCmd := &cobra.Command{
+		Use:     "client",
+		Aliases: []string{"c"},
+		Short:   `Unarchives a client, and optionally its projects`,
+		Example: `mt unarchive client "clientCode"
+mt unarchive client "clientCode" --projects`,
+		Args: codeArgs("client", "unarchive"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setClientArchived(args[0], false, unarchiveProjects)
+		},
+	}
+
+	archiveProjectCmd := &cobra.Command{
+		Use:     "project",
+		Aliases: []string{"p", "proj"},
+		Short:   `Archives a project`,
+		Example: `mt archive project "projectCode"`,
+		Args:    codeArgs("project", "archive"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setArchived("Project", args[0], "archive", projectService.ArchiveProject)
+		},
+	}
+
+	unarchiveProjectCmd := &cobra.Command{
+		Use:     "project",
+		Aliases: []string{"p", "proj"},
+		Short:   `Unarchives a project`,
+		Example: `mt unarchive project "projectCode"`,
+		Args:    codeArgs("project", "unarchive"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setArchived("Project", args[0], "unarchive", projectService.UnarchiveProject)
+		},
+	}
+
+	archiveCategoryCmd := &cobra.Command{
+		Use:     "category",
+		Aliases: []string{"cat"},
+		Short:   `Archives a category`,
+		Example: `mt archive category "categoryCode"`,
+		Args:    codeArgs("category", "archive"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setArchived("Category", args[0], "archive", categoryService.ArchiveCategory)
+		},
+	}
+
+	unarchiveCategoryCmd := &cobra.Command{
+		Use:     "category",
+		Aliases: []string{"cat"},
+		Short:   `Unarchives a category`,
+		Example: `mt unarchive category "categoryCode"`,
+		Args:    codeArgs("category", "unarchive"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setArchived("Category", args[0], "unarchive", categoryService.UnarchiveCategory)
+		},
+	}
+
+	archiveClientCmd.Flags().BoolVarP(&archiveProjects, "projects", "", false, "Also archive the client's projects, without asking")
+	unarchiveClientCmd.Flags().BoolVarP(&unarchiveProjects, "projects", "", false, "Also unarchive the client's projects")
+
+	archiveCmd.AddCommand(archiveClientCmd, archiveProjectCmd, archiveCategoryCmd)
+	unarchiveCmd.AddCommand(unarchiveClientCmd, unarchiveProjectCmd, unarchiveCategoryCmd)
+	rootCmd.AddCommand(archiveCmd, unarchiveCmd)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/adampresley/mytime/api/backups"
+	"github.com/spf13/cobra"
+)
+
+// autoBackup takes a rotating backup of the data directory before a
+// command changes or removes existing records. If the backup can't be
+// taken the command stops, rather than risk losing data.
+func autoBackup(reason string) error {
+	if _, err := backupService.AutoBackup(reason); err != nil {
+		return fmt.Errorf("Unable to take a backup before continuing: %w", err)
+	}
+
+	return nil
+}
+
+func init() {
+	var (
+		outputFile string
+		yes        bool
+	)
+
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: `Backs up your data and configuration to a compressed archive`,
+		Long: fmt.Sprintf(`Backs up your data and configuration to a compressed archive. Automatic backups are also
+taken before commands that change existing records, and kept in the "%s" folder of your
+data directory.`, backups.BackupDirectory),
+		Example: `mt backup
+mt backup --out ~/Dropbox/mytime.tar.gz`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			var fileName string
+
+			if fileName, err = backupService.CreateBackup(outputFile); err != nil {
+				return fmt.Errorf("Problem creating backup: %w", err)
+			}
+
+			fmt.Printf("Backup written to %s\n", au.Green(fileName))
+			return nil
+		},
+	}
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: `Restores your data and configuration from a backup archive`,
+		Long: `Restores your data and configuration from a backup archive. The archive is checked before
+anything is replaced, and your current data is backed up automatically first.`,
+		Example: `mt restore mytime-backup-20200101-120000.tar.gz`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("Please provide the backup archive to restore")
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			var manifest backups.Manifest
+
+			if manifest, err = backupService.ValidateBackup(args[0]); err != nil {
+				return fmt.Errorf("Cannot restore this backup: %w", err)
+			}
+
+			fmt.Printf("Backup taken %s contains %d files.\n", appSettings.FormatDateTime(manifest.CreatedAt), len(manifest.Files))
+
+			if !yes && !confirm("Replace your current data with this backup?") {
+				fmt.Printf("Nothing was restored.\n")
+				return nil
+			}
+
+			if err = backupService.Restore(args[0]); err != nil {
+				return fmt.Errorf("Problem restoring backup: %w", err)
+			}
+
+			fmt.Printf("Backup %s restored!\n", au.Green(args[0]))
+			return nil
+		},
+	}
+
+	backupCmd.Flags().StringVarP(&outputFile, "out", "o", "", "File to write the backup to. Defaults to a timestamped file in the current directory")
+	restoreCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Restore without asking for confirmation")
+
+	rootCmd.AddCommand(backupCmd, restoreCmd)
+}
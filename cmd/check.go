@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/adampresley/mytime/api/apperrors"
+	"github.com/adampresley/mytime/api/categories"
+	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/helpers"
+	"github.com/adampresley/mytime/api/projects"
+	"github.com/adampresley/mytime/api/sessions"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func printClientMismatches(mismatches []sessions.ClientMismatch) {
+	tableData := make([][]string, 0, len(mismatches))
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Session", "Project", "Session Client", "Project Client", "Invoiced"})
+	table.SetBorder(false)
+
+	setHeaderColor(table,
+		tablewriter.Colors{tablewriter.Bold},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgRedColor},
+		tablewriter.Colors{tablewriter.Bold},
+		tablewriter.Colors{tablewriter.Bold},
+	)
+
+	for _, m := range mismatches {
+		p, _ := projectService.GetProjectByID(m.ProjectID)
+		sessionClient, _ := clientService.GetClientByID(m.ClientID)
+		projectClient, _ := clientService.GetClientByID(m.ProjectClientID)
+
+		session := strconv.Itoa(m.SessionID)
+
+		if m.Active {
+			session = "running"
+		}
+
+		invoiced := "No"
+
+		if m.Invoiced {
+			invoiced = "Yes"
+		}
+
+		tableData = append(tableData, []string{session, p.Code, sessionClient.Code, projectClient.Code, invoiced})
+	}
+
+	table.AppendBulk(tableData)
+	table.Render()
+}
+
+/*
+ * skipInvoiced leaves out the invoiced mismatches unless includeInvoiced
+ * is set, and returns how many it left out. Invoiced sessions are most
+ * likely left with their old client on purpose.
+ */
+func skipInvoiced(mismatches []sessions.ClientMismatch, includeInvoiced bool) ([]sessions.ClientMismatch, int) {
+	kept := make([]sessions.ClientMismatch, 0, len(mismatches))
+
+	for _, m := range mismatches {
+		if !m.Invoiced || includeInvoiced {
+			kept = append(kept, m)
+		}
+	}
+
+	return kept, len(mismatches) - len(kept)
+}
+
+/*
+ * repairClientMismatches fixes the sessions whose client doesn't match
+ * their project. Invoiced sessions may have been left with their old
+ * client on purpose, so they are only repaired when asked.
+ */
+func repairClientMismatches(includeInvoiced bool) error {
+	var (
+		err        error
+		mismatches []sessions.ClientMismatch
+	)
+
+	unlock, err := lockData()
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	if mismatches, err = sessionService.FindClientMismatches(); err != nil {
+		return fmt.Errorf("Problem checking sessions: %w", err)
+	}
+
+	repairs, skipped := skipInvoiced(mismatches, includeInvoiced)
+
+	if len(repairs) > 0 {
+		if err = autoBackup("repair"); err != nil {
+			return err
+		}
+
+		if err = journal.Begin("repair"); err != nil {
+			return err
+		}
+
+		if err = sessionService.RepairClientMismatches(repairs); err != nil {
+			journal.Rollback()
+			return fmt.Errorf("Problem repairing sessions, so nothing was changed: %w", err)
+		}
+
+		if err = journal.Commit(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Repaired %s\n", countOf(len(repairs), "session"))
+
+	if skipped > 0 {
+		fmt.Printf("Left %s alone. Use --include-invoiced to repair them too\n", countOf(skipped, "invoiced session"))
+	}
+
+	return nil
+}
+
+/*
+ * findDuplicateCodes describes the codes used by more than one client,
+ * project, or category, such as "client acme and ACME". Codes ignore case
+ * now, but data saved before then can have both.
+ */
+func findDuplicateCodes() ([]string, error) {
+	var (
+		err                error
+		activeClients      clients.ClientCollection
+		archivedClients    clients.ClientCollection
+		allProjects        projects.ProjectCollection
+		activeCategories   categories.CategoryCollection
+		archivedCategories categories.CategoryCollection
+	)
+
+	if activeClients, err = clientService.ListClients(clients.ClientSearch{}); err != nil {
+		return nil, err
+	}
+
+	if archivedClients, err = clientService.ListClients(clients.ClientSearch{Archived: true}); err != nil {
+		return nil, err
+	}
+
+	if allProjects, err = listAllProjects(); err != nil {
+		return nil, err
+	}
+
+	if activeCategories, err = categoryService.ListCategories(categories.CategorySearch{}); err != nil {
+		return nil, err
+	}
+
+	if archivedCategories, err = categoryService.ListCategories(categories.CategorySearch{Archived: true}); err != nil {
+		return nil, err
+	}
+
+	codes := map[string]helpers.Codes{}
+
+	for _, c := range append(activeClients, archivedClients...) {
+		codes["client"] = append(codes["client"], c.Code)
+	}
+
+	for _, p := range allProjects {
+		codes["project"] = append(codes["project"], p.Code)
+	}
+
+	for _, c := range append(activeCategories, archivedCategories...) {
+		codes["category"] = append(codes["category"], c.Code)
+	}
+
+	result := make([]string, 0, 2)
+
+	for _, entity := range []string{"client", "project", "category"} {
+		for _, group := range codes[entity].Duplicates() {
+			result = append(result, fmt.Sprintf("%s %s", entity, strings.Join(group, " and ")))
+		}
+	}
+
+	return result, nil
+}
+
+func init() {
+	var (
+		repair          bool
+		includeInvoiced bool
+	)
+
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: `Checks your data for sessions whose client doesn't match their project, and duplicate codes`,
+		Long: `Checks your data for sessions whose client doesn't match their project, such as after a project
+was moved to another client. These make reports by client disagree with reports by project. The
+command exits with the conflict code when it finds any, unless --repair is given to fix them.
+
+It also reports codes used by more than one client, project, or category, such as "acme" and
+"ACME", which data saved before codes ignored case can have. Only one of them can be reached by
+its code, so give the others new codes with mt edit.
+
+Invoiced sessions are left with their old client when a project moves, so they are ignored
+unless --include-invoiced is given, both when checking and when repairing.`,
+		Example: `mt check
+mt check --include-invoiced
+mt check --repair
+mt check --repair --include-invoiced`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			var mismatches []sessions.ClientMismatch
+			var skipped int
+			var duplicates []string
+
+			if repair {
+				return repairClientMismatches(includeInvoiced)
+			}
+
+			if mismatches, err = sessionService.FindClientMismatches(); err != nil {
+				return fmt.Errorf("Problem checking sessions: %w", err)
+			}
+
+			mismatches, skipped = skipInvoiced(mismatches, includeInvoiced)
+
+			if duplicates, err = findDuplicateCodes(); err != nil {
+				return fmt.Errorf("Problem checking codes: %w", err)
+			}
+
+			if outputFormat != OutputTable {
+				if _, err = printOutput(mismatches); err != nil {
+					return err
+				}
+			} else if len(mismatches) > 0 {
+				printClientMismatches(mismatches)
+			}
+
+			problems := make([]string, 0, 2)
+
+			if len(mismatches) > 0 {
+				repairCommand := "mt check --repair"
+
+				if includeInvoiced {
+					repairCommand += " --include-invoiced"
+				}
+
+				problems = append(problems, fmt.Sprintf("Found %s whose client doesn't match their project. Run '%s' to fix them", countOf(len(mismatches), "session"), repairCommand))
+			}
+
+			/*
+			 * Only one record of each can be reached by its code, so
+			 * they have to be told apart by hand
+			 */
+			if len(duplicates) > 0 {
+				problems = append(problems, fmt.Sprintf("Found codes used more than once: %s. Give each its own code with 'mt edit'", strings.Join(duplicates, ", ")))
+			}
+
+			if len(problems) > 0 {
+				return apperrors.Conflict("%s", strings.Join(problems, ". "))
+			}
+
+			if outputFormat == OutputTable {
+				fmt.Printf("No problems found\n")
+
+				if skipped > 0 {
+					fmt.Printf("Ignored %s. Use --include-invoiced to check them too\n", countOf(skipped, "invoiced session"))
+				}
+			}
+
+			return nil
+		},
+	}
+
+	checkCmd.Flags().BoolVarP(&repair, "repair", "", false, "Give each session the client of its project")
+	checkCmd.Flags().BoolVarP(&includeInvoiced, "include-invoiced", "", false, "Also check and repair invoiced sessions")
+
+	rootCmd.AddCommand(checkCmd)
+}
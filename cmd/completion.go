@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adampresley/mytime/api/categories"
+	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/projects"
+	"github.com/adampresley/mytime/api/sessions"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// RecentSessionCount is how many of the latest sessions are offered when
+// completing a session ID.
+const RecentSessionCount int = 20
+
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// isCompletionCommand reports whether cmd prints a completion script or
+// answers a completion request. Neither needs the data set up first.
+func isCompletionCommand(cmd *cobra.Command) bool {
+	return cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == "completion"
+}
+
+/*
+ * registerCompletions attaches the dynamic completions to every command
+ * whose first argument is a code or session ID, and to every flag that
+ * takes one. It runs before a script is generated, as the bash script
+ * only asks for completions where they were registered.
+ */
+func registerCompletions() {
+	argCompletions := map[string]completionFunc{
+		"archive client":     completeClients(false),
+		"archive project":    completeProjects(false),
+		"archive category":   completeCategories(false),
+		"unarchive client":   completeClients(true),
+		"unarchive project":  completeProjects(true),
+		"unarchive category": completeCategories(true),
+		"delete client":      completeClients(false),
+		"delete project":     completeProjects(false),
+		"delete category":    completeCategories(false),
+		"edit client":        completeClients(false),
+		"edit project":       completeProjects(false),
+		"edit category":      completeCategories(false),
+		"show client":        completeClients(false),
+		"show project":       completeProjects(false),
+		"show category":      completeCategories(false),
+		"session start":      completeProjects(false),
+		"session close":      completeSessionIDs,
+		"session invoice":    completeSessionIDs,
+	}
+
+	flagCompletions := map[string]completionFunc{
+		"client":      completeClients(false),
+		"project":     completeProjects(false),
+		"category":    completeCategories(false),
+		"id":          completeSessionIDs,
+		"ids":         completeSessionIDs,
+		"reassign-to": completeSameEntity,
+	}
+
+	var walk func(cmd *cobra.Command)
+
+	walk = func(cmd *cobra.Command) {
+		if cmd.HasParent() {
+			if f, ok := argCompletions[cmd.Parent().Name()+" "+cmd.Name()]; ok {
+				cmd.ValidArgsFunction = firstArgument(f)
+			}
+		}
+
+		register := func(flag *pflag.Flag) {
+			if f, ok := flagCompletions[flag.Name]; ok {
+				_ = cmd.RegisterFlagCompletionFunc(flag.Name, f)
+			}
+		}
+
+		cmd.Flags().VisitAll(register)
+		cmd.PersistentFlags().VisitAll(register)
+
+		for _, child := range cmd.Commands() {
+			walk(child)
+		}
+	}
+
+	walk(rootCmd)
+}
+
+// firstArgument only completes the first argument. Any after it are
+// notes or names.
+func firstArgument(f completionFunc) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		return f(cmd, args, toComplete)
+	}
+}
+
+/*
+ * completionData sets up the data directory and services for a
+ * completion request. It is done here rather than before the command,
+ * so --data-dir and --profile on the line being completed are used.
+ */
+func completionData(cmd *cobra.Command) bool {
+	if clientService.ClientRepository != nil {
+		return true
+	}
+
+	return setupData(cmd) == nil
+}
+
+func completeClients(archived bool) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var err error
+		var result clients.ClientCollection
+
+		if !completionData(cmd) {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		if result, err = clientService.ListClients(clients.ClientSearch{Archived: archived}); err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		completions := make([]string, 0, len(result))
+
+		for _, c := range result {
+			completions = append(completions, c.Code+"\t"+c.Name)
+		}
+
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func completeProjects(archived bool) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var err error
+		var result projects.ProjectCollection
+
+		if !completionData(cmd) {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		if result, err = projectService.ListProjects(projects.ProjectSearch{Archived: archived}); err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		completions := make([]string, 0, len(result))
+
+		for _, p := range result {
+			completions = append(completions, p.Code+"\t"+p.Name)
+		}
+
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func completeCategories(archived bool) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var err error
+		var result categories.CategoryCollection
+
+		if !completionData(cmd) {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		if result, err = categoryService.ListCategories(categories.CategorySearch{Archived: archived}); err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		completions := make([]string, 0, len(result))
+
+		for _, c := range result {
+			completions = append(completions, c.Code+"\t"+c.Name)
+		}
+
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeSameEntity completes --reassign-to with records of the kind
+// being deleted.
+func completeSameEntity(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch cmd.Name() {
+	case "client":
+		return completeClients(false)(cmd, args, toComplete)
+
+	case "project":
+		return completeProjects(false)(cmd, args, toComplete)
+
+	case "category":
+		return completeCategories(false)(cmd, args, toComplete)
+	}
+
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+/*
+ * completeSessionIDs offers the most recent sessions, newest first. IDs
+ * can be given as a comma separated list, so anything up to the last
+ * comma is kept in front of each one.
+ */
+func completeSessionIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var err error
+	var result sessions.SessionCollection
+
+	if !completionData(cmd) {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	if result, err = sessionService.ListAllSessions(); err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartDateTime.After(result[j].StartDateTime)
+	})
+
+	if len(result) > RecentSessionCount {
+		result = result[:RecentSessionCount]
+	}
+
+	prefix := ""
+
+	if index := strings.LastIndex(toComplete, ","); index > -1 {
+		prefix = toComplete[:index+1]
+	}
+
+	completions := make([]string, 0, len(result))
+
+	for _, s := range result {
+		p, _ := projectService.GetProjectByID(s.ProjectID)
+		description := fmt.Sprintf("%s %s %s", appSettings.FormatDate(s.StartDateTime), p.Code, s.Notes)
+
+		completions = append(completions, prefix+strconv.Itoa(s.SessionID)+"\t"+strings.TrimSpace(description))
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+/*
+ * cobra's zsh script can't ask the program for completions, so this one
+ * does, in the same way as the bash and fish scripts.
+ */
+const zshCompletion string = `#compdef mt
+
+_mt() {
+  local -a lines completions
+  local out directive line value
+
+  out="$(${words[1]} __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"
+  lines=("${(@f)out}")
+  directive="${lines[-1]#:}"
+  lines=("${(@)lines[1,-2]}")
+
+  if (( directive & 1 )); then
+    return 1
+  fi
+
+  for line in "${lines[@]}"; do
+    value="${${line%%$'\t'*}//:/\\:}"
+
+    if [[ "$line" == *$'\t'* ]]; then
+      completions+=("${value}:${line#*$'\t'}")
+    else
+      completions+=("${value}")
+    fi
+  done
+
+  if (( ${#completions} > 0 )); then
+    if (( directive & 2 )); then
+      _describe -t values 'values' completions -S ''
+    else
+      _describe -t values 'values' completions
+    fi
+  elif (( ! (directive & 4) )); then
+    _files
+  fi
+}
+
+if [ "$funcstack[1]" = "_mt" ]; then
+  _mt "$@"
+else
+  compdef _mt mt
+fi
+`
+
+func init() {
+	completionCmd := &cobra.Command{
+		Use:   "completion",
+		Short: `Prints a shell completion script for bash, zsh, or fish`,
+		Long: `Prints a shell completion script for bash, zsh, or fish. Besides commands and flags it
+completes client, project, and category codes, and recent session IDs. Archived records are left
+out, except when unarchiving.
+
+To load completions in the current shell:
+
+  bash: source <(mt completion bash)
+  zsh:  source <(mt completion zsh)
+  fish: mt completion fish | source
+
+To load them in every new shell, add that line to ~/.bashrc, ~/.zshrc, or
+~/.config/fish/config.fish.`,
+		Example: `mt completion bash
+mt completion zsh > "${fpath[1]}/_mt"
+mt completion fish > ~/.config/fish/completions/mt.fish`,
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("Please provide the shell to print a completion script for: bash, zsh, or fish")
+			}
+
+			return cobra.OnlyValidArgs(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch args[0] {
+			case "bash":
+				return rootCmd.GenBashCompletion(os.Stdout)
+
+			case "zsh":
+				_, err := fmt.Print(zshCompletion)
+				return err
+			}
+
+			return rootCmd.GenFishCompletion(os.Stdout, true)
+		},
+	}
+
+	rootCmd.AddCommand(completionCmd)
+}
@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/adampresley/mytime/api/settings"
+	"github.com/adampresley/mytime/api/storage"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// settingOutput is a setting as shown by config list and get with
+// --output.
+type settingOutput struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Default     string `json:"default"`
+	IsSet       bool   `json:"isSet"`
+	Description string `json:"description"`
+}
+
+func newSettingOutput(v settings.Value) settingOutput {
+	return settingOutput{
+		Key:         v.Key,
+		Value:       v.Value,
+		Default:     v.Default,
+		IsSet:       v.IsSet,
+		Description: v.Description,
+	}
+}
+
+// isConfigCommand reports whether cmd is one of the config commands,
+// which must still run when config.yml is invalid.
+func isConfigCommand(cmd *cobra.Command) bool {
+	return cmd.HasParent() && cmd.Parent().Name() == "config"
+}
+
+func init() {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: `Shows and changes settings`,
+		Long: `Shows and changes settings. Settings are kept in config.yml in the data directory, so each
+profile has its own. Run "mt config list" to see every setting and what it does.`,
+	}
+
+	listConfigCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   `Lists every setting, its value, and what it does`,
+		Example: `mt config list`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			var values []settings.Value
+
+			if values, err = settingsService.List(); err != nil {
+				return fmt.Errorf("Problem reading settings: %w", err)
+			}
+
+			if outputFormat != OutputTable {
+				records := make([]settingOutput, 0, len(values))
+
+				for _, v := range values {
+					records = append(records, newSettingOutput(v))
+				}
+
+				_, err = printOutput(records)
+				return err
+			}
+
+			tableData := make([][]string, 0, len(values))
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Setting", "Value", "Default", "Description"})
+			table.SetBorder(false)
+			table.SetAutoWrapText(false)
+
+			setHeaderColor(table,
+				tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
+				tablewriter.Colors{tablewriter.Bold},
+				tablewriter.Colors{tablewriter.Bold},
+				tablewriter.Colors{tablewriter.Bold},
+			)
+
+			for _, v := range values {
+				value := v.Value
+
+				if !v.IsSet {
+					value = ""
+				}
+
+				tableData = append(tableData, []string{v.Key, value, v.Default, v.Description})
+			}
+
+			table.AppendBulk(tableData)
+			table.Render()
+
+			fmt.Printf("\nSettings file: %s\n", configFile)
+			return nil
+		},
+	}
+
+	getConfigCmd := &cobra.Command{
+		Use:     "get",
+		Short:   `Shows the value of a setting`,
+		Example: `mt config get time.clock`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			var value settings.Value
+
+			if value, err = settingsService.Get(args[0]); err != nil {
+				return configError(err)
+			}
+
+			if printed, err := printOutput(newSettingOutput(value)); printed {
+				return err
+			}
+
+			fmt.Println(value.Value)
+			return nil
+		},
+	}
+
+	setConfigCmd := &cobra.Command{
+		Use:   "set",
+		Short: `Changes a setting`,
+		Example: `mt config set time.clock 24h
+mt config set date.format 2006-01-02
+mt config set rounding.increment 15`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := settingsService.Set(args[0], args[1]); err != nil {
+				return configError(err)
+			}
+
+			value, _ := settingsService.Get(args[0])
+			fmt.Printf("%s set to %s\n", au.Green(args[0]), value.Value)
+			return nil
+		},
+	}
+
+	unsetConfigCmd := &cobra.Command{
+		Use:     "unset",
+		Short:   `Removes a setting, so its default is used`,
+		Example: `mt config unset time.clock`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := settingsService.Unset(args[0]); err != nil {
+				return configError(err)
+			}
+
+			value, _ := settingsService.Get(args[0])
+			fmt.Printf("%s reset to %s\n", au.Green(args[0]), value.Value)
+			return nil
+		},
+	}
+
+	editConfigCmd := &cobra.Command{
+		Use:   "edit",
+		Short: `Opens the settings file in your editor`,
+		Long: `Opens the settings file in the editor named by $VISUAL or $EDITOR. The file is checked when
+the editor closes, and only saved if every setting is valid.`,
+		Example: `mt config edit
+EDITOR=nano mt config edit`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return editConfig()
+		},
+	}
+
+	configCmd.AddCommand(listConfigCmd)
+	configCmd.AddCommand(getConfigCmd)
+	configCmd.AddCommand(setConfigCmd)
+	configCmd.AddCommand(unsetConfigCmd)
+	configCmd.AddCommand(editConfigCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// configError points at the list of settings when a key isn't known.
+func configError(err error) error {
+	if errors.Is(err, settings.ErrUnknownSetting) {
+		return fmt.Errorf("%w. Run 'mt config list' to see every setting", err)
+	}
+
+	return err
+}
+
+/*
+ * editConfig edits a copy of config.yml, so a mistake never reaches the
+ * real file. A new file starts out listing every setting, commented out.
+ */
+func editConfig() error {
+	var (
+		err  error
+		b    []byte
+		temp *os.File
+	)
+
+	if b, err = ioutil.ReadFile(configFile); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("Problem reading settings: %w", err)
+		}
+
+		b = []byte(configTemplate())
+	}
+
+	if temp, err = ioutil.TempFile("", "mytime-config-*.yml"); err != nil {
+		return fmt.Errorf("Problem creating temporary file: %w", err)
+	}
+
+	tempName := temp.Name()
+	defer os.Remove(tempName)
+
+	_, err = temp.Write(b)
+	temp.Close()
+
+	if err != nil {
+		return fmt.Errorf("Problem creating temporary file: %w", err)
+	}
+
+	for {
+		if err = runEditor(tempName); err != nil {
+			return fmt.Errorf("Problem running editor: %w", err)
+		}
+
+		if err = settingsService.ValidateFile(tempName); err == nil {
+			break
+		}
+
+		fmt.Printf("%s %s\n", au.Red("ERROR:"), err.Error())
+
+		if !confirm("Edit again?") {
+			return fmt.Errorf("Your changes were not saved: %w", err)
+		}
+	}
+
+	if b, err = ioutil.ReadFile(tempName); err != nil {
+		return fmt.Errorf("Problem reading edited settings: %w", err)
+	}
+
+	if err = storage.WriteFile(afero.NewOsFs(), configFile, b, 0644); err != nil {
+		return fmt.Errorf("Problem saving settings: %w", err)
+	}
+
+	fmt.Printf("Settings saved to %s\n", au.Green(configFile))
+	return nil
+}
+
+func runEditor(fileName string) error {
+	editor := os.Getenv("VISUAL")
+
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+
+	if editor == "" {
+		editor = "vi"
+
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	/*
+	 * Editors are often given with arguments, such as "code --wait"
+	 */
+	parts := strings.Fields(editor)
+
+	command := exec.Command(parts[0], append(parts[1:], fileName)...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+
+	return command.Run()
+}
+
+func configTemplate() string {
+	var sb strings.Builder
+
+	sb.WriteString("# My Time settings. Uncomment a setting to change it.\n")
+	sb.WriteString("# Run 'mt config list' to see the current values.\n")
+
+	for _, d := range settings.Definitions {
+		parts := strings.Split(d.Key, ".")
+
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf("# %s\n", d.Description))
+
+		for index, part := range parts {
+			value := ""
+
+			if index == len(parts)-1 {
+				value = " " + d.Default
+
+				if d.Kind == settings.KindLayout || d.Kind == settings.KindString {
+					value = fmt.Sprintf(" %q", d.Default)
+				}
+			}
+
+			sb.WriteString(fmt.Sprintf("# %s%s:%s\n", strings.Repeat("  ", index), part, value))
+		}
+	}
+
+	return sb.String()
+}
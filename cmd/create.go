@@ -1,19 +1,25 @@
 package cmd
 
 import (
-	"errors"
 	"fmt"
 	"strconv"
 
 	"github.com/adampresley/mytime/api/categories"
 	"github.com/adampresley/mytime/api/clients"
 	"github.com/adampresley/mytime/api/projects"
-	"github.com/adampresley/simdb"
-	. "github.com/logrusorgru/aurora"
 	"github.com/spf13/cobra"
 )
 
 func init() {
+	var (
+		address      string
+		contactName  string
+		contactEmail string
+		paymentTerms int
+		taxID        string
+		notes        string
+	)
+
 	createCmd := &cobra.Command{
 		Use:     "create",
 		Aliases: []string{"c"},
@@ -24,35 +30,65 @@ func init() {
 		Use:     "client",
 		Aliases: []string{"c"},
 		Short:   `Creates a new client`,
-		Example: `mt create client "Client A" "clientcode"`,
+		Long:    `Creates a new client. Leave out the code to have one made from the name.`,
+		Example: `mt create client "Client A" "clientcode"
+mt create client "Client A"
+mt create client "Client A" "clientcode" --address "123 Main St, Springfield" --contact "Jane Doe" --email "jane@clienta.com" --terms 30 --tax-id "US123456" --notes "Invoice monthly"`,
 		Args: func(cmd *cobra.Command, args []string) error {
-			if len(args) < 2 {
-				return fmt.Errorf("Please provide a name and code for this client!")
+			if len(args) < 1 {
+				return fmt.Errorf("Please provide a name for this client!")
+			}
+
+			if paymentTerms < 0 {
+				return fmt.Errorf("Payment terms must be zero or more days")
 			}
 
 			return nil
 		},
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				err        error
 				clientName string
 				clientCode string
+				clientID   int
 			)
 
 			clientName = args[0]
-			clientCode = args[1]
+
+			if len(args) > 1 {
+				clientCode = args[1]
+			}
 
 			client := clients.Client{
-				Name:     clientName,
-				Code:     clientCode,
-				Archived: false,
+				Name:         clientName,
+				Code:         clientCode,
+				Address:      address,
+				ContactName:  contactName,
+				ContactEmail: contactEmail,
+				PaymentTerms: paymentTerms,
+				TaxID:        taxID,
+				Notes:        notes,
+				Archived:     false,
+			}
+
+			unlock, err := lockData()
+
+			if err != nil {
+				return err
 			}
 
-			if _, err = clientService.CreateClient(client); err != nil {
-				displayError(fmt.Sprintf("Error creating client: %s", err.Error()))
+			defer unlock()
+
+			if clientID, err = clientService.CreateClient(client); err != nil {
+				return fmt.Errorf("Error creating client: %w", err)
 			}
 
-			fmt.Printf("New client %s created!\n", Green(clientName))
+			if client, err = clientService.GetClientByID(clientID); err != nil {
+				return err
+			}
+
+			fmt.Printf("New client %s created with the code %s!\n", au.Green(clientName), au.Green(client.Code))
+			return nil
 		},
 	}
 
@@ -60,31 +96,37 @@ func init() {
 		Use:     "category",
 		Aliases: []string{"cat"},
 		Short:   `Creates a new category. `,
-		Example: `mt create category "Development" "dev" 50.00`,
+		Long:    `Creates a new category. Leave out the code to have one made from the name.`,
+		Example: `mt create category "Development" "dev" 50.00
+mt create category "Development" 50.00`,
 		Args: func(cmd *cobra.Command, args []string) error {
 			var err error
 
-			if len(args) < 3 {
-				return fmt.Errorf("Please provide the name, code, and rate for your new category!")
+			if len(args) < 2 {
+				return fmt.Errorf("Please provide the name, code (optional), and rate for your new category!")
 			}
 
-			if _, err = strconv.ParseFloat(args[2], 64); err != nil {
+			if _, err = strconv.ParseFloat(args[len(args)-1], 64); err != nil {
 				return fmt.Errorf("Invalid rate. Must be a decimal number!")
 			}
 
 			return nil
 		},
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				err          error
 				categoryName string
 				code         string
 				rate         float64
+				categoryID   int
 			)
 
 			categoryName = args[0]
-			code = args[1]
-			rate, _ = strconv.ParseFloat(args[2], 64)
+			rate, _ = strconv.ParseFloat(args[len(args)-1], 64)
+
+			if len(args) > 2 {
+				code = args[1]
+			}
 
 			category := categories.Category{
 				Name:     categoryName,
@@ -93,11 +135,24 @@ func init() {
 				Archived: false,
 			}
 
-			if _, err = categoryService.CreateCategory(category); err != nil {
-				displayError(fmt.Sprintf("Error creating category: %s", err.Error()))
+			unlock, err := lockData()
+
+			if err != nil {
+				return err
 			}
 
-			fmt.Printf("New category '%s' created!\n", Green(categoryName))
+			defer unlock()
+
+			if categoryID, err = categoryService.CreateCategory(category); err != nil {
+				return fmt.Errorf("Error creating category: %w", err)
+			}
+
+			if category, err = categoryService.GetCategoryByID(categoryID); err != nil {
+				return err
+			}
+
+			fmt.Printf("New category '%s' created with the code %s!\n", au.Green(categoryName), au.Green(category.Code))
+			return nil
 		},
 	}
 
@@ -105,16 +160,18 @@ func init() {
 		Use:     "project",
 		Aliases: []string{"p", "proj"},
 		Short:   `Create a new project.`,
-		Long:    `Creates a new project. Projects are tied to clients, and are what time is tracked against.`,
-		Example: `mt create project "Name" "code" "clientCode" "defaultCategoryCode"`,
+		Long: `Creates a new project. Projects are tied to clients, and are what time is tracked against.
+Leave out the code to have one made from the name.`,
+		Example: `mt create project "Name" "code" "clientCode" "defaultCategoryCode"
+mt create project "Name" "clientCode" "defaultCategoryCode"`,
 		Args: func(cmd *cobra.Command, args []string) error {
-			if len(args) < 4 {
-				return fmt.Errorf("Please provide a name, code, client code, and default category code for your new project!")
+			if len(args) < 3 {
+				return fmt.Errorf("Please provide a name, code (optional), client code, and default category code for your new project!")
 			}
 
 			return nil
 		},
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				err                 error
 				name                string
@@ -129,24 +186,27 @@ func init() {
 			)
 
 			name = args[0]
-			code = args[1]
-			clientCode = args[2]
-			defaultCategoryCode = args[3]
+			clientCode = args[len(args)-2]
+			defaultCategoryCode = args[len(args)-1]
+
+			if len(args) > 3 {
+				code = args[1]
+			}
+
+			unlock, err := lockData()
+
+			if err != nil {
+				return err
+			}
+
+			defer unlock()
 
 			if client, err = clientService.GetClientByCode(clientCode); err != nil {
-				if errors.Is(err, simdb.ErrZeroRecords) {
-					displayError(fmt.Sprintf("Client code %s not found", Green(clientCode)))
-				} else {
-					displayError(err.Error())
-				}
+				return err
 			}
 
 			if category, err = categoryService.GetCategoryByCode(defaultCategoryCode); err != nil {
-				if errors.Is(err, simdb.ErrZeroRecords) {
-					displayError(fmt.Sprintf("Category code %s not found", Green(defaultCategoryCode)))
-				} else {
-					displayError(err.Error())
-				}
+				return err
 			}
 
 			newProject := projects.Project{
@@ -158,13 +218,25 @@ func init() {
 			}
 
 			if newProjectID, err = projectService.CreateProject(newProject); err != nil {
-				displayError(fmt.Sprintf("Problem creating project: %s", err.Error()))
+				return fmt.Errorf("Problem creating project: %w", err)
 			}
 
-			fmt.Printf("New project created!\n\nID: %d\nName: %s\nCode: %s\n", newProjectID, name, Green(code))
+			if newProject, err = projectService.GetProjectByID(newProjectID); err != nil {
+				return err
+			}
+
+			fmt.Printf("New project created!\n\nID: %d\nName: %s\nCode: %s\n", newProjectID, name, au.Green(newProject.Code))
+			return nil
 		},
 	}
 
+	createClientCmd.Flags().StringVarP(&address, "address", "", "", "Billing address for the client")
+	createClientCmd.Flags().StringVarP(&contactName, "contact", "", "", "Name of the billing contact")
+	createClientCmd.Flags().StringVarP(&contactEmail, "email", "", "", "Email address of the billing contact")
+	createClientCmd.Flags().IntVarP(&paymentTerms, "terms", "", 0, "Payment terms in days (e.g. 30 for Net 30)")
+	createClientCmd.Flags().StringVarP(&taxID, "tax-id", "", "", "Tax or VAT ID for the client")
+	createClientCmd.Flags().StringVarP(&notes, "notes", "", "", "Free-form notes about the client")
+
 	createCmd.AddCommand(createClientCmd, createCategoryCmd, createProjectCmd)
 	rootCmd.AddCommand(createCmd)
 }
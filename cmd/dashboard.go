@@ -0,0 +1,671 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/adampresley/mytime/api/categories"
+	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/projects"
+	"github.com/adampresley/mytime/api/sessions"
+	"github.com/eiannone/keyboard"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// DashboardRefresh is how often the dashboard reloads its data, to
+	// pick up changes made by other mt commands.
+	DashboardRefresh time.Duration = 10 * time.Second
+
+	// DashboardTodayLimit is how many of today's sessions are listed.
+	DashboardTodayLimit int = 8
+
+	enterFullScreen string = "\x1b[?1049h\x1b[?25l"
+	leaveFullScreen string = "\x1b[?25h\x1b[?1049l"
+	clearScreen     string = "\x1b[H\x1b[2J"
+)
+
+type dashboardMode int
+
+const (
+	dashboardNormal dashboardMode = iota
+	dashboardPicking
+	dashboardTyping
+)
+
+// clientAmount is the unbilled amount for one client.
+type clientAmount struct {
+	name   string
+	amount float64
+}
+
+/*
+ * dashboard is the state of the full screen dashboard. Besides the data
+ * on screen it keeps a paused timer, which only lives as long as the
+ * dashboard is open.
+ */
+type dashboard struct {
+	mode    dashboardMode
+	message string
+
+	pickTitle string
+	pickList  projects.ProjectCollection
+	picked    int
+	onPick    func(project projects.Project)
+
+	inputPrompt string
+	input       []rune
+	onInput     func(text string)
+
+	paused *sessions.ActiveSession
+
+	active   *sessions.ActiveSession
+	today    sessions.SessionCollection
+	week     sessions.SessionCollection
+	unbilled []clientAmount
+	loadedAt time.Time
+
+	clients    map[int]clients.Client
+	projects   map[int]projects.Project
+	categories map[int]categories.Category
+}
+
+func newDashboard() *dashboard {
+	return &dashboard{
+		clients:    make(map[int]clients.Client),
+		projects:   make(map[int]projects.Project),
+		categories: make(map[int]categories.Category),
+	}
+}
+
+func (d *dashboard) client(id int) clients.Client {
+	if c, ok := d.clients[id]; ok {
+		return c
+	}
+
+	c, _ := clientService.GetClientByID(id)
+	d.clients[id] = c
+	return c
+}
+
+func (d *dashboard) project(id int) projects.Project {
+	if p, ok := d.projects[id]; ok {
+		return p
+	}
+
+	p, _ := projectService.GetProjectByID(id)
+	d.projects[id] = p
+	return p
+}
+
+func (d *dashboard) category(id int) categories.Category {
+	if c, ok := d.categories[id]; ok {
+		return c
+	}
+
+	c, _ := categoryService.GetCategoryByID(id)
+	d.categories[id] = c
+	return c
+}
+
+// refresh reloads the timer and sessions shown on the dashboard.
+func (d *dashboard) refresh() error {
+	var (
+		err              error
+		all              sessions.SessionCollection
+		hasActiveSession bool
+		activeSession    sessions.ActiveSession
+	)
+
+	d.clients = make(map[int]clients.Client)
+	d.projects = make(map[int]projects.Project)
+	d.categories = make(map[int]categories.Category)
+	d.active = nil
+
+	if hasActiveSession, err = sessionService.HasActiveSession(); err != nil {
+		return err
+	}
+
+	if hasActiveSession {
+		if activeSession, err = sessionService.GetActiveSession(); err != nil {
+			return err
+		}
+
+		d.active = &activeSession
+	}
+
+	if all, err = sessionService.ListAllSessions(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	startOfWeek := appSettings.StartOfWeek(now)
+	unbilled := make(map[int]float64)
+
+	d.today = make(sessions.SessionCollection, 0, 10)
+	d.week = make(sessions.SessionCollection, 0, 50)
+
+	for _, s := range all {
+		if !s.StartDateTime.Before(startOfToday) {
+			d.today = append(d.today, s)
+		}
+
+		if !s.StartDateTime.Before(startOfWeek) {
+			d.week = append(d.week, s)
+		}
+
+		if !s.Invoiced && !s.Paid {
+			duration := appSettings.Round(s.EndDateTime.Sub(s.StartDateTime))
+			unbilled[s.ClientID] += duration.Hours() * d.category(s.CategoryID).Rate
+		}
+	}
+
+	sort.Slice(d.today, func(i, j int) bool {
+		return d.today[i].StartDateTime.Before(d.today[j].StartDateTime)
+	})
+
+	d.unbilled = make([]clientAmount, 0, len(unbilled))
+
+	for clientID, amount := range unbilled {
+		d.unbilled = append(d.unbilled, clientAmount{name: d.client(clientID).Name, amount: amount})
+	}
+
+	sort.Slice(d.unbilled, func(i, j int) bool {
+		return d.unbilled[i].name < d.unbilled[j].name
+	})
+
+	d.loadedAt = now
+	return nil
+}
+
+func (d *dashboard) elapsed() time.Duration {
+	if d.active == nil {
+		return 0
+	}
+
+	return time.Now().Sub(d.active.StartTime)
+}
+
+/*
+ * runningWithin returns how much of the running timer falls between from
+ * and to, so a timer started yesterday only counts today from midnight.
+ */
+func (d *dashboard) runningWithin(from, to time.Time) time.Duration {
+	if d.active == nil {
+		return 0
+	}
+
+	start := d.active.StartTime
+	end := time.Now()
+
+	if start.Before(from) {
+		start = from
+	}
+
+	if end.After(to) {
+		end = to
+	}
+
+	if !end.After(start) {
+		return 0
+	}
+
+	return end.Sub(start)
+}
+
+// total adds up the sessions in list and the running timer between from
+// and to.
+func (d *dashboard) total(list sessions.SessionCollection, from, to time.Time) time.Duration {
+	var result time.Duration
+
+	for _, s := range list {
+		result += appSettings.Round(s.EndDateTime.Sub(s.StartDateTime))
+	}
+
+	return result + d.runningWithin(from, to)
+}
+
+func (d *dashboard) label(projectID, categoryID int) string {
+	p := d.project(projectID)
+	return fmt.Sprintf("%s / %s (%s)", d.client(p.ClientID).Name, p.Name, d.category(categoryID).Name)
+}
+
+func (d *dashboard) render() {
+	var sb strings.Builder
+
+	now := time.Now()
+
+	sb.WriteString(clearScreen)
+	sb.WriteString(fmt.Sprintf("%s  %s\n\n", au.Bold("My Time"), appSettings.FormatDateTime(now)))
+
+	switch {
+	case d.active != nil:
+		sb.WriteString(fmt.Sprintf("%s %s  %s\n", au.Green("● Timing"), d.label(d.active.ProjectID, d.active.CategoryID), au.Bold(appSettings.FormatDuration(d.elapsed()))))
+		sb.WriteString(fmt.Sprintf("  %s\n", d.active.Notes))
+
+	case d.paused != nil:
+		sb.WriteString(fmt.Sprintf("%s %s\n", au.Yellow("‖ Paused"), d.label(d.paused.ProjectID, d.paused.CategoryID)))
+		sb.WriteString(fmt.Sprintf("  %s\n", d.paused.Notes))
+
+	default:
+		sb.WriteString("No timer running\n\n")
+	}
+
+	if d.mode == dashboardPicking {
+		d.renderPicker(&sb)
+	} else {
+		d.renderSessions(&sb)
+	}
+
+	sb.WriteString("\n")
+
+	switch d.mode {
+	case dashboardPicking:
+		sb.WriteString(fmt.Sprintf("%s select  %s choose  %s cancel\n", au.Cyan("↑/↓"), au.Cyan("enter"), au.Cyan("esc")))
+
+	case dashboardTyping:
+		sb.WriteString(fmt.Sprintf("%s: %s_\n", d.inputPrompt, string(d.input)))
+		sb.WriteString(fmt.Sprintf("%s save  %s cancel\n", au.Cyan("enter"), au.Cyan("esc")))
+
+	default:
+		pause := "pause"
+
+		if d.paused != nil && d.active == nil {
+			pause = "resume"
+		}
+
+		sb.WriteString(fmt.Sprintf("%s start  %s stop  %s switch  %s %s  %s notes  %s quit\n",
+			au.Cyan("s"), au.Cyan("x"), au.Cyan("w"), au.Cyan("p"), pause, au.Cyan("n"), au.Cyan("q")))
+	}
+
+	if d.message != "" {
+		sb.WriteString(fmt.Sprintf("\n%s\n", d.message))
+	}
+
+	fmt.Print(sb.String())
+}
+
+func (d *dashboard) renderSessions(sb *strings.Builder) {
+	today := d.today
+	now := time.Now()
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	startOfWeek := appSettings.StartOfWeek(now)
+
+	sb.WriteString(fmt.Sprintf("\n%s  %s\n", au.Bold("Today"), appSettings.FormatDuration(d.total(d.today, startOfToday, startOfToday.AddDate(0, 0, 1)))))
+
+	if len(today) > DashboardTodayLimit {
+		sb.WriteString(fmt.Sprintf("  ... %s earlier\n", countOf(len(today)-DashboardTodayLimit, "session")))
+		today = today[len(today)-DashboardTodayLimit:]
+	}
+
+	for _, s := range today {
+		sb.WriteString(fmt.Sprintf("  %s - %s  %s  %-10s %s\n",
+			appSettings.FormatTime(s.StartDateTime),
+			appSettings.FormatTime(s.EndDateTime),
+			appSettings.FormatDuration(appSettings.Round(s.EndDateTime.Sub(s.StartDateTime))),
+			d.project(s.ProjectID).Code,
+			s.Notes,
+		))
+	}
+
+	/*
+	 * The week is summed up by day, as listing every session would run
+	 * off the screen
+	 */
+	sb.WriteString(fmt.Sprintf("\n%s  %s\n", au.Bold("This Week"), appSettings.FormatDuration(d.total(d.week, startOfWeek, startOfWeek.AddDate(0, 0, 7)))))
+
+	for day := 0; day < 7; day++ {
+		from := startOfWeek.AddDate(0, 0, day)
+		to := from.AddDate(0, 0, 1)
+
+		if from.After(now) {
+			break
+		}
+
+		var total time.Duration
+
+		for _, s := range d.week {
+			if !s.StartDateTime.Before(from) && s.StartDateTime.Before(to) {
+				total += appSettings.Round(s.EndDateTime.Sub(s.StartDateTime))
+			}
+		}
+
+		total += d.runningWithin(from, to)
+
+		sb.WriteString(fmt.Sprintf("  %s  %s\n", from.Format("Mon"), appSettings.FormatDuration(total)))
+	}
+
+	sb.WriteString(fmt.Sprintf("\n%s\n", au.Bold("Unbilled")))
+
+	if len(d.unbilled) == 0 {
+		sb.WriteString("  Nothing waiting to be billed\n")
+	}
+
+	for _, u := range d.unbilled {
+		sb.WriteString(fmt.Sprintf("  %-24s %s\n", u.name, au.Green(appSettings.FormatMoney(u.amount))))
+	}
+}
+
+func (d *dashboard) renderPicker(sb *strings.Builder) {
+	sb.WriteString(fmt.Sprintf("\n%s\n", au.Bold(d.pickTitle)))
+
+	for index, p := range d.pickList {
+		line := fmt.Sprintf("%-12s %s / %s", p.Code, d.client(p.ClientID).Name, p.Name)
+
+		if index == d.picked {
+			sb.WriteString(fmt.Sprintf("%s %s\n", au.Green(">"), au.Green(line)))
+		} else {
+			sb.WriteString(fmt.Sprintf("  %s\n", line))
+		}
+	}
+}
+
+// pickProject lists the projects that aren't archived, and calls onPick
+// with the one chosen.
+func (d *dashboard) pickProject(title string, onPick func(project projects.Project)) {
+	var err error
+
+	if d.pickList, err = projectService.ListProjects(projects.ProjectSearch{}); err != nil {
+		d.message = err.Error()
+		return
+	}
+
+	if len(d.pickList) == 0 {
+		d.message = "There are no projects. Create one with 'mt create project'"
+		return
+	}
+
+	d.mode = dashboardPicking
+	d.pickTitle = title
+	d.picked = 0
+	d.onPick = onPick
+}
+
+func (d *dashboard) askText(prompt, initial string, onInput func(text string)) {
+	d.mode = dashboardTyping
+	d.inputPrompt = prompt
+	d.input = []rune(initial)
+	d.onInput = onInput
+}
+
+// handleKey acts on a key press, and reports whether to quit.
+func (d *dashboard) handleKey(event keyboard.KeyEvent) bool {
+	switch d.mode {
+	case dashboardPicking:
+		switch event.Key {
+		case keyboard.KeyArrowUp:
+			if d.picked > 0 {
+				d.picked--
+			}
+
+		case keyboard.KeyArrowDown:
+			if d.picked < len(d.pickList)-1 {
+				d.picked++
+			}
+
+		case keyboard.KeyEnter:
+			d.mode = dashboardNormal
+			d.onPick(d.pickList[d.picked])
+
+		case keyboard.KeyEsc, keyboard.KeyCtrlC:
+			d.mode = dashboardNormal
+		}
+
+		return false
+
+	case dashboardTyping:
+		switch event.Key {
+		case keyboard.KeyEnter:
+			d.mode = dashboardNormal
+			d.onInput(strings.TrimSpace(string(d.input)))
+
+		case keyboard.KeyEsc, keyboard.KeyCtrlC:
+			d.mode = dashboardNormal
+
+		case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+			if len(d.input) > 0 {
+				d.input = d.input[:len(d.input)-1]
+			}
+
+		case keyboard.KeySpace:
+			d.input = append(d.input, ' ')
+
+		default:
+			if event.Rune != 0 {
+				d.input = append(d.input, event.Rune)
+			}
+		}
+
+		return false
+	}
+
+	if event.Key == keyboard.KeyEsc || event.Key == keyboard.KeyCtrlC {
+		return true
+	}
+
+	d.message = ""
+
+	switch event.Rune {
+	case 'q':
+		return true
+
+	case 's':
+		if d.active != nil {
+			d.message = "A timer is already running. Press w to switch to another project"
+			break
+		}
+
+		d.pickProject("Start timing", func(project projects.Project) {
+			d.askText("Notes", "", func(notes string) {
+				d.start(project.ProjectID, project.DefaultCategoryID, notes)
+			})
+		})
+
+	case 'x':
+		if d.active == nil {
+			d.message = "There is no active session"
+			break
+		}
+
+		d.stop()
+		d.paused = nil
+
+	case 'w':
+		if d.active == nil {
+			d.message = "There is no active session to switch from. Press s to start one"
+			break
+		}
+
+		d.pickProject("Switch to", func(project projects.Project) {
+			d.askText("Notes", d.active.Notes, func(notes string) {
+				if d.stop() {
+					d.start(project.ProjectID, project.DefaultCategoryID, notes)
+				}
+			})
+		})
+
+	case 'p':
+		d.togglePause()
+
+	case 'n':
+		if d.active == nil {
+			d.message = "There is no active session"
+			break
+		}
+
+		d.askText("Notes", d.active.Notes, d.setNotes)
+	}
+
+	return false
+}
+
+func (d *dashboard) start(projectID, categoryID int, notes string) {
+	project := d.project(projectID)
+	category := d.category(categoryID)
+
+	if category.Archived {
+		d.message = fmt.Sprintf("Category %s is archived. Unarchive it with 'mt unarchive category %s'", category.Code, category.Code)
+		return
+	}
+
+	if _, _, err := startActiveSession(project.ProjectID, category.CategoryID, project.ClientID, notes); err != nil {
+		d.message = err.Error()
+		return
+	}
+
+	d.paused = nil
+	d.message = fmt.Sprintf("Started timing %s", project.Code)
+	d.reload()
+}
+
+// stop records the running timer, and reports whether it worked.
+func (d *dashboard) stop() bool {
+	session, err := stopActiveSession()
+
+	if err != nil {
+		d.message = err.Error()
+		return false
+	}
+
+	d.message = fmt.Sprintf("Recorded %s on %s", appSettings.FormatDuration(session.EndDateTime.Sub(session.StartDateTime)), d.project(session.ProjectID).Code)
+	d.reload()
+	return true
+}
+
+/*
+ * togglePause stops the timer, remembering it, or starts a new one like
+ * it. Pausing records the time so far as a session, so nothing is lost
+ * if the dashboard is closed while paused.
+ */
+func (d *dashboard) togglePause() {
+	if d.active != nil {
+		paused := *d.active
+
+		if d.stop() {
+			d.paused = &paused
+			d.message = "Paused. Press p to resume"
+		}
+
+		return
+	}
+
+	if d.paused == nil {
+		d.message = "There is no active session"
+		return
+	}
+
+	d.start(d.paused.ProjectID, d.paused.CategoryID, d.paused.Notes)
+}
+
+func (d *dashboard) setNotes(notes string) {
+	unlock, err := lockData()
+
+	if err != nil {
+		d.message = err.Error()
+		return
+	}
+
+	defer unlock()
+
+	activeSession := *d.active
+	activeSession.Notes = notes
+
+	if err = sessionService.UpdateActiveSession(activeSession); err != nil {
+		d.message = err.Error()
+		return
+	}
+
+	d.message = "Notes saved"
+	d.active = &activeSession
+}
+
+func (d *dashboard) reload() {
+	if err := d.refresh(); err != nil {
+		d.message = err.Error()
+	}
+}
+
+func runDashboard() error {
+	var err error
+	var keys <-chan keyboard.KeyEvent
+
+	d := newDashboard()
+
+	if err = d.refresh(); err != nil {
+		return err
+	}
+
+	if keys, err = keyboard.GetKeys(10); err != nil {
+		return fmt.Errorf("Problem capturing keyboard input: %w", err)
+	}
+
+	defer keyboard.Close()
+
+	fmt.Print(enterFullScreen)
+	defer fmt.Print(leaveFullScreen)
+
+	/*
+	 * A signal quits the same way as 'q', so the screen is put back and
+	 * the timer keeps running
+	 */
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	d.render()
+
+	for {
+		select {
+		case event, ok := <-keys:
+			if !ok {
+				return nil
+			}
+
+			if event.Err != nil {
+				return fmt.Errorf("Problem reading key from keyboard: %w", event.Err)
+			}
+
+			if d.handleKey(event) {
+				return nil
+			}
+
+		case <-signals:
+			return nil
+
+		case <-ticker.C:
+			if time.Since(d.loadedAt) >= DashboardRefresh {
+				d.reload()
+			}
+		}
+
+		d.render()
+	}
+}
+
+func init() {
+	dashboardCmd := &cobra.Command{
+		Use:     "dashboard",
+		Aliases: []string{"dash", "d"},
+		Short:   `Opens a full screen dashboard of your timer and sessions`,
+		Long: `Opens a full screen dashboard showing the running timer, today's sessions, this week's
+hours by day, and what is waiting to be billed for each client. Keys start, stop, switch, and
+pause the timer, and edit its notes. Pausing records the time so far, and resuming starts a new
+session for the same project. Quitting leaves a running timer running.`,
+		Example: `mt dashboard`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDashboard()
+		},
+	}
+
+	rootCmd.AddCommand(dashboardCmd)
+}
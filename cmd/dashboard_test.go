@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adampresley/mytime/api/sessions"
+)
+
+func TestRunningWithin(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		started time.Duration
+		from    time.Duration
+		to      time.Duration
+		want    time.Duration
+	}{
+		{name: "all inside", started: 2 * time.Hour, from: 3 * time.Hour, to: -time.Hour, want: 2 * time.Hour},
+		{name: "started before the range", started: 5 * time.Hour, from: 2 * time.Hour, to: -time.Hour, want: 2 * time.Hour},
+		{name: "range ends before now", started: 5 * time.Hour, from: 6 * time.Hour, to: 4 * time.Hour, want: time.Hour},
+		{name: "range before the timer", started: 2 * time.Hour, from: 4 * time.Hour, to: 3 * time.Hour, want: 0},
+		{name: "range after now", started: 2 * time.Hour, from: -time.Hour, to: -2 * time.Hour, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDashboard()
+			d.active = &sessions.ActiveSession{StartTime: now.Add(-tt.started)}
+
+			got := d.runningWithin(now.Add(-tt.from), now.Add(-tt.to))
+
+			if diff := got - tt.want; diff < 0 || diff > time.Minute {
+				t.Errorf("runningWithin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := newDashboard().runningWithin(now.Add(-time.Hour), now); got != 0 {
+		t.Errorf("runningWithin() with no timer = %v, want 0", got)
+	}
+}
@@ -0,0 +1,584 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adampresley/mytime/api/apperrors"
+	"github.com/adampresley/mytime/api/categories"
+	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/projects"
+	"github.com/adampresley/mytime/api/sessions"
+	"github.com/spf13/cobra"
+)
+
+// dependents is everything that refers to a record about to be deleted.
+type dependents struct {
+	projects projects.ProjectCollection
+	sessions sessions.SessionCollection
+	active   bool
+}
+
+func (d dependents) empty() bool {
+	return len(d.projects) == 0 && len(d.sessions) == 0 && !d.active
+}
+
+// String lists the dependents, such as "2 projects (web, app) and 5
+// sessions, 3 of them invoiced".
+func (d dependents) String() string {
+	parts := make([]string, 0, 2)
+
+	if len(d.projects) > 0 {
+		codes := make([]string, 0, len(d.projects))
+
+		for _, p := range d.projects {
+			codes = append(codes, p.Code)
+		}
+
+		parts = append(parts, fmt.Sprintf("%s (%s)", countOf(len(d.projects), "project"), strings.Join(codes, ", ")))
+	}
+
+	if len(d.sessions) > 0 {
+		invoiced := 0
+
+		for _, s := range d.sessions {
+			if s.Invoiced {
+				invoiced++
+			}
+		}
+
+		description := countOf(len(d.sessions), "session")
+
+		if invoiced > 0 {
+			description += fmt.Sprintf(", %d of them invoiced", invoiced)
+		}
+
+		parts = append(parts, description)
+	}
+
+	return strings.Join(parts, " and ")
+}
+
+// deleteOptions are the flags shared by the delete commands.
+type deleteOptions struct {
+	reassignTo string
+	cascade    bool
+	yes        bool
+}
+
+func countOf(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// listAllProjects returns active and archived projects.
+func listAllProjects() (projects.ProjectCollection, error) {
+	var err error
+	var active, archived projects.ProjectCollection
+
+	if active, err = projectService.ListProjects(projects.ProjectSearch{}); err != nil {
+		return active, err
+	}
+
+	if archived, err = projectService.ListProjects(projects.ProjectSearch{Archived: true}); err != nil {
+		return active, err
+	}
+
+	return append(active, archived...), nil
+}
+
+/*
+ * findDependents gathers the projects and then the sessions that match.
+ * The running timer is checked too, as it becomes a session when it stops.
+ */
+func findDependents(matchProject func(p projects.Project) bool, matchSession func(s sessions.Session) bool) (dependents, error) {
+	var (
+		err              error
+		result           dependents
+		allProjects      projects.ProjectCollection
+		allSessions      sessions.SessionCollection
+		hasActiveSession bool
+		activeSession    sessions.ActiveSession
+	)
+
+	if matchProject != nil {
+		if allProjects, err = listAllProjects(); err != nil {
+			return result, err
+		}
+
+		for _, p := range allProjects {
+			if matchProject(p) {
+				result.projects = append(result.projects, p)
+			}
+		}
+	}
+
+	if allSessions, err = sessionService.ListAllSessions(); err != nil {
+		return result, err
+	}
+
+	for _, s := range allSessions {
+		if matchSession(s) {
+			result.sessions = append(result.sessions, s)
+		}
+	}
+
+	if hasActiveSession, err = sessionService.HasActiveSession(); err != nil {
+		return result, err
+	}
+
+	if hasActiveSession {
+		if activeSession, err = sessionService.GetActiveSession(); err != nil {
+			return result, err
+		}
+
+		result.active = matchSession(sessions.Session{
+			ClientID:   activeSession.ClientID,
+			ProjectID:  activeSession.ProjectID,
+			CategoryID: activeSession.CategoryID,
+		})
+	}
+
+	return result, nil
+}
+
+/*
+ * same reports whether two lookups found the same dependents, so a
+ * confirmation given for one still holds for the other.
+ */
+func (d dependents) same(other dependents) bool {
+	if len(d.projects) != len(other.projects) || len(d.sessions) != len(other.sessions) || d.active != other.active {
+		return false
+	}
+
+	for i := range d.projects {
+		if d.projects[i].ProjectID != other.projects[i].ProjectID {
+			return false
+		}
+	}
+
+	for i := range d.sessions {
+		if d.sessions[i].SessionID != other.sessions[i].SessionID || d.sessions[i].Invoiced != other.sessions[i].Invoiced {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+ * deletion is a record ready to be deleted. change moves or removes the
+ * dependents, as asked for by the options, and remove deletes the record
+ * itself.
+ */
+type deletion struct {
+	code   string
+	deps   dependents
+	change func() error
+	remove func() error
+}
+
+/*
+ * check refuses a deletion the dependents stand in the way of.
+ */
+func (d deletion) check(entity string, options deleteOptions) error {
+	if d.deps.active {
+		return apperrors.Conflict("%s %s is being timed. Stop the session first", strings.Title(entity), d.code)
+	}
+
+	if !d.deps.empty() && options.reassignTo == "" && !options.cascade {
+		return apperrors.Conflict("%s %s is used by %s. Use --reassign-to to move them, or --cascade to delete them too", strings.Title(entity), d.code, d.deps)
+	}
+
+	return nil
+}
+
+/*
+ * runDelete deletes a record and its dependents in one journaled
+ * operation. plan looks the record up and finds what refers to it. A
+ * cascade is confirmed before the data is locked, so other mt processes
+ * don't wait on the answer, and plan runs again under the lock. If the
+ * dependents changed while the question was open nothing is deleted.
+ */
+func runDelete(entity string, options deleteOptions, plan func() (deletion, error)) error {
+	var (
+		err       error
+		d         deletion
+		confirmed dependents
+		asked     bool
+	)
+
+	if options.cascade && !options.yes {
+		if d, err = plan(); err != nil {
+			return err
+		}
+
+		if err = d.check(entity, options); err != nil {
+			return err
+		}
+
+		if !d.deps.empty() {
+			if !confirm(fmt.Sprintf("Delete %s %s along with %s?", entity, d.code, d.deps)) {
+				fmt.Printf("Nothing was deleted.\n")
+				return nil
+			}
+
+			confirmed = d.deps
+			asked = true
+		}
+	}
+
+	unlock, err := lockData()
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	if d, err = plan(); err != nil {
+		return err
+	}
+
+	if err = d.check(entity, options); err != nil {
+		return err
+	}
+
+	if asked && !d.deps.same(confirmed) {
+		return apperrors.Conflict("What uses %s %s changed while you were deciding, so nothing was deleted. Please try again", entity, d.code)
+	}
+
+	if err = autoBackup("delete"); err != nil {
+		return err
+	}
+
+	if err = journal.Begin("delete " + entity); err != nil {
+		return err
+	}
+
+	if !d.deps.empty() {
+		if err = d.change(); err != nil {
+			journal.Rollback()
+			return fmt.Errorf("Problem with %s, so nothing was deleted: %w", d.deps, err)
+		}
+	}
+
+	if err = d.remove(); err != nil {
+		journal.Rollback()
+		return err
+	}
+
+	if err = journal.Commit(); err != nil {
+		return err
+	}
+
+	if !d.deps.empty() {
+		if options.cascade {
+			fmt.Printf("Deleted %s\n", d.deps)
+		} else {
+			fmt.Printf("Moved %s to %s %s\n", d.deps, entity, au.Green(options.reassignTo))
+		}
+	}
+
+	fmt.Printf("%s %s deleted\n", strings.Title(entity), au.Green(d.code))
+	return nil
+}
+
+func deleteSessions(list sessions.SessionCollection) error {
+	for _, s := range list {
+		if err := sessionService.DeleteSession(s.SessionID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteClient(code string, options deleteOptions) error {
+	return runDelete("client", options, func() (deletion, error) {
+		var (
+			err    error
+			client clients.Client
+			target clients.Client
+			deps   dependents
+		)
+
+		if client, err = clientService.GetClientByCode(code); err != nil {
+			return deletion{}, err
+		}
+
+		if options.reassignTo != "" {
+			if target, err = clientService.GetClientByCode(options.reassignTo); err != nil {
+				return deletion{}, err
+			}
+
+			if target.ClientID == client.ClientID {
+				return deletion{}, apperrors.Validation("Can't reassign client %s to itself", code)
+			}
+		}
+
+		/*
+		 * Sessions on the client's projects go with them, even ones left
+		 * with another client when a project moved
+		 */
+		clientProjects := make(map[int]bool)
+
+		deps, err = findDependents(
+			func(p projects.Project) bool {
+				if p.ClientID == client.ClientID {
+					clientProjects[p.ProjectID] = true
+				}
+
+				return p.ClientID == client.ClientID
+			},
+			func(s sessions.Session) bool { return s.ClientID == client.ClientID || clientProjects[s.ProjectID] },
+		)
+
+		if err != nil {
+			return deletion{}, err
+		}
+
+		change := func() error {
+			for _, s := range deps.sessions {
+				if options.cascade {
+					err = sessionService.DeleteSession(s.SessionID)
+				} else {
+					s.ClientID = target.ClientID
+					err = sessionService.UpdateSession(s)
+				}
+
+				if err != nil {
+					return err
+				}
+			}
+
+			for _, p := range deps.projects {
+				if options.cascade {
+					err = projectService.DeleteProject(p.Code)
+				} else {
+					p.ClientID = target.ClientID
+					err = projectService.UpdateProject(p)
+				}
+
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		remove := func() error {
+			return clientService.DeleteClient(client.Code)
+		}
+
+		return deletion{code: client.Code, deps: deps, change: change, remove: remove}, nil
+	})
+}
+
+func deleteProject(code string, options deleteOptions) error {
+	return runDelete("project", options, func() (deletion, error) {
+		var (
+			err     error
+			project projects.Project
+			target  projects.Project
+			deps    dependents
+		)
+
+		if project, err = projectService.GetProjectByCode(code); err != nil {
+			return deletion{}, err
+		}
+
+		if options.reassignTo != "" {
+			if target, err = projectService.GetProjectByCode(options.reassignTo); err != nil {
+				return deletion{}, err
+			}
+
+			if target.ProjectID == project.ProjectID {
+				return deletion{}, apperrors.Validation("Can't reassign project %s to itself", code)
+			}
+		}
+
+		deps, err = findDependents(nil, func(s sessions.Session) bool {
+			return s.ProjectID == project.ProjectID
+		})
+
+		if err != nil {
+			return deletion{}, err
+		}
+
+		change := func() error {
+			if options.cascade {
+				return deleteSessions(deps.sessions)
+			}
+
+			/*
+			 * A session's client follows its project
+			 */
+			for _, s := range deps.sessions {
+				s.ProjectID = target.ProjectID
+				s.ClientID = target.ClientID
+
+				if err = sessionService.UpdateSession(s); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		remove := func() error {
+			return projectService.DeleteProject(project.Code)
+		}
+
+		return deletion{code: project.Code, deps: deps, change: change, remove: remove}, nil
+	})
+}
+
+func deleteCategory(code string, options deleteOptions) error {
+	return runDelete("category", options, func() (deletion, error) {
+		var (
+			err      error
+			category categories.Category
+			target   categories.Category
+			deps     dependents
+		)
+
+		if category, err = categoryService.GetCategoryByCode(code); err != nil {
+			return deletion{}, err
+		}
+
+		if options.reassignTo != "" {
+			if target, err = categoryService.GetCategoryByCode(options.reassignTo); err != nil {
+				return deletion{}, err
+			}
+
+			if target.CategoryID == category.CategoryID {
+				return deletion{}, apperrors.Validation("Can't reassign category %s to itself", code)
+			}
+		}
+
+		deps, err = findDependents(
+			func(p projects.Project) bool { return p.DefaultCategoryID == category.CategoryID },
+			func(s sessions.Session) bool { return s.CategoryID == category.CategoryID },
+		)
+
+		if err != nil {
+			return deletion{}, err
+		}
+
+		/*
+		 * Deleting a category shouldn't take whole projects with it, so the
+		 * projects using it as their default have to be moved.
+		 */
+		if options.cascade && len(deps.projects) > 0 {
+			moving := dependents{projects: deps.projects}
+			return deletion{}, apperrors.Conflict("Category %s is the default category of %s. Use --reassign-to to move them", category.Code, moving)
+		}
+
+		change := func() error {
+			if options.cascade {
+				return deleteSessions(deps.sessions)
+			}
+
+			for _, s := range deps.sessions {
+				s.CategoryID = target.CategoryID
+
+				if err = sessionService.UpdateSession(s); err != nil {
+					return err
+				}
+			}
+
+			for _, p := range deps.projects {
+				p.DefaultCategoryID = target.CategoryID
+
+				if err = projectService.UpdateProject(p); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		remove := func() error {
+			return categoryService.DeleteCategory(category.Code)
+		}
+
+		return deletion{code: category.Code, deps: deps, change: change, remove: remove}, nil
+	})
+}
+
+func init() {
+	var options deleteOptions
+
+	/*
+	 * Checked with the arguments, so using both flags is a usage error
+	 */
+	deleteArgs := func(entity string) cobra.PositionalArgs {
+		return func(cmd *cobra.Command, args []string) error {
+			if options.reassignTo != "" && options.cascade {
+				return fmt.Errorf("Please use either --reassign-to or --cascade, not both")
+			}
+
+			return codeArgs(entity, "delete")(cmd, args)
+		}
+	}
+
+	deleteCmd := &cobra.Command{
+		Use:     "delete",
+		Aliases: []string{"del", "rm"},
+		Short:   `Deletes a client, project, or category`,
+		Long: `Deletes a client, project, or category. If sessions or projects use the record it isn't
+deleted, unless you move them to another record with --reassign-to, or delete them too with
+--cascade. To keep a record's history, archive it instead.`,
+	}
+
+	deleteClientCmd := &cobra.Command{
+		Use:     "client",
+		Aliases: []string{"c"},
+		Short:   `Deletes a client`,
+		Example: `mt delete client "clientCode"
+mt delete client "clientCode" --reassign-to "otherClientCode"
+mt delete client "clientCode" --cascade`,
+		Args: deleteArgs("client"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return deleteClient(args[0], options)
+		},
+	}
+
+	deleteProjectCmd := &cobra.Command{
+		Use:     "project",
+		Aliases: []string{"p", "proj"},
+		Short:   `Deletes a project`,
+		Example: `mt delete project "projectCode"
+mt delete project "projectCode" --reassign-to "otherProjectCode"
+mt delete project "projectCode" --cascade`,
+		Args: deleteArgs("project"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return deleteProject(args[0], options)
+		},
+	}
+
+	deleteCategoryCmd := &cobra.Command{
+		Use:     "category",
+		Aliases: []string{"cat"},
+		Short:   `Deletes a category`,
+		Example: `mt delete category "categoryCode"
+mt delete category "categoryCode" --reassign-to "otherCategoryCode"
+mt delete category "categoryCode" --cascade`,
+		Args: deleteArgs("category"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return deleteCategory(args[0], options)
+		},
+	}
+
+	deleteCmd.PersistentFlags().StringVarP(&options.reassignTo, "reassign-to", "", "", "Code of the record to move sessions and projects to")
+	deleteCmd.PersistentFlags().BoolVarP(&options.cascade, "cascade", "", false, "Also delete the sessions and projects that use the record")
+	deleteCmd.PersistentFlags().BoolVarP(&options.yes, "yes", "y", false, "Delete without asking for confirmation")
+
+	deleteCmd.AddCommand(deleteClientCmd, deleteProjectCmd, deleteCategoryCmd)
+	rootCmd.AddCommand(deleteCmd)
+}
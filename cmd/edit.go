@@ -1,17 +1,70 @@
 package cmd
 
 import (
-	"errors"
 	"fmt"
 
 	"github.com/adampresley/mytime/api/categories"
 	"github.com/adampresley/mytime/api/clients"
 	"github.com/adampresley/mytime/api/projects"
-	"github.com/adampresley/simdb"
-	. "github.com/logrusorgru/aurora"
+	"github.com/adampresley/mytime/api/sessions"
 	"github.com/spf13/cobra"
 )
 
+/*
+ * askMoveInvoiced asks whether moving a project to another client should
+ * move its invoiced sessions too. Those were billed to the old client, so
+ * the answer defaults to no, and nothing is asked when there are none.
+ */
+func askMoveInvoiced(projectCode, clientCode string) (bool, error) {
+	var (
+		err             error
+		project         projects.Project
+		client          clients.Client
+		projectSessions sessions.SessionCollection
+	)
+
+	if project, err = projectService.GetProjectByCode(projectCode); err != nil {
+		return false, err
+	}
+
+	if client, err = clientService.GetClientByCode(clientCode); err != nil {
+		return false, err
+	}
+
+	if client.ClientID == project.ClientID {
+		return false, nil
+	}
+
+	if projectSessions, err = sessionService.ListAllSessions(); err != nil {
+		return false, err
+	}
+
+	invoiced := 0
+
+	for _, s := range projectSessions {
+		if s.ProjectID == project.ProjectID && s.ClientID != client.ClientID && s.Invoiced {
+			invoiced++
+		}
+	}
+
+	if invoiced == 0 {
+		return false, nil
+	}
+
+	return confirm(fmt.Sprintf("This project has %s. Move them to the new client too?", countOf(invoiced, "invoiced session"))), nil
+}
+
+// anyChanged reports whether any of the named flags were given.
+func anyChanged(cmd *cobra.Command, names ...string) bool {
+	for _, name := range names {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func init() {
 	var (
 		name     string
@@ -19,6 +72,15 @@ func init() {
 		rate     float64
 		client   string
 		category string
+
+		address      string
+		contactName  string
+		contactEmail string
+		paymentTerms int
+		taxID        string
+		notes        string
+
+		moveInvoiced bool
 	)
 
 	editCmd := &cobra.Command{
@@ -31,33 +93,45 @@ func init() {
 		Use:     "client",
 		Aliases: []string{"c"},
 		Short:   `Edit a client record`,
-		Example: `mt edit client "test" --name "New Name" --code "New Code"`,
+		Example: `mt edit client "test" --name "New Name" --code "New Code"
+mt edit client "test" --address "456 Elm St, Springfield" --contact "John Doe" --email "john@test.com" --terms 15 --tax-id "US654321" --notes "New notes"
+mt edit client "test" --notes "" --tax-id ""`,
 		Args: func(cmd *cobra.Command, args []string) error {
-			if len(args) < 1 {
+			if len(args) < 1 && !canPick() {
 				return fmt.Errorf("Please provide the code for the client you wish to edit")
 			}
 
+			if paymentTerms < 0 {
+				return fmt.Errorf("Payment terms must be zero or more days")
+			}
+
 			return nil
 		},
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				err        error
 				clientCode string
 				client     clients.Client
 			)
 
-			if name == "" && code == "" {
-				return
+			if name == "" && code == "" && !anyChanged(cmd, "address", "contact", "email", "terms", "tax-id", "notes") {
+				return nil
+			}
+
+			if clientCode, err = pickClientCode(firstArg(args)); err != nil {
+				return err
+			}
+
+			unlock, err := lockData()
+
+			if err != nil {
+				return err
 			}
 
-			clientCode = args[0]
+			defer unlock()
 
 			if client, err = clientService.GetClientByCode(clientCode); err != nil {
-				if errors.Is(err, simdb.ErrZeroRecords) {
-					displayError(fmt.Sprintf("Client code %s not found", Green(clientCode)))
-				} else {
-					displayError(fmt.Sprintf("Cannot load client %s: %s", clientCode, err.Error()))
-				}
+				return err
 			}
 
 			if name != "" {
@@ -68,11 +142,39 @@ func init() {
 				client.Code = code
 			}
 
+			/*
+			 * These can be cleared, such as with --notes ""
+			 */
+			if cmd.Flags().Changed("address") {
+				client.Address = address
+			}
+
+			if cmd.Flags().Changed("contact") {
+				client.ContactName = contactName
+			}
+
+			if cmd.Flags().Changed("email") {
+				client.ContactEmail = contactEmail
+			}
+
+			if cmd.Flags().Changed("terms") {
+				client.PaymentTerms = paymentTerms
+			}
+
+			if cmd.Flags().Changed("tax-id") {
+				client.TaxID = taxID
+			}
+
+			if cmd.Flags().Changed("notes") {
+				client.Notes = notes
+			}
+
 			if err = clientService.UpdateClient(client); err != nil {
-				displayError(fmt.Sprintf("Problem updating client record: %s", err.Error()))
+				return fmt.Errorf("Problem updating client record: %w", err)
 			}
 
-			fmt.Printf("Client %s updated!\n", Green(clientCode))
+			fmt.Printf("Client %s updated!\n", au.Green(clientCode))
+			return nil
 		},
 	}
 
@@ -82,13 +184,13 @@ func init() {
 		Short:   `Edit a category record`,
 		Example: `mt edit category "test" --name "New Name" --code "New Code" --rate 10.00`,
 		Args: func(cmd *cobra.Command, args []string) error {
-			if len(args) < 1 {
+			if len(args) < 1 && !canPick() {
 				return fmt.Errorf("Please provide the code for the category you wish to edit")
 			}
 
 			return nil
 		},
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				err          error
 				categoryCode string
@@ -96,17 +198,23 @@ func init() {
 			)
 
 			if name == "" && code == "" && rate == -10.00 {
-				return
+				return nil
+			}
+
+			if categoryCode, err = pickCategoryCode(firstArg(args)); err != nil {
+				return err
 			}
 
-			categoryCode = args[0]
+			unlock, err := lockData()
+
+			if err != nil {
+				return err
+			}
+
+			defer unlock()
 
 			if category, err = categoryService.GetCategoryByCode(categoryCode); err != nil {
-				if errors.Is(err, simdb.ErrZeroRecords) {
-					displayError(fmt.Sprintf("Category code %s not found", Green(categoryCode)))
-				} else {
-					displayError(fmt.Sprintf("Cannot load category %s: %s", categoryCode, err.Error()))
-				}
+				return err
 			}
 
 			if name != "" {
@@ -122,10 +230,11 @@ func init() {
 			}
 
 			if err = categoryService.UpdateCategory(category); err != nil {
-				displayError(fmt.Sprintf("Problem updating category record: %s", err.Error()))
+				return fmt.Errorf("Problem updating category record: %w", err)
 			}
 
-			fmt.Printf("Client %s updated!\n", Green(categoryCode))
+			fmt.Printf("Client %s updated!\n", au.Green(categoryCode))
+			return nil
 		},
 	}
 
@@ -133,35 +242,65 @@ func init() {
 		Use:     "project",
 		Aliases: []string{"projects", "proj", "p"},
 		Short:   `Edit a project record`,
-		Example: `mt edit project "test" --name "New Name" --code "New Code" --client "New client" --category "New default category"`,
+		Long: `Edit a project record. When a project moves to another client its sessions move too. Invoiced
+sessions were billed to the old client, so you are asked about those, unless --move-invoiced is given.`,
+		Example: `mt edit project "test" --name "New Name" --code "New Code" --client "New client" --category "New default category"
+mt edit project "test" --client "New client" --move-invoiced=false`,
 		Args: func(cmd *cobra.Command, args []string) error {
-			if len(args) < 1 {
+			if len(args) < 1 && !canPick() {
 				return fmt.Errorf("Please provide the code for the project you wish to edit")
 			}
 
 			return nil
 		},
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				err         error
 				projectCode string
 				project     projects.Project
+				oldClientID int
 			)
 
 			if name == "" && code == "" && client == "" && category == "" {
-				return
+				return nil
 			}
 
-			projectCode = args[0]
+			if projectCode, err = pickProjectCode(firstArg(args)); err != nil {
+				return err
+			}
 
-			if project, err = projectService.GetProjectByCode(projectCode); err != nil {
-				if errors.Is(err, simdb.ErrZeroRecords) {
-					displayError(fmt.Sprintf("Project code %s not found", Green(projectCode)))
-				} else {
-					displayError(fmt.Sprintf("Cannot load project %s: %s", projectCode, err.Error()))
+			if category != "" {
+				if category, err = pickCategoryCode(category); err != nil {
+					return err
+				}
+			}
+
+			includeInvoiced := moveInvoiced
+
+			/*
+			 * Asked before the data is locked, so other mt processes
+			 * don't wait on the answer
+			 */
+			if client != "" && !cmd.Flags().Changed("move-invoiced") {
+				if includeInvoiced, err = askMoveInvoiced(projectCode, client); err != nil {
+					return err
 				}
 			}
 
+			unlock, err := lockData()
+
+			if err != nil {
+				return err
+			}
+
+			defer unlock()
+
+			if project, err = projectService.GetProjectByCode(projectCode); err != nil {
+				return err
+			}
+
+			oldClientID = project.ClientID
+
 			if name != "" {
 				project.Name = name
 			}
@@ -174,7 +313,7 @@ func init() {
 				var c clients.Client
 
 				if c, err = clientService.GetClientByCode(client); err != nil {
-					displayError(fmt.Sprintf("Client %s not found", client))
+					return err
 				}
 
 				project.ClientID = c.ClientID
@@ -184,22 +323,63 @@ func init() {
 				var c categories.Category
 
 				if c, err = categoryService.GetCategoryByCode(category); err != nil {
-					displayError(fmt.Sprintf("Category %s not found", category))
+					return err
 				}
 
 				project.DefaultCategoryID = c.CategoryID
 			}
 
+			if project.ClientID == oldClientID {
+				if err = projectService.UpdateProject(project); err != nil {
+					return fmt.Errorf("Problem updating project record: %w", err)
+				}
+
+				fmt.Printf("Project %s updated!\n", au.Green(projectCode))
+				return nil
+			}
+
+			/*
+			 * The project is moving to another client, so its sessions
+			 * have to follow, or reports by client and by project disagree.
+			 */
+			if err = autoBackup("move"); err != nil {
+				return err
+			}
+
+			if err = journal.Begin("move project"); err != nil {
+				return err
+			}
+
 			if err = projectService.UpdateProject(project); err != nil {
-				displayError(fmt.Sprintf("Problem updating project record: %s", err.Error()))
+				journal.Rollback()
+				return fmt.Errorf("Problem updating project record: %w", err)
+			}
+
+			moved, err := sessionService.MoveProjectSessions(project.ProjectID, project.ClientID, includeInvoiced)
+
+			if err != nil {
+				journal.Rollback()
+				return fmt.Errorf("Problem moving sessions, so the project was not changed: %w", err)
 			}
 
-			fmt.Printf("Project %s updated!\n", Green(projectCode))
+			if err = journal.Commit(); err != nil {
+				return err
+			}
+
+			fmt.Printf("Project %s updated!\n", au.Green(projectCode))
+			fmt.Printf("Moved %s to the new client\n", countOf(moved, "session"))
+			return nil
 		},
 	}
 
 	editClientCmd.Flags().StringVarP(&name, "name", "n", "", "New name for a client")
 	editClientCmd.Flags().StringVarP(&code, "code", "c", "", "New code for a client")
+	editClientCmd.Flags().StringVarP(&address, "address", "", "", "New billing address for a client")
+	editClientCmd.Flags().StringVarP(&contactName, "contact", "", "", "New billing contact name for a client")
+	editClientCmd.Flags().StringVarP(&contactEmail, "email", "", "", "New billing contact email for a client")
+	editClientCmd.Flags().IntVarP(&paymentTerms, "terms", "", 0, "New payment terms in days for a client, or 0 for due on receipt")
+	editClientCmd.Flags().StringVarP(&taxID, "tax-id", "", "", "New tax or VAT ID for a client")
+	editClientCmd.Flags().StringVarP(&notes, "notes", "", "", "New notes for a client")
 	editCategoryCmd.Flags().StringVarP(&name, "name", "n", "", "New name for a category")
 	editCategoryCmd.Flags().StringVarP(&code, "code", "c", "", "New code for a category")
 	editCategoryCmd.Flags().Float64VarP(&rate, "rate", "r", -10.00, "New rate for a category")
@@ -207,6 +387,7 @@ func init() {
 	editProjectCmd.Flags().StringVarP(&code, "code", "c", "", "New code for a project")
 	editProjectCmd.Flags().StringVarP(&client, "client", "", "", "New client code for a project")
 	editProjectCmd.Flags().StringVarP(&category, "category", "", "", "New default category for a project")
+	editProjectCmd.Flags().BoolVarP(&moveInvoiced, "move-invoiced", "", false, "When moving to a new client, also move invoiced sessions, without asking")
 
 	editCmd.AddCommand(editClientCmd, editCategoryCmd, editProjectCmd)
 	rootCmd.AddCommand(editCmd)
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/adampresley/mytime/api/apperrors"
+	"github.com/adampresley/mytime/api/profiles"
+	"github.com/adampresley/mytime/api/settings"
+	"github.com/adampresley/mytime/api/storage"
+)
+
+// Exit codes. These are documented in the README, so scripts can rely
+// on them. Don't renumber them.
+const (
+	ExitOK            int = 0
+	ExitError         int = 1
+	ExitUsage         int = 2
+	ExitNotFound      int = 3
+	ExitDuplicateCode int = 4
+	ExitConflict      int = 5
+	ExitValidation    int = 6
+	ExitLocked        int = 7
+	ExitCorrupt       int = 8
+)
+
+var exitCodeNames = map[int]string{
+	ExitError:         "error",
+	ExitUsage:         "usage",
+	ExitNotFound:      "not_found",
+	ExitDuplicateCode: "duplicate_code",
+	ExitConflict:      "conflict",
+	ExitValidation:    "validation",
+	ExitLocked:        "locked",
+	ExitCorrupt:       "corrupt",
+}
+
+// exitCode picks the exit code for an error returned by a command.
+func exitCode(err error) int {
+	var (
+		notFound      *apperrors.NotFoundError
+		duplicateCode *apperrors.DuplicateCodeError
+		conflict      *apperrors.ConflictError
+		validation    *apperrors.ValidationError
+	)
+
+	switch {
+	case err == nil:
+		return ExitOK
+
+	case errors.Is(err, storage.ErrCorrupt):
+		return ExitCorrupt
+
+	case errors.Is(err, storage.ErrLocked), errors.Is(err, storage.ErrOperationInProgress):
+		return ExitLocked
+
+	case errors.As(err, &notFound), errors.Is(err, storage.ErrNotFound), errors.Is(err, profiles.ErrProfileNotFound):
+		return ExitNotFound
+
+	case errors.As(err, &duplicateCode), errors.Is(err, profiles.ErrProfileExists):
+		return ExitDuplicateCode
+
+	case errors.As(err, &conflict):
+		return ExitConflict
+
+	case errors.As(err, &validation), errors.Is(err, settings.ErrUnknownSetting):
+		return ExitValidation
+	}
+
+	return ExitError
+}
+
+// printError reports an error on stderr. With machine readable output the
+// error is written as JSON, so it never mixes with results.
+func printError(err error, code int) {
+	if outputFormat != OutputTable {
+		b, _ := json.Marshal(struct {
+			Error    string `json:"error"`
+			Code     string `json:"code"`
+			ExitCode int    `json:"exitCode"`
+		}{
+			Error:    ansiPattern.ReplaceAllString(err.Error(), ""),
+			Code:     exitCodeNames[code],
+			ExitCode: code,
+		})
+
+		fmt.Fprintln(os.Stderr, string(b))
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "%s %v\n", au.Red("ERROR:"), err)
+}
@@ -0,0 +1,318 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/adampresley/mytime/api/exports"
+	"github.com/adampresley/mytime/api/sessions"
+	"github.com/spf13/cobra"
+)
+
+// sessionFilters holds the flags used to narrow down which sessions a
+// command works with. They mirror the filters on sessions.SessionSearch.
+type sessionFilters struct {
+	categoryCode string
+	clientCode   string
+	projectCode  string
+	paid         bool
+	invoiced     bool
+	sessionID    int
+	sessionIDs   []int
+	week         bool
+}
+
+func (f *sessionFilters) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.categoryCode, "category", "a", "", "Filter sessions by category code")
+	cmd.Flags().StringVarP(&f.clientCode, "client", "c", "", "Filter sessions by client code")
+	cmd.Flags().StringVarP(&f.projectCode, "project", "p", "", "Filter sessions by project code")
+	cmd.Flags().BoolVarP(&f.paid, "paid", "m", false, "Filter sessions for those that are paid")
+	cmd.Flags().BoolVarP(&f.invoiced, "invoiced", "i", false, "Filter sessions for those that are invoiced")
+	cmd.Flags().IntVarP(&f.sessionID, "id", "", 0, "Filter sessions by ID")
+	cmd.Flags().IntSliceVarP(&f.sessionIDs, "ids", "", []int{}, "Filter sessions by a list of IDs")
+	cmd.Flags().BoolVarP(&f.week, "week", "w", false, "Filter sessions for those started this week")
+}
+
+func (f *sessionFilters) search() sessions.SessionSearch {
+	result := sessions.SessionSearch{
+		CategoryCode: f.categoryCode,
+		ClientCode:   f.clientCode,
+		Invoiced:     f.invoiced,
+		Paid:         f.paid,
+		ProjectCode:  f.projectCode,
+		SessionID:    f.sessionID,
+		SessionIDs:   f.sessionIDs,
+	}
+
+	if f.week {
+		result.From = appSettings.StartOfWeek(time.Now())
+		result.To = result.From.AddDate(0, 0, 7)
+	}
+
+	return result
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// openExportOutput returns where an export should be written. An empty
+// file name means stdout.
+func openExportOutput(fileName string, appendToFile bool) (io.WriteCloser, error) {
+	if fileName == "" {
+		return nopWriteCloser{Writer: os.Stdout}, nil
+	}
+
+	if appendToFile {
+		return os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	}
+
+	return os.Create(fileName)
+}
+
+func init() {
+	var (
+		format        string
+		outputFile    string
+		filters       sessionFilters
+		icsOutputFile string
+		icsFilters    sessionFilters
+
+		plainTextOutputFile string
+		plainTextAppend     bool
+		plainTextFilters    sessionFilters
+
+		timewOutputFile     string
+		timewDir            string
+		timewProjectPrefix  string
+		timewCategoryPrefix string
+		timewProjectTags    map[string]string
+		timewCategoryTags   map[string]string
+		timewFilters        sessionFilters
+	)
+
+	exportCmd := &cobra.Command{
+		Use:     "export",
+		Aliases: []string{"x"},
+		Short:   `Exports sessions to other formats`,
+	}
+
+	exportSessionsCmd := &cobra.Command{
+		Use:     "sessions",
+		Aliases: []string{"s", "session"},
+		Short:   `Exports sessions as CSV, JSON, or JSON lines`,
+		Example: `mt export sessions --format csv
+mt export sessions --format json --client "clientCode"
+mt export sessions --format jsonl --invoiced --out sessions.jsonl`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if format != "csv" && format != "json" && format != "jsonl" {
+				return fmt.Errorf("Invalid format '%s'. Must be one of csv, json, or jsonl", format)
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				err     error
+				records exports.SessionRecordCollection
+				out     io.WriteCloser
+			)
+
+			if records, err = exportService.GetSessionRecords(filters.search()); err != nil {
+				return err
+			}
+
+			if out, err = openExportOutput(outputFile, false); err != nil {
+				return fmt.Errorf("Problem opening output file: %w", err)
+			}
+
+			defer out.Close()
+
+			switch format {
+			case "csv":
+				err = exportService.WriteCSV(out, records)
+
+			case "json":
+				err = exportService.WriteJSON(out, records)
+
+			case "jsonl":
+				err = exportService.WriteJSONLines(out, records)
+			}
+
+			if err != nil {
+				return fmt.Errorf("Problem exporting sessions: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	exportICSCmd := &cobra.Command{
+		Use:     "ics",
+		Aliases: []string{"ical", "calendar"},
+		Short:   `Exports sessions as an iCalendar (.ics) file`,
+		Long: `Exports sessions as an iCalendar (.ics) file, one event per session. The event summary holds
+the project code, project, and client, and the description holds the session notes.`,
+		Example: `mt export ics --out sessions.ics
+mt export ics --client "clientCode" --invoiced --out invoiced.ics`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				err     error
+				records exports.SessionRecordCollection
+				out     io.WriteCloser
+			)
+
+			if records, err = exportService.GetSessionRecords(icsFilters.search()); err != nil {
+				return err
+			}
+
+			if out, err = openExportOutput(icsOutputFile, false); err != nil {
+				return fmt.Errorf("Problem opening output file: %w", err)
+			}
+
+			defer out.Close()
+
+			if err = exportService.WriteICS(out, records); err != nil {
+				return fmt.Errorf("Problem exporting sessions: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	exportPlainText := func(write func(w io.Writer, records exports.SessionRecordCollection) error) error {
+		var (
+			err     error
+			records exports.SessionRecordCollection
+			out     io.WriteCloser
+		)
+
+		if records, err = exportService.GetSessionRecords(plainTextFilters.search()); err != nil {
+			return err
+		}
+
+		if out, err = openExportOutput(plainTextOutputFile, plainTextAppend); err != nil {
+			return fmt.Errorf("Problem opening output file: %w", err)
+		}
+
+		defer out.Close()
+
+		if err = write(out, records); err != nil {
+			return fmt.Errorf("Problem exporting sessions: %w", err)
+		}
+
+		return nil
+	}
+
+	exportTimeclockCmd := &cobra.Command{
+		Use:     "timeclock",
+		Aliases: []string{"ledger", "hledger"},
+		Short:   `Exports sessions as ledger/hledger timeclock entries`,
+		Long: `Exports sessions as ledger/hledger timeclock entries. Each session becomes an "i" and "o"
+line pair against the account "clientCode:projectCode", with the session notes as the
+description. Use --append to add to an existing timeclock file.`,
+		Example: `mt export timeclock --out ~/finance/time.timeclock --append
+mt export timeclock --client "clientCode" | hledger -f - balance`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return exportPlainText(exportService.WriteTimeclock)
+		},
+	}
+
+	exportOrgCmd := &cobra.Command{
+		Use:     "org",
+		Aliases: []string{"org-mode"},
+		Short:   `Exports sessions as Org-mode CLOCK entries`,
+		Long: `Exports sessions as Org-mode CLOCK entries, grouped under a heading for each client and
+project. Each session gets its own heading, named after its notes. Use --append to add to
+an existing Org file.`,
+		Example: `mt export org --out ~/org/time.org --append
+mt export org --project "projectCode"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return exportPlainText(exportService.WriteOrg)
+		},
+	}
+
+	exportTimewarriorCmd := &cobra.Command{
+		Use:     "timewarrior",
+		Aliases: []string{"timew"},
+		Short:   `Exports sessions as Timewarrior intervals`,
+		Long: `Exports sessions as Timewarrior intervals. Each session is tagged with its project code and
+category code, and its notes become the annotation. Use --project-prefix and --category-prefix
+to tag them as, say, "project:web", or --map and --tag-map to pick a tag for a particular code.
+Both take tag=code, the same way round as mt import timewarrior, so --map website=web tags
+sessions on the project web with "website". With --timew-dir the intervals are added to the
+monthly data files in that Timewarrior data directory, skipping any that are already there.`,
+		Example: `mt export timewarrior > intervals.data
+mt export timewarrior --timew-dir ~/.timewarrior/data
+mt export timewarrior --project-prefix "project:" --map "website=web" --timew-dir ~/.timewarrior/data`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				err     error
+				records exports.SessionRecordCollection
+				out     io.WriteCloser
+				added   int
+			)
+
+			options := exports.TimewarriorOptions{
+				ProjectPrefix:  timewProjectPrefix,
+				CategoryPrefix: timewCategoryPrefix,
+				ProjectTags:    timewProjectTags,
+				CategoryTags:   timewCategoryTags,
+			}
+
+			if records, err = exportService.GetSessionRecords(timewFilters.search()); err != nil {
+				return err
+			}
+
+			if timewDir != "" {
+				if added, err = exportService.WriteTimewarriorDataDir(timewDir, records, options); err != nil {
+					return fmt.Errorf("Problem exporting sessions: %w", err)
+				}
+
+				fmt.Printf("Added %d intervals to %s\n", added, timewDir)
+				return nil
+			}
+
+			if out, err = openExportOutput(timewOutputFile, false); err != nil {
+				return fmt.Errorf("Problem opening output file: %w", err)
+			}
+
+			defer out.Close()
+
+			if err = exportService.WriteTimewarrior(out, records, options); err != nil {
+				return fmt.Errorf("Problem exporting sessions: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	exportSessionsCmd.Flags().StringVarP(&format, "format", "f", "csv", "Export format. One of csv, json, or jsonl")
+	exportSessionsCmd.Flags().StringVarP(&outputFile, "out", "o", "", "File to write to. Defaults to stdout")
+	filters.addFlags(exportSessionsCmd)
+	exportICSCmd.Flags().StringVarP(&icsOutputFile, "out", "o", "", "File to write to. Defaults to stdout")
+	icsFilters.addFlags(exportICSCmd)
+
+	for _, c := range []*cobra.Command{exportTimeclockCmd, exportOrgCmd} {
+		c.Flags().StringVarP(&plainTextOutputFile, "out", "o", "", "File to write to. Defaults to stdout")
+		c.Flags().BoolVarP(&plainTextAppend, "append", "", false, "Append to the output file instead of replacing it")
+		plainTextFilters.addFlags(c)
+	}
+
+	exportTimewarriorCmd.Flags().StringVarP(&timewOutputFile, "out", "o", "", "File to write to. Defaults to stdout")
+	exportTimewarriorCmd.Flags().StringVarP(&timewDir, "timew-dir", "", "", "Timewarrior data directory, such as ~/.timewarrior/data, to add intervals to")
+	exportTimewarriorCmd.Flags().StringVarP(&timewProjectPrefix, "project-prefix", "", "", "Prefix for project tags. E.g. project:")
+	exportTimewarriorCmd.Flags().StringVarP(&timewCategoryPrefix, "category-prefix", "", "", "Prefix for category tags. E.g. category:")
+	exportTimewarriorCmd.Flags().StringToStringVarP(&timewProjectTags, "map", "", map[string]string{}, "Use a specific tag for a project code, given as tag=code. E.g. --map website=web")
+	exportTimewarriorCmd.Flags().StringToStringVarP(&timewCategoryTags, "tag-map", "", map[string]string{}, "Use a specific tag for a category code, given as tag=code. E.g. --tag-map meeting=mtg")
+	timewFilters.addFlags(exportTimewarriorCmd)
+
+	exportCmd.AddCommand(exportSessionsCmd, exportICSCmd, exportTimeclockCmd, exportOrgCmd, exportTimewarriorCmd)
+	rootCmd.AddCommand(exportCmd)
+}
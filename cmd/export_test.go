@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adampresley/mytime/api/sessions"
+)
+
+// tempDir makes a directory that is removed when the test ends.
+func tempDir(t *testing.T, name string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", name)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+/*
+ * runCommand runs mt with args, with profiles kept in a directory of
+ * their own so the user's data is never touched.
+ */
+func runCommand(t *testing.T, args ...string) error {
+	t.Helper()
+
+	home := os.Getenv(HomeEnvironmentVariable)
+	os.Setenv(HomeEnvironmentVariable, tempDir(t, "mytime-home"))
+	defer os.Setenv(HomeEnvironmentVariable, home)
+
+	rootCmd.SetArgs(args)
+	_, err := rootCmd.ExecuteC()
+	return err
+}
+
+func TestExportTimewarriorDirectories(t *testing.T) {
+	dataDir := tempDir(t, "mytime-data")
+	timewDir := tempDir(t, "mytime-timew")
+
+	for _, args := range [][]string{
+		{"--data-dir", dataDir, "create", "client", "Acme", "acme"},
+		{"--data-dir", dataDir, "create", "category", "Development", "dev", "100"},
+		{"--data-dir", dataDir, "create", "project", "Web", "web", "acme", "dev"},
+	} {
+		if err := runCommand(t, args...); err != nil {
+			t.Fatalf("mt %s: %v", strings.Join(args, " "), err)
+		}
+	}
+
+	start := time.Date(2020, time.January, 7, 9, 0, 0, 0, time.UTC)
+
+	if _, err := sessionService.CreateSession(sessions.Session{ClientID: 1, ProjectID: 1, CategoryID: 1, StartDateTime: start, EndDateTime: start.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runCommand(t, "--data-dir", dataDir, "export", "timewarrior", "--timew-dir", timewDir); err != nil {
+		t.Fatalf("mt export timewarrior: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(timewDir, "2020-01.data"))
+
+	if err != nil || !strings.Contains(string(b), "inc 20200107T090000Z - 20200107T100000Z # web dev") {
+		t.Errorf("Timewarrior data = %q, %v, want the session", b, err)
+	}
+
+	if written, _ := filepath.Glob(filepath.Join(dataDir, "*.data")); len(written) > 0 {
+		t.Errorf("Timewarrior data written to the My Time data directory: %v", written)
+	}
+}
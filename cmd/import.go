@@ -0,0 +1,454 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adampresley/mytime/api/imports"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// importDateFormat is how dates are given to import flags.
+const importDateFormat string = "2006-01-02"
+
+/*
+ * importFlags are the options shared by every import command.
+ */
+type importFlags struct {
+	dryRun        bool
+	allowOverlaps bool
+	clientCode    string
+	categoryCode  string
+	projectMap    map[string]string
+	categoryMap   map[string]string
+}
+
+func (f *importFlags) addFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&f.dryRun, "dry-run", "n", false, "Show what would be imported, and any conflicts, without saving anything")
+	cmd.Flags().BoolVarP(&f.allowOverlaps, "allow-overlaps", "", false, "Import sessions even when they overlap sessions already recorded")
+	cmd.Flags().StringVarP(&f.clientCode, "client", "", "", "Client code to create missing projects under")
+	cmd.Flags().StringVarP(&f.categoryCode, "category", "", "", "Category code to use when nothing else provides one. Also the default category of created projects")
+	cmd.Flags().StringToStringVarP(&f.projectMap, "map", "", map[string]string{}, "Map an imported project to a project code. E.g. --map \"Old Project=newcode\"")
+	cmd.Flags().StringToStringVarP(&f.categoryMap, "tag-map", "", map[string]string{}, "Map an imported tag or category to a category code. E.g. --tag-map meeting=mtg")
+}
+
+func (f *importFlags) options() imports.ImportOptions {
+	return imports.ImportOptions{
+		AllowOverlaps:  f.allowOverlaps,
+		CategoryCode:   f.categoryCode,
+		CategoryMap:    f.categoryMap,
+		ClientCode:     f.clientCode,
+		CreateProjects: true,
+		ProjectMap:     f.projectMap,
+	}
+}
+
+func displayImportPlan(plan imports.ImportPlan) {
+	tableData := make([][]string, 0, len(plan.Sessions))
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Source", "Date", "Time", "Client", "Project", "Category", "Notes", "Status"})
+	table.SetBorder(false)
+
+	for _, s := range plan.Sessions {
+		project := s.ProjectCode
+		client := s.ClientCode
+		status := s.Status
+
+		if s.NewProject {
+			project += " (new)"
+		}
+
+		if s.NewClient {
+			client += " (new)"
+		}
+
+		if s.Reason != "" {
+			status = fmt.Sprintf("%s: %s", s.Status, s.Reason)
+		}
+
+		t := fmt.Sprintf("%s - %s", appSettings.FormatTimeWithSeconds(s.Entry.Start), appSettings.FormatTimeWithSeconds(s.Entry.End))
+		tableData = append(tableData, []string{s.Entry.Source, appSettings.FormatDate(s.Entry.Start), t, client, project, s.CategoryCode, s.Session.Notes, status})
+	}
+
+	table.AppendBulk(tableData)
+	table.Render()
+
+	fmt.Printf("\n")
+
+	for _, c := range plan.NewClients {
+		fmt.Printf("New client %s (%s)\n", au.Green(c.Name), c.Code)
+	}
+
+	for _, p := range plan.NewProjects {
+		fmt.Printf("New project %s (%s) for client %s\n", au.Green(p.Name), p.Code, p.ClientCode)
+	}
+
+	fmt.Printf("%d new, %d duplicates, %d overlapping, %d unresolved\n",
+		au.Green(plan.Count(imports.StatusNew)),
+		au.Cyan(plan.Count(imports.StatusDuplicate)),
+		au.Yellow(plan.Count(imports.StatusOverlap)),
+		au.Red(plan.Count(imports.StatusUnresolved)),
+	)
+}
+
+/*
+ * runImport shows the plan and, unless this is a dry run, applies it.
+ */
+func runImport(entries imports.ImportEntryCollection, options imports.ImportOptions, dryRun bool) error {
+	var (
+		err    error
+		plan   imports.ImportPlan
+		result imports.ImportResult
+	)
+
+	unlock, err := lockData()
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	if plan, err = importService.Plan(entries, options); err != nil {
+		return fmt.Errorf("Problem planning import: %w", err)
+	}
+
+	displayImportPlan(plan)
+
+	if dryRun {
+		fmt.Printf("\nDry run. Nothing was imported.\n")
+		return nil
+	}
+
+	if err = autoBackup("import"); err != nil {
+		return err
+	}
+
+	if result, err = importService.Apply(plan); err != nil {
+		return fmt.Errorf("Problem importing sessions: %w", err)
+	}
+
+	fmt.Printf("\nImported %d sessions, skipped %d. Created %d clients and %d projects.\n", au.Green(result.SessionsCreated), result.SessionsSkipped, result.ClientsCreated, result.ProjectsCreated)
+	return nil
+}
+
+func init() {
+	var (
+		watsonFlags importFlags
+		csvFlags    importFlags
+
+		preset         string
+		columns        map[string]string
+		dateFormat     string
+		timeFormat     string
+		dateTimeFormat string
+		delimiter      string
+		create         bool
+
+		icsFlags   importFlags
+		icsPattern string
+		icsFrom    string
+		icsTo      string
+
+		timewFlags          importFlags
+		timewProjectPrefix  string
+		timewCategoryPrefix string
+	)
+
+	importCmd := &cobra.Command{
+		Use:     "import",
+		Aliases: []string{"imp"},
+		Short:   `Imports sessions from other time tracking tools`,
+	}
+
+	importWatsonCmd := &cobra.Command{
+		Use:   "watson",
+		Short: `Imports sessions from a Watson frames file`,
+		Long: `Imports sessions from a Watson frames file. Each Watson project is matched to a project by
+code or name, and created if it doesn't exist. Tags that match a category code or name set the
+session's category. All other tags are added to the session's notes.`,
+		Example: `mt import watson ~/.config/watson/frames --dry-run
+mt import watson ~/.config/watson/frames --client "clientCode" --category "dev"
+mt import watson ~/.config/watson/frames --map "website=web" --tag-map "meeting=mtg"`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("Please provide the path to the Watson frames file")
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				err     error
+				f       *os.File
+				entries imports.ImportEntryCollection
+			)
+
+			if f, err = os.Open(args[0]); err != nil {
+				return fmt.Errorf("Problem opening Watson frames file: %w", err)
+			}
+
+			defer f.Close()
+
+			if entries, err = importService.ReadWatsonFrames(f); err != nil {
+				return err
+			}
+
+			return runImport(entries, watsonFlags.options(), watsonFlags.dryRun)
+		},
+	}
+
+	importCSVCmd := &cobra.Command{
+		Use:   "csv",
+		Short: `Imports sessions from a CSV timesheet, such as a Toggl or Clockify export`,
+		Long: `Imports sessions from a CSV timesheet. Use --preset for exports from Toggl or Clockify, or
+map your own columns with --column. Columns that can be mapped are client, project, category,
+notes, tags, start, end, start-date, start-time, end-date, end-time, and duration. Sessions
+already recorded with the same start, end, and project are skipped, so importing the same
+file twice is safe.`,
+		Example: `mt import csv toggl.csv --preset toggl --dry-run
+mt import csv clockify.csv --preset clockify --create
+mt import csv hours.csv --column project=Job --column start=From --column end=To --datetime-format "2006-01-02 15:04"
+mt import csv hours.csv --column project=Job --column start-date=Day --column start-time=In --column duration=Hours --client "clientCode"`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("Please provide the path to the CSV file")
+			}
+
+			if _, ok := imports.CSVPresets[preset]; !ok {
+				return fmt.Errorf("Unknown preset '%s'. Must be one of generic, toggl, or clockify", preset)
+			}
+
+			if len([]rune(delimiter)) > 1 {
+				return fmt.Errorf("The delimiter must be a single character")
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				err     error
+				f       *os.File
+				entries imports.ImportEntryCollection
+			)
+
+			mapping := imports.CSVPresets[preset]
+
+			for field, column := range columns {
+				if err = mapping.SetColumn(field, column); err != nil {
+					return err
+				}
+			}
+
+			if dateFormat != "" {
+				mapping.DateFormat = dateFormat
+			}
+
+			if timeFormat != "" {
+				mapping.TimeFormat = timeFormat
+			}
+
+			if dateTimeFormat != "" {
+				mapping.DateTimeFormat = dateTimeFormat
+			}
+
+			if delimiter != "" {
+				mapping.Delimiter = []rune(delimiter)[0]
+			}
+
+			if f, err = os.Open(args[0]); err != nil {
+				return fmt.Errorf("Problem opening CSV file: %w", err)
+			}
+
+			defer f.Close()
+
+			if entries, err = importService.ReadCSV(f, mapping); err != nil {
+				return err
+			}
+
+			options := csvFlags.options()
+			options.CreateClients = create
+			options.CreateProjects = create
+
+			return runImport(entries, options, csvFlags.dryRun)
+		},
+	}
+
+	importICSCmd := &cobra.Command{
+		Use:     "ics",
+		Aliases: []string{"ical", "calendar"},
+		Short:   `Imports calendar events from an iCalendar (.ics) file as sessions`,
+		Long: `Imports calendar events from an iCalendar (.ics) file as sessions. Only events whose summary
+matches the pattern are imported. The pattern's first capture group must be the project code.
+By default this is a code in square brackets at the start of the summary, such as
+"[web] Weekly sync". The rest of the summary becomes the session notes, and event categories
+that match a category set the session's category. Files written by "mt export ics" keep their
+notes. Recurring events import every occurrence between --from and --to, which defaults to
+now, less excluded and cancelled ones, and with any changes made to single occurrences.
+All-day events are skipped.`,
+		Example: `mt import ics calendar.ics --dry-run
+mt import ics calendar.ics --pattern "^mt:(\w+)" --category "mtg"
+mt import ics calendar.ics --from 2020-06-01 --to 2020-07-01`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			if len(args) < 1 {
+				return fmt.Errorf("Please provide the path to the .ics file")
+			}
+
+			if icsPattern != "" {
+				if _, err = regexp.Compile(icsPattern); err != nil {
+					return fmt.Errorf("Invalid pattern: %s", err.Error())
+				}
+			}
+
+			for _, date := range []string{icsFrom, icsTo} {
+				if date != "" {
+					if _, err = time.ParseInLocation(importDateFormat, date, time.Local); err != nil {
+						return fmt.Errorf("Please give dates as YYYY-MM-DD, such as 2020-06-01")
+					}
+				}
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				err     error
+				f       *os.File
+				entries imports.ImportEntryCollection
+			)
+
+			options := imports.ICSOptions{Pattern: imports.DefaultICSPattern}
+
+			if icsPattern != "" {
+				options.Pattern = regexp.MustCompile(icsPattern)
+			}
+
+			if icsFrom != "" {
+				options.From, _ = time.ParseInLocation(importDateFormat, icsFrom, time.Local)
+			}
+
+			if icsTo != "" {
+				options.To, _ = time.ParseInLocation(importDateFormat, icsTo, time.Local)
+			}
+
+			if f, err = os.Open(args[0]); err != nil {
+				return fmt.Errorf("Problem opening calendar file: %w", err)
+			}
+
+			defer f.Close()
+
+			if entries, err = importService.ReadICS(f, options); err != nil {
+				return err
+			}
+
+			importOptions := icsFlags.options()
+			importOptions.CreateProjects = false
+
+			return runImport(entries, importOptions, icsFlags.dryRun)
+		},
+	}
+
+	importTimewarriorCmd := &cobra.Command{
+		Use:     "timewarrior",
+		Aliases: []string{"timew"},
+		Short:   `Imports sessions from Timewarrior`,
+		Long: `Imports sessions from Timewarrior. Give a data file, a data directory (every .data file in it
+is read), or the JSON output of "timew export". Defaults to ~/.timewarrior/data. The first tag
+that is a project code (or is mapped with --map) picks the project, and tags matching
+category codes pick the category. Annotations become the session notes. Use --project-prefix
+and --category-prefix if your tags look like "project:web". --map and --tag-map take tag=code,
+the same way round as mt export timewarrior, so --map website=web puts sessions tagged
+"website" on the project web.`,
+		Example: `mt import timewarrior --dry-run
+mt import timewarrior ~/.timewarrior/data/2020-01.data --client "clientCode"
+timew export > intervals.json && mt import timewarrior intervals.json --project-prefix "project:"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				err     error
+				path    string
+				homeDir string
+				info    os.FileInfo
+				entries imports.ImportEntryCollection
+			)
+
+			if len(args) > 0 {
+				path = args[0]
+			} else {
+				if homeDir, err = os.UserHomeDir(); err != nil {
+					return fmt.Errorf("Unable to find user's home directory: %w", err)
+				}
+
+				path = filepath.Join(homeDir, ".timewarrior", "data")
+			}
+
+			if info, err = os.Stat(path); err != nil {
+				return fmt.Errorf("Problem opening Timewarrior data: %w", err)
+			}
+
+			fileNames := []string{path}
+
+			if info.IsDir() {
+				if fileNames, err = filepath.Glob(filepath.Join(path, "*.data")); err != nil {
+					return fmt.Errorf("Problem listing Timewarrior data files: %w", err)
+				}
+
+				sort.Strings(fileNames)
+			}
+
+			readers := make([]io.Reader, 0, len(fileNames))
+
+			for _, fileName := range fileNames {
+				var f *os.File
+
+				if f, err = os.Open(fileName); err != nil {
+					return fmt.Errorf("Problem opening %s: %w", fileName, err)
+				}
+
+				defer f.Close()
+				readers = append(readers, f, strings.NewReader("\n"))
+			}
+
+			options := imports.TimewarriorOptions{
+				ProjectPrefix:  timewProjectPrefix,
+				CategoryPrefix: timewCategoryPrefix,
+				ProjectMap:     timewFlags.projectMap,
+			}
+
+			if entries, err = importService.ReadTimewarrior(io.MultiReader(readers...), options); err != nil {
+				return err
+			}
+
+			return runImport(entries, timewFlags.options(), timewFlags.dryRun)
+		},
+	}
+
+	watsonFlags.addFlags(importWatsonCmd)
+	timewFlags.addFlags(importTimewarriorCmd)
+	importTimewarriorCmd.Flags().StringVarP(&timewProjectPrefix, "project-prefix", "", "", "Prefix of tags that name a project code. E.g. project:")
+	importTimewarriorCmd.Flags().StringVarP(&timewCategoryPrefix, "category-prefix", "", "", "Prefix of tags that name a category code. E.g. category:")
+	icsFlags.addFlags(importICSCmd)
+	importICSCmd.Flags().StringVarP(&icsPattern, "pattern", "", "", "Regular expression an event summary must match. The first capture group is the project code")
+	importICSCmd.Flags().StringVarP(&icsFrom, "from", "", "", "Only import events starting on or after this date, as YYYY-MM-DD")
+	importICSCmd.Flags().StringVarP(&icsTo, "to", "", "", "Only import events ending before this date, as YYYY-MM-DD. Defaults to now")
+	csvFlags.addFlags(importCSVCmd)
+	importCSVCmd.Flags().StringVarP(&preset, "preset", "", "generic", "Column layout to start from. One of generic, toggl, or clockify")
+	importCSVCmd.Flags().StringToStringVarP(&columns, "column", "", map[string]string{}, "Map a field to a CSV column. E.g. --column project=Job")
+	importCSVCmd.Flags().StringVarP(&dateFormat, "date-format", "", "", "Go layout of date columns. E.g. 2006-01-02")
+	importCSVCmd.Flags().StringVarP(&timeFormat, "time-format", "", "", "Go layout of time columns. E.g. 15:04:05")
+	importCSVCmd.Flags().StringVarP(&dateTimeFormat, "datetime-format", "", "", "Go layout of combined date and time columns. E.g. 2006-01-02 15:04")
+	importCSVCmd.Flags().StringVarP(&delimiter, "delimiter", "", "", "Field delimiter. Defaults to a comma")
+	importCSVCmd.Flags().BoolVarP(&create, "create", "", false, "Create clients and projects that don't exist yet")
+
+	importCmd.AddCommand(importWatsonCmd, importCSVCmd, importICSCmd, importTimewarriorCmd)
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/adampresley/mytime/api/sessions"
+	"github.com/eiannone/keyboard"
+)
+
+/*
+ * What interactive mode can do with the timer when it is interrupted. These
+ * are also the choices of the interactive.interrupt setting, along with
+ * InterruptAsk.
+ */
+const (
+	InterruptAsk     string = "ask"
+	InterruptSave    string = "save"
+	InterruptDiscard string = "discard"
+	InterruptKeep    string = "keep"
+)
+
+/*
+ * watchActiveSession shows the running timer until 'q' records it or 'd'
+ * detaches, leaving it running. Ctrl-C, and SIGINT, SIGTERM, or SIGHUP,
+ * follow the interactive.interrupt setting. The terminal is put back
+ * however this returns, including on a panic.
+ */
+func watchActiveSession(activeSession sessions.ActiveSession) error {
+	var err error
+	var keys <-chan keyboard.KeyEvent
+
+	if keys, err = keyboard.GetKeys(10); err != nil {
+		return fmt.Errorf("Problem capturing keyboard input: %w", err)
+	}
+
+	defer keyboard.Close()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	fmt.Printf("\nPress '%s' to stop timing, or '%s' to leave the timer running.\n\n", au.BrightRed("q"), au.BrightRed("d"))
+	fmt.Printf("\rTime: %s", appSettings.FormatDuration(time.Now().Sub(activeSession.StartTime)))
+
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Printf("\rTime: %s", appSettings.FormatDuration(time.Now().Sub(activeSession.StartTime)))
+
+		case event, ok := <-keys:
+			/*
+			 * Losing the keyboard means the terminal is gone, which is
+			 * treated like a hang up
+			 */
+			if !ok || event.Err != nil {
+				return finishInteractive(syscall.SIGHUP)
+			}
+
+			switch {
+			case event.Key == keyboard.KeyCtrlC:
+				return finishInteractive(os.Interrupt)
+
+			case event.Rune == 'q':
+				return endInteractive(InterruptSave)
+
+			case event.Rune == 'd':
+				return endInteractive(InterruptKeep)
+			}
+
+		case sig := <-signals:
+			return finishInteractive(sig)
+		}
+	}
+}
+
+/*
+ * finishInteractive decides what to do with the timer after a signal. With
+ * the ask policy only Ctrl-C asks, as after SIGTERM or SIGHUP nobody may be
+ * there to answer. Those keep the timer running, so no time is lost.
+ */
+func finishInteractive(sig os.Signal) error {
+	action := appSettings.Interrupt
+
+	if action == InterruptAsk {
+		action = InterruptKeep
+
+		if sig == os.Interrupt {
+			action = askInterrupt()
+		}
+	}
+
+	return endInteractive(action)
+}
+
+func askInterrupt() string {
+	/*
+	 * Put the terminal back to read a line, and let another Ctrl-C quit
+	 * while asking. That leaves the timer running.
+	 */
+	keyboard.Close()
+	signal.Reset(os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	fmt.Printf("\n")
+
+	for {
+		switch ask("Save this session (s), discard it (d), or keep the timer running (k)? [S/d/k]", "s") {
+		case "s", "save":
+			return InterruptSave
+
+		case "d", "discard":
+			return InterruptDiscard
+
+		case "k", "keep":
+			return InterruptKeep
+		}
+	}
+}
+
+func endInteractive(action string) error {
+	var err error
+	var session sessions.Session
+
+	keyboard.Close()
+	fmt.Printf("\n")
+
+	switch action {
+	case InterruptSave:
+		if session, err = stopActiveSession(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Total time: %s\n", au.Green(appSettings.FormatDuration(session.EndDateTime.Sub(session.StartDateTime))))
+		fmt.Printf("\nSession recorded!\n")
+
+	case InterruptDiscard:
+		unlock, err := lockData()
+
+		if err != nil {
+			return err
+		}
+
+		defer unlock()
+
+		if err = sessionService.DeleteActiveSessions(); err != nil {
+			return fmt.Errorf("Problem clearing the active session: %w", err)
+		}
+
+		fmt.Printf("Session discarded\n")
+
+	default:
+		fmt.Printf("The timer is still running. Stop it with 'mt session stop', or watch it again with 'mt session attach'\n")
+	}
+
+	return nil
+}
@@ -14,6 +14,15 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// projectOutput is a project with its client and default category codes,
+// for --output.
+type projectOutput struct {
+	projects.Project
+	ClientCode          string `json:"clientCode"`
+	ClientName          string `json:"clientName"`
+	DefaultCategoryCode string `json:"defaultCategoryCode"`
+}
+
 func init() {
 	var archived bool
 	var name string
@@ -32,7 +41,7 @@ func init() {
 		Example: `mt list clients
 mt list clients --archived
 mt list clients --name "client name"`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var err error
 			var result clients.ClientCollection
 
@@ -44,14 +53,18 @@ mt list clients --name "client name"`,
 			}
 
 			if result, err = clientService.ListClients(search); err != nil {
-				displayError(fmt.Sprintf("Error listing clients: %s", err.Error()))
+				return fmt.Errorf("Error listing clients: %w", err)
+			}
+
+			if printed, err := printOutput(result); printed {
+				return err
 			}
 
 			table := tablewriter.NewWriter(os.Stdout)
 			table.SetHeader([]string{"ID", "Client", "Code"})
 			table.SetBorder(false)
 
-			table.SetHeaderColor(
+			setHeaderColor(table,
 				tablewriter.Colors{tablewriter.Bold},
 				tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
 				tablewriter.Colors{tablewriter.Bold, tablewriter.FgRedColor},
@@ -63,6 +76,7 @@ mt list clients --name "client name"`,
 
 			table.AppendBulk(tableData)
 			table.Render()
+			return nil
 		},
 	}
 
@@ -73,7 +87,7 @@ mt list clients --name "client name"`,
 		Example: `mt list categories
 mt list categories --archived
 mt list categories --name "category name"`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var err error
 			var result categories.CategoryCollection
 
@@ -85,14 +99,18 @@ mt list categories --name "category name"`,
 			}
 
 			if result, err = categoryService.ListCategories(search); err != nil {
-				displayError(fmt.Sprintf("Error listing categories: %s", err.Error()))
+				return fmt.Errorf("Error listing categories: %w", err)
+			}
+
+			if printed, err := printOutput(result); printed {
+				return err
 			}
 
 			table := tablewriter.NewWriter(os.Stdout)
 			table.SetHeader([]string{"ID", "Category", "Code", "Rate"})
 			table.SetBorder(false)
 
-			table.SetHeaderColor(
+			setHeaderColor(table,
 				tablewriter.Colors{tablewriter.Bold},
 				tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
 				tablewriter.Colors{tablewriter.Bold, tablewriter.FgRedColor},
@@ -100,11 +118,12 @@ mt list categories --name "category name"`,
 			)
 
 			for _, c := range result {
-				tableData = append(tableData, []string{strconv.Itoa(c.CategoryID), c.Name, c.Code, fmt.Sprintf("%.2f", c.Rate)})
+				tableData = append(tableData, []string{strconv.Itoa(c.CategoryID), c.Name, c.Code, appSettings.FormatMoney(c.Rate)})
 			}
 
 			table.AppendBulk(tableData)
 			table.Render()
+			return nil
 		},
 	}
 
@@ -116,7 +135,7 @@ mt list categories --name "category name"`,
 mt list projects --archived
 mt list projects --name "value"
 mt list projects --client "client"`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var err error
 			var result projects.ProjectCollection
 
@@ -129,14 +148,33 @@ mt list projects --client "client"`,
 			}
 
 			if result, err = projectService.ListProjects(search); err != nil {
-				displayError(fmt.Sprintf("Problem listing projects: %s", err.Error()))
+				return fmt.Errorf("Problem listing projects: %w", err)
+			}
+
+			if outputFormat != OutputTable {
+				records := make([]projectOutput, 0, len(result))
+
+				for _, p := range result {
+					c, _ := clientService.GetClientByID(p.ClientID)
+					cat, _ := categoryService.GetCategoryByID(p.DefaultCategoryID)
+
+					records = append(records, projectOutput{
+						Project:             p,
+						ClientCode:          c.Code,
+						ClientName:          c.Name,
+						DefaultCategoryCode: cat.Code,
+					})
+				}
+
+				_, err = printOutput(records)
+				return err
 			}
 
 			table := tablewriter.NewWriter(os.Stdout)
 			table.SetHeader([]string{"ID", "Project", "Code", "Client", "Default Category"})
 			table.SetBorder(false)
 
-			table.SetHeaderColor(
+			setHeaderColor(table,
 				tablewriter.Colors{tablewriter.Bold},
 				tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
 				tablewriter.Colors{tablewriter.Bold, tablewriter.FgRedColor},
@@ -153,6 +191,7 @@ mt list projects --client "client"`,
 
 			table.AppendBulk(tableData)
 			table.Render()
+			return nil
 		},
 	}
 
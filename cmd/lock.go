@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/adampresley/mytime/api/storage"
+)
+
+// lockData takes the exclusive lock on the data directory for a command
+// that reads records and then writes based on what it found, so another
+// mt process can't change them in between. Call the returned function to
+// release it.
+func lockData() (func(), error) {
+	if err := locker.Lock(); err != nil {
+		if errors.Is(err, storage.ErrLocked) {
+			return nil, fmt.Errorf("%w. Please try again in a moment", err)
+		}
+
+		return nil, fmt.Errorf("Problem locking data directory: %w", err)
+	}
+
+	return locker.Unlock, nil
+}
+
+// recoverJournal rolls back an operation that was interrupted part way,
+// such as by a crash, before any command looks at the data.
+func recoverJournal() error {
+	recovery, err := journal.Recover()
+
+	if err != nil {
+		return fmt.Errorf("Problem recovering from an interrupted operation: %w", err)
+	}
+
+	if recovery != nil {
+		fmt.Fprintf(os.Stderr, "An interrupted %s from %s was rolled back\n", recovery.Operation, appSettings.FormatDateTime(recovery.StartedAt))
+	}
+
+	return nil
+}
+
+// recoverRestore puts back the data from before a restore that was
+// interrupted part way.
+func recoverRestore() error {
+	recovered, err := backupService.RecoverRestore()
+
+	if err != nil {
+		return fmt.Errorf("Problem recovering from an interrupted restore: %w", err)
+	}
+
+	if recovered {
+		fmt.Fprintf(os.Stderr, "An interrupted restore was rolled back\n")
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/adampresley/mytime/api/migrations"
+	"github.com/spf13/cobra"
+)
+
+// runPendingMigrations brings the data directory up to the latest schema
+// version. It runs before every command except migrate itself.
+func runPendingMigrations() error {
+	var (
+		err     error
+		pending migrations.MigrationCollection
+	)
+
+	if err = migrationService.Initialize(); err != nil {
+		return fmt.Errorf("Problem preparing your data directory: %w", err)
+	}
+
+	if pending, err = migrationService.Pending(); err != nil {
+		return err
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if err = autoBackup("migrate"); err != nil {
+		return err
+	}
+
+	if _, err = migrationService.Run(false); err != nil {
+		return fmt.Errorf("Problem upgrading your data: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Your data was upgraded to schema version %d\n", migrationService.LatestVersion())
+	return nil
+}
+
+func init() {
+	var (
+		status bool
+		dryRun bool
+	)
+
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: `Upgrades your data to the latest schema version`,
+		Long: `Upgrades your data to the latest schema version. This happens automatically when running any
+other command, so you only need this to see what has been, or would be, changed.`,
+		Example: `mt migrate --status
+mt migrate --dry-run
+mt migrate`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				err      error
+				current  int
+				statuses []migrations.MigrationStatus
+				results  []migrations.MigrationResult
+			)
+
+			if err = migrationService.Initialize(); err != nil {
+				return fmt.Errorf("Problem preparing your data directory: %w", err)
+			}
+
+			if current, err = migrationService.CurrentVersion(); err != nil {
+				return err
+			}
+
+			if status {
+				if statuses, err = migrationService.Status(); err != nil {
+					return err
+				}
+
+				if printed, err := printOutput(statuses); printed {
+					return err
+				}
+
+				fmt.Printf("Schema version %d of %d\n\n", current, migrationService.LatestVersion())
+
+				for _, s := range statuses {
+					applied := au.Yellow("pending")
+
+					if s.Applied {
+						applied = au.Green("applied")
+					}
+
+					fmt.Printf("%3d  %s  %s\n", s.Version, applied, s.Description)
+				}
+
+				return nil
+			}
+
+			if !dryRun {
+				if err = autoBackup("migrate"); err != nil {
+					return err
+				}
+			}
+
+			if results, err = migrationService.Run(dryRun); err != nil {
+				return err
+			}
+
+			if len(results) == 0 {
+				fmt.Printf("Your data is already at the latest schema version (%d)\n", current)
+				return nil
+			}
+
+			for _, r := range results {
+				fmt.Printf("%s %d: %s\n", au.Cyan("Migration"), r.Version, r.Description)
+
+				if len(r.Changes) == 0 {
+					fmt.Printf("    No changes needed\n")
+				}
+
+				for _, change := range r.Changes {
+					fmt.Printf("    %s\n", change)
+				}
+			}
+
+			if dryRun {
+				fmt.Printf("\nDry run. Nothing was changed.\n")
+			} else {
+				fmt.Printf("\nYour data is now at schema version %d\n", migrationService.LatestVersion())
+			}
+
+			return nil
+		},
+	}
+
+	migrateCmd.Flags().BoolVarP(&status, "status", "s", false, "Show which migrations have been applied")
+	migrateCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Show what would change without changing anything")
+
+	rootCmd.AddCommand(migrateCmd)
+}
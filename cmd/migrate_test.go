@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adampresley/mytime/api/migrations"
+)
+
+func TestMigrateStartsNewDataUpToDate(t *testing.T) {
+	var err error
+	var current int
+
+	dataDir := tempDir(t, "mytime-data")
+
+	if err = runCommand(t, "--data-dir", dataDir, "--output", "json", "migrate", "--status"); err != nil {
+		t.Fatalf("mt migrate --status: %v", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(dataDir, migrations.SchemaFile)); err != nil {
+		t.Errorf("schema file: %v, want it written", err)
+	}
+
+	if current, err = migrationService.CurrentVersion(); err != nil || current != migrationService.LatestVersion() {
+		t.Errorf("CurrentVersion() = %d, %v, want %d", current, err, migrationService.LatestVersion())
+	}
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	OutputTable string = "table"
+	OutputJSON  string = "json"
+	OutputCSV   string = "csv"
+	OutputYAML  string = "yaml"
+)
+
+var outputFormats = []string{OutputTable, OutputJSON, OutputCSV, OutputYAML}
+
+func validateOutputFormat() error {
+	for _, f := range outputFormats {
+		if outputFormat == f {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("--output must be one of %s", strings.Join(outputFormats, ", "))
+}
+
+// printOutput writes records to stdout as JSON, CSV, or YAML when --output
+// asks for one of them, and reports whether it did, along with any error
+// writing them. Table output is left
+// to the caller. Records are a struct or a slice of structs, and their
+// JSON field names are used in every format.
+func printOutput(records interface{}) (bool, error) {
+	var err error
+
+	/*
+	 * An empty list is written as [] rather than null
+	 */
+	if value := reflect.ValueOf(records); value.Kind() == reflect.Slice && value.IsNil() {
+		records = reflect.MakeSlice(value.Type(), 0, 0).Interface()
+	}
+
+	switch outputFormat {
+	case OutputJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(records)
+
+	case OutputCSV:
+		err = writeOutputCSV(records)
+
+	case OutputYAML:
+		err = writeOutputYAML(records)
+
+	default:
+		return false, nil
+	}
+
+	if err != nil {
+		return true, fmt.Errorf("Problem writing output: %w", err)
+	}
+
+	return true, nil
+}
+
+/*
+ * YAML is written from the JSON encoding, so both use the same field
+ * names and order.
+ */
+func writeOutputYAML(records interface{}) error {
+	var (
+		err    error
+		b      []byte
+		object yaml.MapSlice
+		list   []yaml.MapSlice
+	)
+
+	if b, err = json.Marshal(records); err != nil {
+		return err
+	}
+
+	if reflect.Indirect(reflect.ValueOf(records)).Kind() == reflect.Slice {
+		if err = yaml.Unmarshal(b, &list); err != nil {
+			return err
+		}
+
+		b, err = yaml.Marshal(list)
+	} else {
+		if err = yaml.Unmarshal(b, &object); err != nil {
+			return err
+		}
+
+		b, err = yaml.Marshal(object)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(b)
+	return err
+}
+
+func writeOutputCSV(records interface{}) error {
+	var header []string
+
+	rows := make([][]string, 0, 10)
+	value := reflect.Indirect(reflect.ValueOf(records))
+
+	if value.Kind() == reflect.Slice {
+		for index := 0; index < value.Len(); index++ {
+			var row []string
+
+			header, row = csvFields(reflect.Indirect(value.Index(index)))
+			rows = append(rows, row)
+		}
+
+		/*
+		 * An empty list still gets its header
+		 */
+		if value.Len() == 0 {
+			header, _ = csvFields(reflect.New(value.Type().Elem()).Elem())
+		}
+	} else {
+		var row []string
+
+		header, row = csvFields(value)
+		rows = append(rows, row)
+	}
+
+	writer := csv.NewWriter(os.Stdout)
+
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvFields(value reflect.Value) ([]string, []string) {
+	header := make([]string, 0, value.NumField())
+	row := make([]string, 0, value.NumField())
+
+	for index := 0; index < value.NumField(); index++ {
+		field := value.Type().Field(index)
+
+		/*
+		 * Embedded structs are flattened, even unexported ones, the same
+		 * as encoding/json does
+		 */
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			h, r := csvFields(value.Field(index))
+			header = append(header, h...)
+			row = append(row, r...)
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		header = append(header, name)
+		row = append(row, csvValue(value.Field(index)))
+	}
+
+	return header, row
+}
+
+func csvValue(value reflect.Value) string {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return ""
+		}
+
+		value = value.Elem()
+	}
+
+	switch v := value.Interface().(type) {
+	case time.Time:
+		return v.Format(time.RFC3339)
+
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+
+	case []string:
+		return strings.Join(v, ";")
+	}
+
+	return fmt.Sprint(value.Interface())
+}
@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/adampresley/mytime/api/categories"
+	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/projects"
+	"github.com/eiannone/keyboard"
+	"github.com/spf13/cobra"
+)
+
+// PickerRows is how many matches the picker shows at once.
+const PickerRows int = 10
+
+var errNothingPicked = errors.New("Nothing was chosen")
+
+type pickOption struct {
+	code  string
+	label string
+}
+
+type pickMatch struct {
+	pickOption
+	score int
+}
+
+/*
+ * canPick reports whether a picker can be shown instead of asking for a
+ * missing code. Both ends need to be a terminal, so scripts and pipes
+ * still get an error.
+ */
+func canPick() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+/*
+ * fuzzyScore reports whether the letters of query appear in order in
+ * text. Matches that start early and keep the letters close together
+ * score lower, and are listed first.
+ */
+func fuzzyScore(query, text string) (int, bool) {
+	query = strings.ToLower(query)
+	text = strings.ToLower(text)
+
+	if query == "" {
+		return 0, true
+	}
+
+	score := 0
+	start := -1
+	last := -1
+	queryRunes := []rune(query)
+	position := 0
+
+	for index, r := range []rune(text) {
+		if r != queryRunes[position] {
+			continue
+		}
+
+		if start == -1 {
+			start = index
+		} else {
+			score += index - last - 1
+		}
+
+		last = index
+		position++
+
+		if position == len(queryRunes) {
+			return score + start, true
+		}
+	}
+
+	return 0, false
+}
+
+func fuzzyFilter(query string, options []pickOption) []pickMatch {
+	result := make([]pickMatch, 0, len(options))
+
+	for _, o := range options {
+		if score, ok := fuzzyScore(query, o.code+" "+o.label); ok {
+			result = append(result, pickMatch{pickOption: o, score: score})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].score < result[j].score
+	})
+
+	return result
+}
+
+/*
+ * pick shows a list of options that narrows as you type, and returns the
+ * code of the one chosen with Enter. Esc or Ctrl-C returns
+ * errNothingPicked. It draws below the cursor rather than taking over
+ * the screen, and clears itself when done.
+ */
+func pick(title, query string, options []pickOption) (string, error) {
+	var err error
+	var keys <-chan keyboard.KeyEvent
+
+	if len(options) == 0 {
+		return "", fmt.Errorf("There is no %s to choose from", strings.ToLower(title))
+	}
+
+	if keys, err = keyboard.GetKeys(10); err != nil {
+		return "", fmt.Errorf("Problem capturing keyboard input: %w", err)
+	}
+
+	defer keyboard.Close()
+
+	input := []rune(query)
+	selected := 0
+	drawn := 0
+
+	for {
+		matches := fuzzyFilter(string(input), options)
+
+		if len(matches) > PickerRows {
+			matches = matches[:PickerRows]
+		}
+
+		if selected >= len(matches) {
+			selected = len(matches) - 1
+		}
+
+		if selected < 0 {
+			selected = 0
+		}
+
+		drawn = drawPicker(title, string(input), matches, selected, drawn)
+		event := <-keys
+
+		if event.Err != nil {
+			clearPicker(drawn)
+			return "", fmt.Errorf("Problem reading key from keyboard: %w", event.Err)
+		}
+
+		switch event.Key {
+		case keyboard.KeyEnter:
+			if len(matches) == 0 {
+				break
+			}
+
+			clearPicker(drawn)
+			fmt.Printf("%s: %s\n", title, au.Green(matches[selected].code))
+			return matches[selected].code, nil
+
+		case keyboard.KeyEsc, keyboard.KeyCtrlC:
+			clearPicker(drawn)
+			return "", errNothingPicked
+
+		case keyboard.KeyArrowUp:
+			selected--
+
+		case keyboard.KeyArrowDown:
+			selected++
+
+		case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+			if len(input) > 0 {
+				input = input[:len(input)-1]
+				selected = 0
+			}
+
+		case keyboard.KeySpace:
+			input = append(input, ' ')
+			selected = 0
+
+		default:
+			if event.Rune != 0 {
+				input = append(input, event.Rune)
+				selected = 0
+			}
+		}
+	}
+}
+
+// drawPicker redraws the picker over the lines it drew last time, and
+// returns how many lines it drew.
+func drawPicker(title, query string, matches []pickMatch, selected, drawn int) int {
+	var sb strings.Builder
+
+	clearPicker(drawn)
+
+	sb.WriteString(fmt.Sprintf("%s (type to search, %s to choose, %s to cancel): %s\n", au.Bold(title), au.Cyan("enter"), au.Cyan("esc"), query))
+
+	if len(matches) == 0 {
+		sb.WriteString("  No matches\n")
+	}
+
+	for index, m := range matches {
+		line := fmt.Sprintf("%-12s %s", m.code, m.label)
+
+		if index == selected {
+			sb.WriteString(fmt.Sprintf("%s %s\n", au.Green(">"), au.Green(line)))
+		} else {
+			sb.WriteString(fmt.Sprintf("  %s\n", line))
+		}
+	}
+
+	fmt.Print(sb.String())
+	return strings.Count(sb.String(), "\n")
+}
+
+func clearPicker(drawn int) {
+	if drawn > 0 {
+		fmt.Printf("\x1b[%dA", drawn)
+	}
+
+	fmt.Printf("\r\x1b[J")
+}
+
+/*
+ * pickCode returns code as is when it names a record, or when there is no
+ * terminal to show a picker on, so the usual error is given. Otherwise the
+ * picker is shown, starting with code as the search.
+ */
+func pickCode(code string, exists func(code string) bool, pickFrom func(query string) (string, error)) (string, error) {
+	if (code != "" && exists(code)) || !canPick() {
+		return code, nil
+	}
+
+	return pickFrom(code)
+}
+
+func pickClientCode(code string) (string, error) {
+	exists := func(code string) bool {
+		_, err := clientService.GetClientByCode(code)
+		return err == nil
+	}
+
+	return pickCode(code, exists, func(query string) (string, error) {
+		var err error
+		var result clients.ClientCollection
+
+		if result, err = clientService.ListClients(clients.ClientSearch{}); err != nil {
+			return "", err
+		}
+
+		options := make([]pickOption, 0, len(result))
+
+		for _, c := range result {
+			options = append(options, pickOption{code: c.Code, label: c.Name})
+		}
+
+		return pick("Client", query, options)
+	})
+}
+
+func pickProjectCode(code string) (string, error) {
+	exists := func(code string) bool {
+		_, err := projectService.GetProjectByCode(code)
+		return err == nil
+	}
+
+	return pickCode(code, exists, func(query string) (string, error) {
+		var err error
+		var result projects.ProjectCollection
+
+		if result, err = projectService.ListProjects(projects.ProjectSearch{}); err != nil {
+			return "", err
+		}
+
+		options := make([]pickOption, 0, len(result))
+
+		for _, p := range result {
+			c, _ := clientService.GetClientByID(p.ClientID)
+			options = append(options, pickOption{code: p.Code, label: fmt.Sprintf("%s (%s)", p.Name, c.Name)})
+		}
+
+		return pick("Project", query, options)
+	})
+}
+
+func pickCategoryCode(code string) (string, error) {
+	exists := func(code string) bool {
+		_, err := categoryService.GetCategoryByCode(code)
+		return err == nil
+	}
+
+	return pickCode(code, exists, func(query string) (string, error) {
+		var err error
+		var result categories.CategoryCollection
+
+		if result, err = categoryService.ListCategories(categories.CategorySearch{}); err != nil {
+			return "", err
+		}
+
+		options := make([]pickOption, 0, len(result))
+
+		for _, c := range result {
+			options = append(options, pickOption{code: c.Code, label: c.Name})
+		}
+
+		return pick("Category", query, options)
+	})
+}
+
+// optionalCodeArgs is codeArgs for commands that can pick the code when
+// it is left out.
+func optionalCodeArgs(entity, action string) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 && !canPick() {
+			return fmt.Errorf("Please provide the code for the %s you wish to %s", entity, action)
+		}
+
+		return nil
+	}
+}
+
+// firstArg returns the first argument, or an empty string when there
+// are none.
+func firstArg(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/adampresley/mytime/api/profiles"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var use bool
+
+	profileCmd := &cobra.Command{
+		Use:   "profile",
+		Short: `Manages profiles, which keep separate sets of data and configuration`,
+		Long: `Manages profiles, which keep separate sets of data and configuration. The current profile
+is used unless another is given with --profile, or a data directory with --data-dir.`,
+	}
+
+	listProfilesCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   `Lists profiles`,
+		Example: `mt profile list`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			var result profiles.ProfileCollection
+
+			if result, err = profileService.ListProfiles(); err != nil {
+				return fmt.Errorf("Error listing profiles: %w", err)
+			}
+
+			if printed, err := printOutput(result); printed {
+				return err
+			}
+
+			tableData := make([][]string, 0, len(result))
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"", "Profile", "Data Directory"})
+			table.SetBorder(false)
+
+			setHeaderColor(table,
+				tablewriter.Colors{},
+				tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
+				tablewriter.Colors{tablewriter.Bold},
+			)
+
+			for _, p := range result {
+				current := ""
+
+				if p.Current {
+					current = "*"
+				}
+
+				tableData = append(tableData, []string{current, p.Name, p.DataDirectory})
+			}
+
+			table.AppendBulk(tableData)
+			table.Render()
+			return nil
+		},
+	}
+
+	createProfileCmd := &cobra.Command{
+		Use:   "create",
+		Short: `Creates a new, empty profile`,
+		Example: `mt profile create consulting
+mt profile create consulting --use`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			var profile profiles.Profile
+
+			if profile, err = profileService.CreateProfile(args[0]); err != nil {
+				if errors.Is(err, profiles.ErrProfileExists) {
+					return err
+				}
+
+				return fmt.Errorf("Problem creating profile: %w", err)
+			}
+
+			fmt.Printf("Profile %s created in %s\n", au.Green(profile.Name), profile.DataDirectory)
+
+			if use {
+				if err = profileService.UseProfile(profile.Name); err != nil {
+					return fmt.Errorf("Problem switching profile: %w", err)
+				}
+
+				fmt.Printf("Now using profile %s\n", au.Green(profile.Name))
+			}
+
+			return nil
+		},
+	}
+
+	useProfileCmd := &cobra.Command{
+		Use:   "use",
+		Short: `Switches the current profile`,
+		Example: `mt profile use consulting
+mt profile use default`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := profileService.UseProfile(args[0]); err != nil {
+				if errors.Is(err, profiles.ErrProfileNotFound) {
+					return err
+				}
+
+				return fmt.Errorf("Problem switching profile: %w", err)
+			}
+
+			fmt.Printf("Now using profile %s\n", au.Green(args[0]))
+			return nil
+		},
+	}
+
+	createProfileCmd.Flags().BoolVar(&use, "use", false, "Switch to the new profile")
+
+	profileCmd.AddCommand(listProfilesCmd)
+	profileCmd.AddCommand(createProfileCmd)
+	profileCmd.AddCommand(useProfileCmd)
+	rootCmd.AddCommand(profileCmd)
+}
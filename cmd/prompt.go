@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/*
+ * stdin is shared by every prompt. A reader of its own per prompt could
+ * read ahead past the answer, losing what was typed for the next one.
+ */
+var stdin = bufio.NewReader(os.Stdin)
+
+// confirm asks a yes or no question on the terminal. Anything but "y" or
+// "yes" is a no.
+func confirm(question string) bool {
+	answer := ask(question+" [y/N]", "n")
+	return answer == "y" || answer == "yes"
+}
+
+// ask puts a question with a set of answers on the terminal and returns
+// the answer in lower case, or fallback when nothing is typed.
+func ask(question, fallback string) string {
+	answer := strings.ToLower(prompt(question))
+
+	if answer == "" {
+		return fallback
+	}
+
+	return answer
+}
+
+// prompt asks for free text, such as notes, and returns it as typed
+// without the surrounding space.
+func prompt(question string) string {
+	fmt.Printf("%s ", question)
+
+	answer, _ := stdin.ReadString('\n')
+	return strings.TrimSpace(answer)
+}
@@ -1,108 +1,314 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 
+	"github.com/adampresley/mytime/api/backups"
 	"github.com/adampresley/mytime/api/categories"
 	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/exports"
 	"github.com/adampresley/mytime/api/helpers"
+	"github.com/adampresley/mytime/api/imports"
+	"github.com/adampresley/mytime/api/migrations"
+	"github.com/adampresley/mytime/api/profiles"
 	"github.com/adampresley/mytime/api/projects"
 	"github.com/adampresley/mytime/api/sessions"
+	"github.com/adampresley/mytime/api/settings"
+	"github.com/adampresley/mytime/api/storage"
 	"github.com/adampresley/simdb"
-	. "github.com/logrusorgru/aurora"
+	"github.com/logrusorgru/aurora"
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 const (
-	Version       string = "0.0.1"
-	DataDirectory string = ".mytime"
+	Version                 string = "0.0.1"
+	DataDirectory           string = ".mytime"
+	HomeEnvironmentVariable string = "MYTIME_HOME"
 )
 
 var (
-	configFile string
+	commandStarted bool
+	configFile     string
+	dataDirFlag    string
+	dataPath       string
+	homePath       string
+	outputFormat   string
+	profileFlag    string
+	profileName    string
 
 	rootCmd = &cobra.Command{
 		Use:   "mt",
 		Short: "Time tracking, invoicing, and reporting!",
+
+		SilenceErrors: true,
+		SilenceUsage:  true,
 	}
 
-	db              *simdb.Driver
-	helperService   helpers.HelperService
-	clientService   clients.ClientService
-	categoryService categories.CategoryService
-	projectService  projects.ProjectService
-	sessionService  sessions.SessionService
+	db               *simdb.Driver
+	locker           storage.FileLocker
+	journal          storage.FileJournal
+	helperService    helpers.HelperService
+	clientService    clients.ClientService
+	categoryService  categories.CategoryService
+	projectService   projects.ProjectService
+	sessionService   sessions.SessionService
+	exportService    exports.ExportService
+	importService    imports.ImportService
+	backupService    backups.BackupService
+	migrationService migrations.MigrationService
+	profileService   profiles.ProfileService
+	settingsService  settings.SettingsService
+
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	appSettings = settings.Defaults()
+	useColor    = os.Getenv("NO_COLOR") == ""
+	au          = aurora.NewAurora(useColor)
 )
 
-func Execute() error {
-	return rootCmd.Execute()
+// Execute runs the command line and returns the exit code for main to
+// exit with. Errors from arguments and flags are usage errors, because
+// they are reported before the command starts.
+func Execute() int {
+	var err error
+	var cmd *cobra.Command
+
+	if cmd, err = rootCmd.ExecuteC(); err == nil {
+		return ExitOK
+	}
+
+	code := exitCode(err)
+
+	if !commandStarted {
+		code = ExitUsage
+	}
+
+	printError(err, code)
+
+	if code == ExitUsage && outputFormat == OutputTable {
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage\n", cmd.CommandPath())
+	}
+
+	return code
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&dataDirFlag, "data-dir", "", "Use the data and configuration in this directory, ignoring profiles")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Use this profile instead of the current one")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", OutputTable, "Output format for lists and reports: table, json, csv, or yaml")
+
+	/*
+	 * Bring the data up to date before running anything that reads it.
+	 * Profile commands only need to know where profiles live, and
+	 * completions set up the data themselves.
+	 */
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		var err error
+
+		commandStarted = true
+
+		if err = validateOutputFormat(); err != nil {
+			outputFormat = OutputTable
+			return err
+		}
+
+		if outputFormat != OutputTable {
+			useColor = false
+			au = aurora.NewAurora(false)
+		}
+
+		if err = setupHome(); err != nil {
+			return err
+		}
+
+		if cmd.HasParent() && cmd.Parent().Name() == "profile" {
+			return nil
+		}
+
+		if isCompletionCommand(cmd) {
+			registerCompletions()
+			return nil
+		}
+
+		if err = setupData(cmd); err != nil {
+			return err
+		}
+
+		if err = recoverRestore(); err != nil {
+			return err
+		}
+
+		if err = recoverJournal(); err != nil {
+			return err
+		}
+
+		/*
+		 * Damaged data can still be replaced from a backup
+		 */
+		if cmd.Name() != "restore" {
+			if err = storage.CheckCollections(dataPath); err != nil {
+				return fmt.Errorf("%w. You can restore a backup with 'mt restore'", err)
+			}
+		}
+
+		if cmd.Name() != "migrate" {
+			return runPendingMigrations()
+		}
+
+		return nil
+	}
+}
+
+// setupHome finds where My Time keeps its profiles. MYTIME_HOME replaces
+// the default of ~/.mytime.
+func setupHome() error {
+	var err error
+
+	if homePath = os.Getenv(HomeEnvironmentVariable); homePath == "" {
+		var userHomeDir string
+
+		if userHomeDir, err = os.UserHomeDir(); err != nil {
+			return fmt.Errorf("Unable to find user's home directory: %w", err)
+		}
+
+		homePath = filepath.Join(userHomeDir, DataDirectory)
+	}
+
+	profileService = profiles.NewProfileService(profiles.ProfileServiceConfig{
+		HomeDirectory: homePath,
+	})
+
+	return nil
+}
+
+// setupData picks the data directory, reads its config file, and creates
+// the services. In order of precedence the directory comes from
+// --data-dir, --profile, then the current profile.
+func setupData(cmd *cobra.Command) error {
 	var err error
-	var homeDir string
-	var fullPath string
 
-	if homeDir, err = os.UserHomeDir(); err != nil {
-		displayError(fmt.Sprintf("Unable to find user's home directory: %s", err.Error()))
-		os.Exit(1)
+	if profileName, err = profileService.CurrentProfile(); err != nil {
+		return err
+	}
+
+	if profileFlag != "" {
+		profileName = profileFlag
+	}
+
+	if dataDirFlag != "" {
+		dataPath = dataDirFlag
+	} else if dataPath, err = profileService.DataDirectory(profileName); err != nil {
+		if errors.Is(err, profiles.ErrProfileNotFound) {
+			return fmt.Errorf("%w. Create it with 'mt profile create %s'", err, profileName)
+		}
+
+		return err
+	}
+
+	if err = os.MkdirAll(dataPath, 0755); err != nil {
+		return fmt.Errorf("Unable to create data directory: %w", err)
 	}
 
 	/*
-	 * Read config file (if any)
+	 * Read config file (if any). Config commands carry on with the
+	 * defaults when it's invalid, so it can be fixed.
 	 */
-	fullPath = filepath.Join(homeDir, DataDirectory, "config.yml")
+	configFile = filepath.Join(dataPath, "config.yml")
+
+	settingsService = settings.NewSettingsService(settings.SettingsServiceConfig{
+		ConfigFile: configFile,
+	})
 
-	viper.SetConfigType("yaml")
-	viper.SetConfigFile(fullPath)
+	if appSettings, err = settingsService.Load(); err != nil && !isConfigCommand(cmd) {
+		return err
+	}
 
-	_ = viper.ReadInConfig()
+	useColor = appSettings.Color && os.Getenv("NO_COLOR") == "" && outputFormat == OutputTable
+	au = aurora.NewAurora(useColor)
 
 	/*
 	 * Load database
 	 */
-	fullPath = filepath.Join(homeDir, DataDirectory)
+	fs := storage.NewAtomicFs(afero.NewOsFs())
 
-	if db, err = simdb.New(afero.NewOsFs(), fullPath); err != nil {
-		displayError(fmt.Sprintf("Unable to create database: %s", err.Error()))
-		os.Exit(1)
+	if db, err = simdb.New(fs, dataPath); err != nil {
+		return fmt.Errorf("Unable to create database: %w", err)
 	}
 
+	locker = storage.NewFileLocker(dataPath, appSettings.LockWait)
+	journal = storage.NewFileJournal(dataPath, locker)
+
 	/*
 	 * Setup services
 	 */
 	helperService = helpers.NewHelperService(helpers.HelperServiceConfig{})
 
+	migrationService = migrations.NewMigrationService(migrations.MigrationServiceConfig{
+		DataDirectory: dataPath,
+		Locker:        locker,
+		Migrations:    migrations.All,
+	})
+
+	backupService = backups.NewBackupService(backups.BackupServiceConfig{
+		DataDirectory: dataPath,
+		Keep:          appSettings.BackupKeep,
+		Locker:        locker,
+	})
+
 	clientService = clients.NewClientService(clients.ClientServiceConfig{
-		DB:            db,
-		HelperService: helperService,
+		ClientRepository: clients.NewSimdbClientRepository(db, fs, locker),
+		HelperService:    helperService,
 	})
 
 	categoryService = categories.NewCategoryService(categories.CategoryServiceConfig{
-		DB:            db,
-		HelperService: helperService,
+		CategoryRepository: categories.NewSimdbCategoryRepository(db, fs, locker),
+		HelperService:      helperService,
 	})
 
 	projectService = projects.NewProjectService(projects.ProjectServiceConfig{
-		ClientService: clientService,
-		DB:            db,
-		HelperService: helperService,
+		ClientService:     clientService,
+		HelperService:     helperService,
+		ProjectRepository: projects.NewSimdbProjectRepository(db, fs, locker),
 	})
 
 	sessionService = sessions.NewSessionService(sessions.SessionServiceConfig{
+		ActiveSessionRepository: sessions.NewSimdbActiveSessionRepository(db, fs, locker),
+		CategoryService:         categoryService,
+		ClientService:           clientService,
+		HelperService:           helperService,
+		Journal:                 journal,
+		ProjectService:          projectService,
+		SessionRepository:       sessions.NewSimdbSessionRepository(db, fs, locker),
+	})
+
+	exportService = exports.NewExportService(exports.ExportServiceConfig{
+		CategoryService: categoryService,
+		ClientService:   clientService,
+		ProjectService:  projectService,
+		SessionService:  sessionService,
+		Settings:        appSettings,
+	})
+
+	importService = imports.NewImportService(imports.ImportServiceConfig{
 		CategoryService: categoryService,
 		ClientService:   clientService,
-		DB:              db,
 		HelperService:   helperService,
+		Journal:         journal,
 		ProjectService:  projectService,
+		SessionService:  sessionService,
 	})
+
+	return nil
 }
 
-func displayError(msg interface{}) {
-	fmt.Printf("%s %v\n", Red("ERROR:"), msg)
-	os.Exit(1)
+// setHeaderColor colours a table's headings, unless colour is turned off.
+func setHeaderColor(table *tablewriter.Table, colors ...tablewriter.Colors) {
+	if useColor {
+		table.SetHeaderColor(colors...)
+	}
 }
@@ -1,37 +1,130 @@
 package cmd
 
 import (
-	"context"
-	"errors"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/adampresley/mytime/api/apperrors"
 	"github.com/adampresley/mytime/api/categories"
 	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/exports"
 	"github.com/adampresley/mytime/api/projects"
 	"github.com/adampresley/mytime/api/sessions"
-	"github.com/adampresley/simdb"
-	"github.com/eiannone/keyboard"
-	. "github.com/logrusorgru/aurora"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
+// activeSessionOutput is what session status shows with --output.
+type activeSessionOutput struct {
+	ClientCode      string    `json:"clientCode"`
+	ClientName      string    `json:"clientName"`
+	ProjectCode     string    `json:"projectCode"`
+	ProjectName     string    `json:"projectName"`
+	CategoryCode    string    `json:"categoryCode"`
+	CategoryName    string    `json:"categoryName"`
+	StartDateTime   time.Time `json:"startDateTime"`
+	DurationSeconds int64     `json:"durationSeconds"`
+	Notes           string    `json:"notes"`
+}
+
+// startActiveSession starts timing, unless a session is already running.
+// The lock is held from checking to starting, so two mt processes can't
+// both start one.
+func startActiveSession(projectID, categoryID, clientID int, notes string) (sessions.ActiveSession, time.Time, error) {
+	var (
+		err              error
+		hasActiveSession bool
+		activeSession    sessions.ActiveSession
+		startTime        time.Time
+		unlock           func()
+	)
+
+	if unlock, err = lockData(); err != nil {
+		return activeSession, startTime, err
+	}
+
+	defer unlock()
+
+	if hasActiveSession, err = sessionService.HasActiveSession(); err != nil {
+		return activeSession, startTime, fmt.Errorf("Problem determining if there is an active session in progress: %w", err)
+	}
+
+	if hasActiveSession {
+		return activeSession, startTime, apperrors.Conflict("You already have an active session in progress!")
+	}
+
+	if activeSession, startTime, err = sessionService.StartActiveSession(projectID, categoryID, clientID, notes); err != nil {
+		return activeSession, startTime, fmt.Errorf("Problem starting session: %w", err)
+	}
+
+	return activeSession, startTime, nil
+}
+
+// stopActiveSession records the running timer as a session and clears
+// it, in one journaled operation.
+func stopActiveSession() (sessions.Session, error) {
+	var (
+		err           error
+		activeSession sessions.ActiveSession
+		session       sessions.Session
+	)
+
+	unlock, err := lockData()
+
+	if err != nil {
+		return session, err
+	}
+
+	defer unlock()
+
+	if activeSession, err = sessionService.GetActiveSession(); err != nil {
+		return session, err
+	}
+
+	session = sessions.Session{
+		ClientID:      activeSession.ClientID,
+		ProjectID:     activeSession.ProjectID,
+		CategoryID:    activeSession.CategoryID,
+		StartDateTime: activeSession.StartTime,
+		EndDateTime:   time.Now(),
+		Notes:         activeSession.Notes,
+		Invoiced:      false,
+		Paid:          false,
+	}
+
+	if err = journal.Begin("stop session"); err != nil {
+		return session, fmt.Errorf("Problem recording session to database: %w", err)
+	}
+
+	if session.SessionID, err = sessionService.CreateSession(session); err != nil {
+		journal.Rollback()
+		return session, fmt.Errorf("Problem recording session to database: %w", err)
+	}
+
+	if err = sessionService.DeleteActiveSessions(); err != nil {
+		journal.Rollback()
+		return session, fmt.Errorf("Problem clearing the active session: %w", err)
+	}
+
+	return session, journal.Commit()
+}
+
 func init() {
 	var (
-		interactive  bool
-		categoryCode string
-		clientCode   string
-		projectCode  string
-		paid         bool
-		invoiced     bool
-		sessionID    int
-		sessionIDs   []int
-		decimal      bool
+		interactive   bool
+		allowArchived bool
+		categoryCode  string
+		clientCode    string
+		projectCode   string
+		paid          bool
+		invoiced      bool
+		sessionID     int
+		sessionIDs    []int
+		decimal       bool
+		week          bool
 	)
 
 	sessionCmd := &cobra.Command{
@@ -46,15 +139,18 @@ func init() {
 		Short:   `Starts a session timing against a project`,
 		Example: `mt session start "projectCode" "notes" - Starts timing using the default category code
 mt session start "projectCode" "notes" --category "categoryCode" - Starts timing using a specific category code
-mt session start "projectCode" "notes" --interactive - Starts timing and displays a time, waiting for you to press Q to stop`,
+mt session start "projectCode" "notes" --interactive - Starts timing and displays a time, waiting for you to press Q to stop or D to leave it running
+mt session start "projectCode" "notes" --allow-archived - Starts timing even if the project or category is archived
+mt session start "notes" - Shows a list of projects to pick from, when run in a terminal
+mt session start "notes" --category "dev" - Also picks the category, starting with those matching "dev", if no category has that code`,
 		Args: func(cmd *cobra.Command, args []string) error {
-			if len(args) < 2 {
+			if len(args) < 2 && !canPick() {
 				return fmt.Errorf("Please provide the project code to start timing for, and a small note describing this session")
 			}
 
 			return nil
 		},
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				err         error
 				projectCode string
@@ -64,148 +160,79 @@ mt session start "projectCode" "notes" --interactive - Starts timing and display
 				client   clients.Client
 				category categories.Category
 
-				hasActiveSession bool
-				wait             *sync.WaitGroup
-				activeSession    sessions.ActiveSession
-				startTime        time.Time
+				activeSession sessions.ActiveSession
+				startTime     time.Time
 			)
 
-			projectCode = args[0]
-			notes = args[1]
-
-			if project, err = projectService.GetProjectByCode(projectCode); err != nil {
-				if errors.Is(err, simdb.ErrZeroRecords) {
-					displayError(fmt.Sprintf("Project code %s not found", Green(projectCode)))
+			/*
+			 * In a terminal the project code can be left out and picked
+			 * instead. A lone argument is the notes, unless it is a project
+			 * code, in which case the notes are asked for.
+			 */
+			switch {
+			case len(args) > 1:
+				projectCode = args[0]
+				notes = args[1]
+
+			case len(args) == 1:
+				if _, err = projectService.GetProjectByCode(args[0]); err == nil {
+					projectCode = args[0]
 				} else {
-					displayError(fmt.Sprintf("Problem getting project: %s", err.Error()))
+					notes = args[0]
 				}
 			}
 
-			if client, err = clientService.GetClientByID(project.ClientID); err != nil {
-				if errors.Is(err, simdb.ErrZeroRecords) {
-					displayError(fmt.Sprintf("Client ID %d not found", Green(project.ClientID)))
-				} else {
-					displayError(fmt.Sprintf("Problem getting client information: %s", err.Error()))
+			if projectCode, err = pickProjectCode(projectCode); err != nil {
+				return err
+			}
+
+			if len(args) < 2 && notes == "" {
+				notes = prompt("Notes:")
+			}
+
+			if categoryCode != "" {
+				if categoryCode, err = pickCategoryCode(categoryCode); err != nil {
+					return err
 				}
 			}
 
+			if project, err = projectService.GetProjectByCode(projectCode); err != nil {
+				return err
+			}
+
+			if client, err = clientService.GetClientByID(project.ClientID); err != nil {
+				return err
+			}
+
 			if categoryCode != "" {
 				if category, err = categoryService.GetCategoryByCode(categoryCode); err != nil {
-					if errors.Is(err, simdb.ErrZeroRecords) {
-						displayError(fmt.Sprintf("Category code %s not found", Green(categoryCode)))
-					} else {
-						displayError(fmt.Sprintf("Problem getting category: %s", err.Error()))
-					}
+					return err
 				}
 			} else {
 				if category, err = categoryService.GetCategoryByID(project.DefaultCategoryID); err != nil {
-					if errors.Is(err, simdb.ErrZeroRecords) {
-						displayError(fmt.Sprintf("Category id %d not found", Green(project.DefaultCategoryID)))
-					} else {
-						displayError(fmt.Sprintf("Problem getting category: %s", err.Error()))
-					}
+					return err
 				}
 			}
 
-			/*
-			 * Don't allow the user to continue if there is an active session. That
-			 * means something went wrong! Otherwise, start a session.
-			 */
-			if hasActiveSession, err = sessionService.HasActiveSession(); err != nil {
-				displayError(fmt.Errorf("Problem determining if there is an active session in progress: %s", err.Error()))
+			if project.Archived && !allowArchived {
+				return apperrors.Conflict("Project %s is archived. Unarchive it with 'mt unarchive project %s', or use --allow-archived", project.Code, project.Code)
 			}
 
-			if hasActiveSession {
-				displayError("You already have an active session in progress!")
+			if category.Archived && !allowArchived {
+				return apperrors.Conflict("Category %s is archived. Unarchive it with 'mt unarchive category %s', or use --allow-archived", category.Code, category.Code)
 			}
 
-			if activeSession, startTime, err = sessionService.StartActiveSession(project.ProjectID, category.CategoryID, client.ClientID, notes); err != nil {
-				displayError(fmt.Errorf("Problem starting session: %s", err.Error()))
+			if activeSession, startTime, err = startActiveSession(project.ProjectID, category.CategoryID, client.ClientID, notes); err != nil {
+				return err
 			}
 
-			fmt.Printf("Timing for %s\nProject: %s\nCategory %s\nStart Time: %s\n", Green(client.Name), Green(project.Name), Cyan(category.Name), startTime.Format("3:04 PM"))
+			fmt.Printf("Timing for %s\nProject: %s\nCategory %s\nStart Time: %s\n", au.Green(client.Name), au.Green(project.Name), au.Cyan(category.Name), appSettings.FormatTime(startTime))
 
 			if interactive {
-				/*
-				 * Start timing
-				 */
-				c, cancel := context.WithCancel(context.Background())
-				wait = &sync.WaitGroup{}
-				wait.Add(1)
-
-				fmt.Printf("\nPress '%s' to stop timing.\n\n", BrightRed("q"))
-
-				go func() {
-					ticker := time.NewTicker(1 * time.Second)
-					done := false
-
-					for {
-						select {
-						case <-ticker.C:
-							diff := time.Now().Sub(startTime)
-
-							fmt.Printf("\rTime: %s", time.Time{}.Add(diff).Format("15:04:05"))
-
-						case <-c.Done():
-							activeSession.EndTime = time.Now()
-
-							if !done {
-								done = true
-								wait.Done()
-							}
-
-							break
-						}
-					}
-				}()
-
-				/*
-				 * Wait for the letter 'q' to be pressed to stop timing
-				 */
-				if err = keyboard.Open(); err != nil {
-					displayError(fmt.Sprintf("Problem capturing keyboard input: %s", err.Error()))
-				}
-
-				for {
-					char, _, err := keyboard.GetKey()
-
-					if err != nil {
-						displayError(fmt.Sprintf("Problem reading key from keyboard: %s", err.Error()))
-						return
-					}
-
-					if char == 'q' {
-						break
-					}
-				}
-
-				keyboard.Close()
-				cancel()
-				wait.Wait()
-
-				fmt.Printf("\n")
-
-				/*
-				 * Store the session
-				 */
-				session := sessions.Session{
-					ClientID:      project.ClientID,
-					ProjectID:     project.ProjectID,
-					CategoryID:    category.CategoryID,
-					StartDateTime: activeSession.StartTime,
-					EndDateTime:   activeSession.EndTime,
-					Notes:         notes,
-					Invoiced:      false,
-					Paid:          false,
-				}
-
-				if _, err = sessionService.CreateSession(session); err != nil {
-					displayError(fmt.Sprintf("Problem recording session to database: %s", err.Error()))
-				}
-
-				fmt.Printf("\nSession recorded!\n")
-				sessionService.DeleteActiveSessions()
+				return watchActiveSession(activeSession)
 			}
+
+			return nil
 		},
 	}
 
@@ -214,41 +241,21 @@ mt session start "projectCode" "notes" --interactive - Starts timing and display
 		Aliases: []string{"st"},
 		Short:   "Stops an active timing session",
 		Example: `mt session stop`,
-		Run: func(cmd *cobra.Command, args []string) {
-			var (
-				err           error
-				activeSession sessions.ActiveSession
-			)
-
-			if activeSession, err = sessionService.GetActiveSession(); err != nil {
-				displayError(err.Error())
-			}
-
-			activeSession.EndTime = time.Now()
-
-			session := sessions.Session{
-				ClientID:      activeSession.ClientID,
-				ProjectID:     activeSession.ProjectID,
-				CategoryID:    activeSession.CategoryID,
-				StartDateTime: activeSession.StartTime,
-				EndDateTime:   activeSession.EndTime,
-				Notes:         activeSession.Notes,
-				Invoiced:      false,
-				Paid:          false,
-			}
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			var session sessions.Session
 
-			if _, err = sessionService.CreateSession(session); err != nil {
-				displayError(fmt.Sprintf("Problem recording session to database: %s", err.Error()))
+			if session, err = stopActiveSession(); err != nil {
+				return err
 			}
 
-			diff := activeSession.EndTime.Sub(activeSession.StartTime)
+			diff := session.EndDateTime.Sub(session.StartDateTime)
 
-			fmt.Printf("Start Time: %s\n", activeSession.StartTime.Format("3:04:05 PM"))
-			fmt.Printf("End Time: %s\n", activeSession.EndTime.Format("3:04:05 PM"))
-			fmt.Printf("Total time: %s\n", Green(time.Time{}.Add(diff).Format("15:04:05")))
+			fmt.Printf("Start Time: %s\n", appSettings.FormatTimeWithSeconds(session.StartDateTime))
+			fmt.Printf("End Time: %s\n", appSettings.FormatTimeWithSeconds(session.EndDateTime))
+			fmt.Printf("Total time: %s\n", au.Green(appSettings.FormatDuration(diff)))
 			fmt.Printf("\nSession recorded!\n")
-			sessionService.DeleteActiveSessions()
-
+			return nil
 		},
 	}
 
@@ -256,7 +263,7 @@ mt session start "projectCode" "notes" --interactive - Starts timing and display
 		Use:     "status",
 		Short:   `Display the status of a current session`,
 		Example: `mt session status`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				err      error
 				client   clients.Client
@@ -267,28 +274,71 @@ mt session start "projectCode" "notes" --interactive - Starts timing and display
 			)
 
 			if activeSession, err = sessionService.GetActiveSession(); err != nil {
-				displayError(err.Error())
+				return err
 			}
 
 			if client, err = clientService.GetClientByID(activeSession.ClientID); err != nil {
-				displayError(fmt.Sprintf("Problem getting client: %s", err.Error()))
+				return fmt.Errorf("Problem getting client: %w", err)
 			}
 
 			if project, err = projectService.GetProjectByID(activeSession.ProjectID); err != nil {
-				displayError(fmt.Sprintf("Problem getting project: %s", err.Error()))
+				return fmt.Errorf("Problem getting project: %w", err)
 			}
 
 			if category, err = categoryService.GetCategoryByID(activeSession.CategoryID); err != nil {
-				displayError(fmt.Sprintf("Problem getting category: %s", err.Error()))
+				return fmt.Errorf("Problem getting category: %w", err)
 			}
 
 			diff := time.Now().Sub(activeSession.StartTime)
 
-			fmt.Printf("Timing for %s\n", Green(client.Name))
-			fmt.Printf("Project: %s\n", Green(project.Name))
-			fmt.Printf("Category: %s\n", Cyan(category.Name))
-			fmt.Printf("Start Time: %s\n", activeSession.StartTime.Format("3:04 PM"))
-			fmt.Printf("Current Duration: %s\n", time.Time{}.Add(diff).Format("15:04:05"))
+			status := activeSessionOutput{
+				ClientCode:      client.Code,
+				ClientName:      client.Name,
+				ProjectCode:     project.Code,
+				ProjectName:     project.Name,
+				CategoryCode:    category.Code,
+				CategoryName:    category.Name,
+				StartDateTime:   activeSession.StartTime,
+				DurationSeconds: int64(diff.Seconds()),
+				Notes:           activeSession.Notes,
+			}
+
+			if printed, err := printOutput(status); printed {
+				return err
+			}
+
+			fmt.Printf("Timing for %s\n", au.Green(client.Name))
+			fmt.Printf("Project: %s\n", au.Green(project.Name))
+			fmt.Printf("Category: %s\n", au.Cyan(category.Name))
+			fmt.Printf("Start Time: %s\n", appSettings.FormatTime(activeSession.StartTime))
+			fmt.Printf("Current Duration: %s\n", appSettings.FormatDuration(diff))
+			return nil
+		},
+	}
+
+	sessionAttachCmd := &cobra.Command{
+		Use:     "attach",
+		Aliases: []string{"watch"},
+		Short:   `Watches the running timer in interactive mode, such as after its terminal was closed`,
+		Example: `mt session attach`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				err           error
+				project       projects.Project
+				activeSession sessions.ActiveSession
+			)
+
+			if activeSession, err = sessionService.GetActiveSession(); err != nil {
+				return err
+			}
+
+			if project, err = projectService.GetProjectByID(activeSession.ProjectID); err != nil {
+				return fmt.Errorf("Problem getting project: %w", err)
+			}
+
+			fmt.Printf("Timing for %s\nStart Time: %s\n", au.Green(project.Name), appSettings.FormatTime(activeSession.StartTime))
+			return watchActiveSession(activeSession)
 		},
 	}
 
@@ -310,19 +360,31 @@ mt session start "projectCode" "notes" --interactive - Starts timing and display
 
 			return nil
 		},
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				err       error
 				sessionID int
 			)
 
 			sessionID, _ = strconv.Atoi(args[0])
+			unlock, err := lockData()
+
+			if err != nil {
+				return err
+			}
+
+			defer unlock()
+
+			if err = autoBackup("close"); err != nil {
+				return err
+			}
 
 			if err = sessionService.CloseSession(sessionID); err != nil {
-				displayError(err.Error())
+				return err
 			}
 
-			fmt.Printf("Session ID %s closed\n", Green(sessionID))
+			fmt.Printf("Session ID %d closed\n", au.Green(sessionID))
+			return nil
 		},
 	}
 
@@ -338,8 +400,9 @@ mt session report --paid
 mt session report --invoiced
 mt session report --id 2
 mt session report --ids 2,54,3
+mt session report --week
 mt session report --decimal`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var err error
 			var result sessions.SessionCollection
 			tableData := make([][]string, 9)
@@ -354,15 +417,31 @@ mt session report --decimal`,
 				SessionIDs:   sessionIDs,
 			}
 
+			if week {
+				search.From = appSettings.StartOfWeek(time.Now())
+				search.To = search.From.AddDate(0, 0, 7)
+			}
+
+			if outputFormat != OutputTable {
+				var records exports.SessionRecordCollection
+
+				if records, err = exportService.GetSessionRecords(search); err != nil {
+					return err
+				}
+
+				_, err = printOutput(records)
+				return err
+			}
+
 			if result, err = sessionService.ListSessions(search); err != nil {
-				displayError(err.Error())
+				return err
 			}
 
 			table := tablewriter.NewWriter(os.Stdout)
 			table.SetHeader([]string{"ID", "Client", "Project", "Category", "Date", "Time", "Duration", "Invoiced", "Paid"})
 			table.SetBorder(false)
 
-			table.SetHeaderColor(
+			setHeaderColor(table,
 				tablewriter.Colors{tablewriter.Bold},
 				tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
 				tablewriter.Colors{tablewriter.Bold},
@@ -381,28 +460,28 @@ mt session report --decimal`,
 				p, _ := projectService.GetProjectByID(s.ProjectID)
 				cat, _ := categoryService.GetCategoryByID(s.CategoryID)
 
-				dateFormatted := s.StartDateTime.Format("Mon Jan _2 2006")
-				startTime := s.StartDateTime.Format("3:04:05PM")
-				endTime := s.EndDateTime.Format("3:04:05PM")
+				dateFormatted := appSettings.FormatDate(s.StartDateTime)
+				startTime := appSettings.FormatTimeWithSeconds(s.StartDateTime)
+				endTime := appSettings.FormatTimeWithSeconds(s.EndDateTime)
 
-				diff := s.EndDateTime.Sub(s.StartDateTime)
+				diff := appSettings.Round(s.EndDateTime.Sub(s.StartDateTime))
 				t := fmt.Sprintf("%s - %s", startTime, endTime)
 
 				if decimal {
-					duration = strconv.FormatFloat(diff.Hours(), 'G', 2, 64)
+					duration = appSettings.FormatHours(diff)
 				} else {
-					duration = time.Time{}.Add(diff).Format("15:04:05")
+					duration = appSettings.FormatDuration(diff)
 				}
 
 				invoiced := ""
 				paid := ""
 
 				if s.Invoiced {
-					invoiced = s.InvoiceDate.Format("Mon Jan _2 2006")
+					invoiced = appSettings.FormatDate(s.InvoiceDate)
 				}
 
 				if s.Paid {
-					paid = s.PaidDate.Format("Mon Jan _2 2006")
+					paid = appSettings.FormatDate(s.PaidDate)
 				}
 
 				tableData = append(tableData, []string{strconv.Itoa(s.SessionID), c.Name, p.Name, cat.Name, dateFormatted, t, duration, invoiced, paid})
@@ -410,7 +489,7 @@ mt session report --decimal`,
 
 			table.AppendBulk(tableData)
 			table.Render()
-
+			return nil
 		},
 	}
 
@@ -436,8 +515,9 @@ mt session report --decimal`,
 
 			return nil
 		},
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var invoicingErrors []error
+			var firstError error
 
 			split := strings.Split(args[0], ",")
 			ids := make([]int, len(split))
@@ -447,28 +527,48 @@ mt session report --decimal`,
 				ids[index] = intValue
 			}
 
+			unlock, err := lockData()
+
+			if err != nil {
+				return err
+			}
+
+			defer unlock()
+
+			if err = autoBackup("invoice"); err != nil {
+				return err
+			}
+
 			invoicingErrors = sessionService.InvoiceSessions(ids)
 			errorCount := 0
 
 			for _, e := range invoicingErrors {
 				if e != nil {
+					if firstError == nil {
+						firstError = e
+					}
+
 					errorCount++
-					fmt.Printf("%s: %s\n", Red("ERROR"), e.Error())
+					fmt.Printf("%s: %s\n", au.Red("ERROR"), e.Error())
 				}
 			}
 
-			if errorCount > 0 && errorCount < len(ids) {
-				fmt.Printf("Some sessions were invoiced, but there were %s errors\n", Cyan(errorCount))
-			} else if errorCount == len(ids) {
-				fmt.Printf("No sessions were invoiced.\n")
-			} else {
+			if errorCount == 0 {
 				fmt.Printf("Sessions invoiced successfully!\n")
+				return nil
 			}
+
+			/*
+			 * Sessions are invoiced all together or not at all. The exit
+			 * code comes from the first session that failed
+			 */
+			return fmt.Errorf("No sessions were invoiced, as %s couldn't be: %w", countOf(errorCount, "session"), firstError)
 		},
 	}
 
 	startSessionCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Starts a timing session in interactive mode")
 	startSessionCmd.Flags().StringVarP(&categoryCode, "category", "c", "", "Category to use in this timing session")
+	startSessionCmd.Flags().BoolVarP(&allowArchived, "allow-archived", "", false, "Allow timing against an archived project or category")
 	sessionReportCmd.Flags().StringVarP(&categoryCode, "category", "a", "", "Filter sessions by category code")
 	sessionReportCmd.Flags().StringVarP(&clientCode, "client", "c", "", "Filter sessions by client code")
 	sessionReportCmd.Flags().StringVarP(&projectCode, "project", "p", "", "Filter sessions by project code")
@@ -477,7 +577,8 @@ mt session report --decimal`,
 	sessionReportCmd.Flags().IntVarP(&sessionID, "id", "", 0, "Filter sessions by ID")
 	sessionReportCmd.Flags().IntSliceVarP(&sessionIDs, "ids", "", []int{}, "Filter sessions by a list of IDs")
 	sessionReportCmd.Flags().BoolVarP(&decimal, "decimal", "d", false, "Show session duration in decimal format")
+	sessionReportCmd.Flags().BoolVarP(&week, "week", "w", false, "Filter sessions for those started this week")
 
-	sessionCmd.AddCommand(startSessionCmd, stopSessionCmd, sessionStatusCmd, sessionCloseCmd, sessionReportCmd, sessionInvoiceCmd)
+	sessionCmd.AddCommand(startSessionCmd, stopSessionCmd, sessionStatusCmd, sessionAttachCmd, sessionCloseCmd, sessionReportCmd, sessionInvoiceCmd)
 	rootCmd.AddCommand(sessionCmd)
 }
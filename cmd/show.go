@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/adampresley/mytime/api/categories"
+	"github.com/adampresley/mytime/api/clients"
+	"github.com/adampresley/mytime/api/exports"
+	"github.com/adampresley/mytime/api/projects"
+	"github.com/adampresley/mytime/api/sessions"
+	"github.com/spf13/cobra"
+)
+
+// statistics sums up the sessions of a client, project, or category.
+// Hours and amounts use the rounding settings, like reports do.
+type statistics struct {
+	TotalHours        float64    `json:"totalHours"`
+	MonthHours        float64    `json:"monthHours"`
+	UnbilledAmount    float64    `json:"unbilledAmount"`
+	OutstandingAmount float64    `json:"outstandingAmount"`
+	LastActivity      *time.Time `json:"lastActivity"`
+}
+
+type clientDetails struct {
+	clients.Client
+	Projects []string `json:"projects"`
+	statistics
+}
+
+type projectDetails struct {
+	projectOutput
+	statistics
+}
+
+type categoryDetails struct {
+	categories.Category
+	statistics
+}
+
+/*
+ * getStatistics adds up the session records matching search, so hours and
+ * amounts are rounded as exports and reports round them. The session
+ * search only finds one invoiced and paid state at a time, so unbilled,
+ * outstanding, and paid sessions are each searched for.
+ */
+func getStatistics(search sessions.SessionSearch) (statistics, error) {
+	var (
+		err     error
+		result  statistics
+		records exports.SessionRecordCollection
+	)
+
+	startOfMonth := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.Local)
+
+	states := []struct {
+		invoiced bool
+		paid     bool
+		amount   *float64
+	}{
+		{invoiced: false, paid: false, amount: &result.UnbilledAmount},
+		{invoiced: true, paid: false, amount: &result.OutstandingAmount},
+		{invoiced: true, paid: true},
+	}
+
+	for _, state := range states {
+		search.Invoiced = state.invoiced
+		search.Paid = state.paid
+
+		if records, err = exportService.GetSessionRecords(search); err != nil {
+			return result, err
+		}
+
+		for _, r := range records {
+			result.TotalHours += r.DurationHours
+
+			if !r.StartDateTime.Before(startOfMonth) {
+				result.MonthHours += r.DurationHours
+			}
+
+			if result.LastActivity == nil || r.EndDateTime.After(*result.LastActivity) {
+				lastActivity := r.EndDateTime
+				result.LastActivity = &lastActivity
+			}
+
+			if state.amount != nil {
+				*state.amount += r.Amount
+			}
+		}
+	}
+
+	result.TotalHours = appSettings.RoundToPrecision(result.TotalHours)
+	result.MonthHours = appSettings.RoundToPrecision(result.MonthHours)
+	result.UnbilledAmount = appSettings.RoundToPrecision(result.UnbilledAmount)
+	result.OutstandingAmount = appSettings.RoundToPrecision(result.OutstandingAmount)
+	return result, nil
+}
+
+func printStatistics(stats statistics) {
+	lastActivity := "Never"
+
+	if stats.LastActivity != nil {
+		lastActivity = appSettings.FormatDate(*stats.LastActivity)
+	}
+
+	fmt.Printf("\n")
+	fmt.Printf("Total Hours: %s\n", appSettings.FormatHours(hours(stats.TotalHours)))
+	fmt.Printf("Hours This Month: %s\n", appSettings.FormatHours(hours(stats.MonthHours)))
+	fmt.Printf("Unbilled: %s\n", au.Green(appSettings.FormatMoney(stats.UnbilledAmount)))
+	fmt.Printf("Invoiced, Awaiting Payment: %s\n", au.Yellow(appSettings.FormatMoney(stats.OutstandingAmount)))
+	fmt.Printf("Last Activity: %s\n", lastActivity)
+}
+
+func hours(h float64) time.Duration {
+	return time.Duration(h * float64(time.Hour))
+}
+
+func init() {
+	showCmd := &cobra.Command{
+		Use:     "show",
+		Aliases: []string{"sh"},
+		Short:   `Shows the details of a client, project, or category`,
+	}
+
+	showClientCmd := &cobra.Command{
+		Use:     "client",
+		Aliases: []string{"c"},
+		Short:   `Shows the details of a client, including billing information and projects`,
+		Example: `mt show client "clientcode"`,
+		Args:    optionalCodeArgs("client", "see"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				err         error
+				clientCode  string
+				client      clients.Client
+				allProjects projects.ProjectCollection
+				details     clientDetails
+			)
+
+			if clientCode, err = pickClientCode(firstArg(args)); err != nil {
+				return err
+			}
+
+			if client, err = clientService.GetClientByCode(clientCode); err != nil {
+				return err
+			}
+
+			if allProjects, err = listAllProjects(); err != nil {
+				return err
+			}
+
+			details.Client = client
+			details.Projects = make([]string, 0, 5)
+
+			for _, p := range allProjects {
+				if p.ClientID == client.ClientID {
+					details.Projects = append(details.Projects, p.Code)
+				}
+			}
+
+			if details.statistics, err = getStatistics(sessions.SessionSearch{ClientCode: client.Code}); err != nil {
+				return err
+			}
+
+			if printed, err := printOutput(details); printed {
+				return err
+			}
+
+			terms := "Due on receipt"
+
+			if client.PaymentTerms > 0 {
+				terms = fmt.Sprintf("Net %d days", client.PaymentTerms)
+			}
+
+			fmt.Printf("Client: %s\n", au.Green(client.Name))
+			fmt.Printf("ID: %d\n", client.ClientID)
+			fmt.Printf("Code: %s\n", au.Green(client.Code))
+			fmt.Printf("Address: %s\n", client.Address)
+			fmt.Printf("Contact: %s\n", client.ContactName)
+			fmt.Printf("Email: %s\n", client.ContactEmail)
+			fmt.Printf("Payment Terms: %s\n", terms)
+			fmt.Printf("Tax ID: %s\n", client.TaxID)
+			fmt.Printf("Notes: %s\n", client.Notes)
+			fmt.Printf("Archived: %t\n", client.Archived)
+
+			printStatistics(details.statistics)
+
+			fmt.Printf("\nProjects:\n")
+
+			for _, p := range allProjects {
+				if p.ClientID != client.ClientID {
+					continue
+				}
+
+				archived := ""
+
+				if p.Archived {
+					archived = " (archived)"
+				}
+
+				fmt.Printf("  %s - %s%s\n", au.Green(p.Code), p.Name, archived)
+			}
+
+			return nil
+		},
+	}
+
+	showProjectCmd := &cobra.Command{
+		Use:     "project",
+		Aliases: []string{"p", "proj"},
+		Short:   `Shows the details of a project`,
+		Example: `mt show project "projectcode"`,
+		Args:    optionalCodeArgs("project", "see"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				err         error
+				projectCode string
+				project     projects.Project
+				details     projectDetails
+			)
+
+			if projectCode, err = pickProjectCode(firstArg(args)); err != nil {
+				return err
+			}
+
+			if project, err = projectService.GetProjectByCode(projectCode); err != nil {
+				return err
+			}
+
+			client, _ := clientService.GetClientByID(project.ClientID)
+			category, _ := categoryService.GetCategoryByID(project.DefaultCategoryID)
+
+			details.projectOutput = projectOutput{
+				Project:             project,
+				ClientCode:          client.Code,
+				ClientName:          client.Name,
+				DefaultCategoryCode: category.Code,
+			}
+
+			if details.statistics, err = getStatistics(sessions.SessionSearch{ProjectCode: project.Code}); err != nil {
+				return err
+			}
+
+			if printed, err := printOutput(details); printed {
+				return err
+			}
+
+			fmt.Printf("Project: %s\n", au.Green(project.Name))
+			fmt.Printf("ID: %d\n", project.ProjectID)
+			fmt.Printf("Code: %s\n", au.Green(project.Code))
+			fmt.Printf("Client: %s (%s)\n", client.Name, client.Code)
+			fmt.Printf("Default Category: %s (%s)\n", category.Name, category.Code)
+			fmt.Printf("Archived: %t\n", project.Archived)
+
+			printStatistics(details.statistics)
+			return nil
+		},
+	}
+
+	showCategoryCmd := &cobra.Command{
+		Use:     "category",
+		Aliases: []string{"cat"},
+		Short:   `Shows the details of a category`,
+		Example: `mt show category "categorycode"`,
+		Args:    optionalCodeArgs("category", "see"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				err          error
+				categoryCode string
+				category     categories.Category
+				details      categoryDetails
+			)
+
+			if categoryCode, err = pickCategoryCode(firstArg(args)); err != nil {
+				return err
+			}
+
+			if category, err = categoryService.GetCategoryByCode(categoryCode); err != nil {
+				return err
+			}
+
+			details.Category = category
+
+			if details.statistics, err = getStatistics(sessions.SessionSearch{CategoryCode: category.Code}); err != nil {
+				return err
+			}
+
+			if printed, err := printOutput(details); printed {
+				return err
+			}
+
+			fmt.Printf("Category: %s\n", au.Green(category.Name))
+			fmt.Printf("ID: %d\n", category.CategoryID)
+			fmt.Printf("Code: %s\n", au.Green(category.Code))
+			fmt.Printf("Rate: %s\n", appSettings.FormatMoney(category.Rate))
+			fmt.Printf("Archived: %t\n", category.Archived)
+
+			printStatistics(details.statistics)
+			return nil
+		},
+	}
+
+	showCmd.AddCommand(showClientCmd, showProjectCmd, showCategoryCmd)
+	rootCmd.AddCommand(showCmd)
+}
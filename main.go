@@ -1,9 +1,11 @@
 package main
 
 import (
+	"os"
+
 	"github.com/adampresley/mytime/cmd"
 )
 
 func main() {
-	cmd.Execute()
+	os.Exit(cmd.Execute())
 }